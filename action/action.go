@@ -6,14 +6,17 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
-	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/fluxcd/pkg/runtime/acl"
 	sourcev1 "github.com/fluxcd/source-controller/api/v1"
 	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
 	"github.com/openfluxcd/artifact/matchers"
 	"github.com/openfluxcd/artifact/utils"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -41,41 +44,6 @@ type ActionResource interface {
 
 var _ ArtifactSource = sourcev1.Source(nil)
 
-func requestsForRevisionChangeOf[T any, P ActionResourcePointerType[T]](client ctrlclient.Client, scheme *runtime.Scheme, opts *Options) handler.MapFunc {
-	// Queues requests for all kustomization resources that either reference the artifact resource directly in their
-	// source ref or that reference another resource that is referenced by the owner reference of the artifact in their
-	// source ref.
-	return func(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
-		log := ctrl.LoggerFrom(ctx)
-		src, ok := obj.(ArtifactSource)
-		if !ok {
-			log.Error(fmt.Errorf("expected an object conformed with GetArtifact() method, but got a %T", obj),
-				"failed to get reconcile requests for revision change")
-			return nil
-		}
-		// If we do not have an artifact, we have no requests to make
-		if src.GetArtifact() == nil {
-			return nil
-		}
-
-		actions := lookupBySourceObj[T, P](ctx, client, scheme, obj, src.GetArtifact())
-
-		if art, ok := src.(*artifactv1.Artifact); ok {
-			for _, ref := range art.OwnerReferences {
-				actions = append(actions, lookupByCoordinates[T, P](ctx, client, scheme, utils.ExtractGroupName(ref.APIVersion), ref.Kind, art.Namespace, ref.Name, art.GetArtifact())...)
-			}
-		}
-		for i := 0; i < len(actions); i++ {
-			if !opts.TriggerPredicate(actions[i].(ActionResource), src) {
-				actions = append(actions[:i], actions[i+1:]...)
-				i--
-			}
-		}
-
-		return opts.RequestMapper(actions)
-	}
-}
-
 func lookupBySourceObj[T any, P ActionResourcePointerType[T]](ctx context.Context, client ctrlclient.Client, scheme *runtime.Scheme, srcobj ctrlclient.Object, art *sourcev1.Artifact) []runtime.Object {
 	gk := utils.GetGroupKindForObject(scheme, srcobj)
 	return lookupByCoordinates[T, P](ctx, client, scheme, gk.Group, gk.Kind, srcobj.GetNamespace(), srcobj.GetName(), art)
@@ -101,8 +69,10 @@ func Setup[T any, P ActionResourcePointerType[T]](ctx context.Context, mgr ctrl.
 	var _obj T
 	obj := P(&_obj)
 
+	opts := EvalOptions(options...)
+
 	if err := mgr.GetCache().IndexField(ctx, obj, SourceRefIndexKey,
-		SourceReferenceIndex[P]()); err != nil {
+		SourceReferenceIndex[P](opts.KindResolver)); err != nil {
 		return nil, fmt.Errorf("failed setting index fields: %w", err)
 	}
 
@@ -111,31 +81,89 @@ func Setup[T any, P ActionResourcePointerType[T]](ctx context.Context, mgr ctrl.
 		return nil, fmt.Errorf("failed setting index fields: %w", err)
 	}
 
-	opts := EvalOptions(options...)
 	bldr := ctrl.NewControllerManagedBy(mgr)
 
 	bldr.For(obj, opts.ForOptions...)
-	for gk, o := range matchers.BuiltinFluxSourceKinds {
+	var sourcePredicate predicate.Predicate = SourceRevisionChangePredicate{}
+	if opts.ReadySourcesRequired() {
+		sourcePredicate = predicate.Or(sourcePredicate, SourceReadyChangePredicate{})
+	}
+
+	// the access to sources may depend on the labels of the namespace of an action resource
+	watchNamespaces := opts.SourceAccessGrantsEnabled()
+	factory := matchers.BuiltinFluxSourceVersions.ForMapper(mgr.GetRESTMapper())
+	for _, gk := range factory.Kinds.GroupKinds() {
 		if opts.AllowedSourceKinds == nil || opts.AllowedSourceKinds.Match(gk) {
+			pred := sourcePredicate
+			if gk == ClusterArtifactGroupKind {
+				pred = predicate.Or(pred, ClusterArtifactAccessChangePredicate{})
+				watchNamespaces = true
+			}
 			bldr = bldr.Watches(
-				o.DeepCopyObject().(ctrlclient.Object),
-				handler.EnqueueRequestsFromMapFunc(requestsForRevisionChangeOf[T, P](client, mgr.GetScheme(), opts)),
-				builder.WithPredicates(SourceRevisionChangePredicate{}),
+				factory.Create(gk),
+				newRevisionChangeHandler[T, P](client, mgr.GetScheme(), opts, pred),
 			)
 		}
 	}
+	if opts.MaintenanceWindowsEnabled() {
+		opts.deferred = newDeferredTriggers(opts.Clock)
+		opts.deferred.resync = resyncDeferredTriggers[T, P](client, mgr.GetScheme(), opts)
+		if err := mgr.Add(opts.deferred); err != nil {
+			return nil, fmt.Errorf("failed adding deferred triggers: %w", err)
+		}
+		bldr = bldr.WatchesRawSource(source.Channel(opts.deferred.events, &handler.EnqueueRequestForObject{}))
+	}
+	if opts.ArtifactChannelsEnabled() {
+		bldr = bldr.Watches(
+			&artifactv1.ArtifactChannel{},
+			newChannelChangeHandler[T, P](client, mgr.GetScheme(), opts, predicate.GenerationChangedPredicate{}),
+		)
+	}
+	if opts.RevisionApprovalsRequired() {
+		bldr = bldr.Watches(
+			&artifactv1.RevisionApproval{},
+			handler.EnqueueRequestsFromMapFunc(requestsForRevisionApprovalOf[T, P](client, mgr.GetScheme(), opts)),
+		)
+	}
+	if opts.SourceAccessGrantsEnabled() {
+		bldr = bldr.Watches(
+			&artifactv1.SourceAccessGrant{},
+			handler.EnqueueRequestsFromMapFunc(requestsForSourceAccessGrantChangeOf[T, P](client, mgr.GetScheme(), opts)),
+		)
+	}
+	if watchNamespaces {
+		// grants and ClusterArtifacts may select namespaces by their labels,
+		// this requires permissions to get, list and watch namespaces
+		bldr = bldr.Watches(
+			&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(requestsForNamespaceChangeOf[T, P](client, mgr.GetScheme(), opts)),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}),
+		)
+	}
 	return bldr, nil
 }
 
-func SourceReferenceIndex[T ActionResource]() func(o ctrlclient.Object) []string {
+// SourceReferenceIndex indexes action resources by the key of their source reference.
+// If a KindResolver is given, the group kind of the reference is resolved before.
+// References which cannot be resolved, e.g. because the kind is not installed yet,
+// are indexed by their unresolved key. The reconciler resolves them again with
+// GetSource, the index is only updated with the next change of the action resource.
+func SourceReferenceIndex[T ActionResource](resolver ...utils.KindResolver) func(o ctrlclient.Object) []string {
+	var r utils.KindResolver
+	if len(resolver) > 0 {
+		r = resolver[0]
+	}
 	return func(o ctrlclient.Object) []string {
 		k, ok := o.(T)
 		if !ok {
 			var _nil T
 			panic(fmt.Sprintf("Expected a resource of type %T, got %T", _nil, o))
 		}
-		sourceref, err := k.GetSourceRef()
-		if err != nil {
+		sourceref, err := ResolvedSourceRef(k, r)
+		if err != nil && r != nil {
+			sourceref, err = ResolvedSourceRef(k, nil)
+		}
+		if err != nil || sourceref == nil {
 			return nil
 		}
 		key := utils.KeyForReference(k, sourceref)
@@ -147,32 +175,130 @@ func SourceReferenceIndex[T ActionResource]() func(o ctrlclient.Object) []string
 	}
 }
 
-func GetSource(ctx context.Context, client ctrlclient.Client, action ActionResource, options ...Option) (ArtifactSource, error) {
+// ResolvedSourceRef returns the normalized source reference of an action resource,
+// as used for the source reference index. If a KindResolver is given, the group
+// kind of the reference is resolved.
+func ResolvedSourceRef(action ActionResource, resolver utils.KindResolver) (utils.SourceRefProvider, error) {
 	raw, err := action.GetSourceRef()
-	if err != nil {
+	if err != nil || raw == nil {
 		return nil, err
 	}
 	ref := utils.NormalizedSourceRef(raw, action.GetNamespace())
+	if resolver != nil {
+		return utils.ResolveSourceRef(resolver, ref)
+	}
+	return ref, nil
+}
 
+func GetSource(ctx context.Context, client ctrlclient.Client, action ActionResource, options ...Option) (ArtifactSource, error) {
 	opts := EvalOptions(options...)
-	if opts.CrossNamespaceRefsForbidden() && ref.GetNamespace() != action.GetNamespace() {
+
+	ref, err := ResolvedSourceRef(action, opts.KindResolver)
+	if err != nil {
+		return nil, err
+	}
+	if ref == nil {
+		return nil, fmt.Errorf("no source ref specified")
+	}
+	// access to cluster-scoped sources is controlled by the sources themselves
+	crossNamespace := !utils.IsClusterScoped(ref) && ref.GetNamespace() != action.GetNamespace()
+	if opts.CrossNamespaceRefsForbidden() && crossNamespace {
 		return nil, acl.AccessDeniedError(
 			fmt.Sprintf("can't access '%s/%s', cross-namespace references have been blocked",
 				ref.GetGroupKind().Kind, ref.GetNamespace()))
 	}
+	if opts.SourceAccessGrantsEnabled() && crossNamespace {
+		if err := CheckSourceAccessGrants(ctx, client, action, ref); err != nil {
+			return nil, err
+		}
+	}
 
 	gk := ref.GetGroupKind()
 
 	if opts.AllowedSourceKinds != nil && !opts.AllowedSourceKinds.Match(gk) {
+		if opts.DecisionRecorder != nil {
+			d := newDecision(client.Scheme(), action, false, StageAllowedSourceKinds, fmt.Sprintf("source kind %s is not allowed", gk))
+			src := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: ref.GetNamespace(), Name: ref.GetName()}}
+			src.SetGroupVersionKind(utils.GetGroupVersionKind(ref))
+			opts.DecisionRecorder.Record(ctx, client.Scheme(), src, d)
+		}
 		return nil, fmt.Errorf("source objects of kind %s are not allowed", gk)
 	}
 
-	if obj := matchers.BuiltinFluxSourceKinds.Create(gk); obj != nil {
+	reader, indexed := ctrlclient.Reader(client), true
+	if opts.Impersonator != nil {
+		impersonated, err := opts.Impersonator.ClientFor(action)
+		if err != nil {
+			return nil, err
+		}
+		if impersonated != nil {
+			reader, indexed = impersonated, false
+		}
+	}
+
+	src, err := lookupSource(ctx, client, reader, indexed, opts, action, ref)
+	if err != nil {
+		return nil, mapAccessError(err, action)
+	}
+	latest := src
+	if art, ok := src.(*artifactv1.ClusterArtifact); ok {
+		if err := checkClusterArtifactAccess(ctx, client, action, art); err != nil {
+			return nil, err
+		}
+	}
+	if revision, digest := pinOf(action); revision != "" || digest != "" {
+		src, err = pinnedSource(ctx, client, reader, indexed, src, revision, digest)
+		if err != nil {
+			return nil, mapAccessError(err, action)
+		}
+	} else {
+		if opts.RevisionApprovalsRequired() {
+			src, err = approvedSource(ctx, client, reader, indexed, action, ref, src)
+			if err != nil {
+				return nil, mapAccessError(err, action)
+			}
+		}
+		if opts.MaintenanceWindowsEnabled() {
+			src, err = maintenanceSource(ctx, client, reader, indexed, opts, action, ref, src)
+			if err != nil {
+				return nil, mapAccessError(err, action)
+			}
+		}
+	}
+	if opts.ReadySourcesRequired() {
+		if err := CheckSourceReady(src.(ctrlclient.Object)); err != nil {
+			return nil, err
+		}
+	}
+	// revisions retained while pinned, unapproved or deferred are not subject to rollouts
+	if art, ok := latest.(*artifactv1.Artifact); ok && opts.Rollout != nil && src.GetArtifact().HasRevision(art.Spec.Revision) {
+		if err := checkRollout(action, art); err != nil {
+			return nil, err
+		}
+	}
+	return src, nil
+}
+
+// lookupSource reads the source object through the given reader. Only if the reader is indexed,
+// the ArtifactOwnerIndexKey index can be used to find Artifacts, otherwise Artifacts are listed
+// and filtered.
+func lookupSource(ctx context.Context, client ctrlclient.Client, reader ctrlclient.Reader, indexed bool, opts *Options, action ActionResource, ref utils.SourceRefProvider) (ArtifactSource, error) {
+	gk := ref.GetGroupKind()
+
+	if isChannelRef(ref) {
+		if !opts.ArtifactChannelsEnabled() {
+			return nil, fmt.Errorf("source objects of kind %s are not allowed, artifact channels are disabled", gk)
+		}
+		return lookupChannelTarget(ctx, reader, ref.GetObjectKey())
+	}
+
+	factory := matchers.BuiltinFluxSourceVersions.ForMapper(client.RESTMapper())
+	if obj := factory.CreateVersion(utils.GetGroupVersionKind(ref)); obj != nil {
 		src, ok := obj.(ArtifactSource)
 		if !ok {
 			return nil, fmt.Errorf("source object %s is not an ArtifactSource", gk)
 		}
-		err := client.Get(ctx, ref.GetObjectKey(), obj)
+		err := reader.Get(ctx, ref.GetObjectKey(), obj)
 		if err != nil {
 			if apierrors.IsNotFound(err) {
 				return nil, err
@@ -188,14 +314,17 @@ func GetSource(ctx context.Context, client ctrlclient.Client, action ActionResou
 		key := utils.KeyForReference(action, ref)
 		artList := &artifactv1.ArtifactList{}
 		if key != "" {
-			err := client.List(ctx, artList, ctrlclient.MatchingFields{
-				ArtifactOwnerIndexKey: key,
-			})
+			err := listArtifactsForKey(ctx, reader, indexed, ref.GetNamespace(), key, artList)
 			if err != nil {
 				return nil, err
 			}
+			found := len(artList.Items)
+			artList.Items = withoutStaleArtifacts(ctx, opts, ref, artList.Items)
 			switch len(artList.Items) {
 			case 0:
+				if found > 0 {
+					return nil, apierrors.NewNotFound(artifactv1.GroupVersion.WithResource("artifacts").GroupResource(), key)
+				}
 				return nil, fmt.Errorf("no artifact resource found for %s", key)
 			case 1:
 				namespacedName.Name = artList.Items[0].Name
@@ -204,7 +333,7 @@ func GetSource(ctx context.Context, client ctrlclient.Client, action ActionResou
 			}
 
 			var art artifactv1.Artifact
-			err = client.Get(ctx, namespacedName, &art)
+			err = reader.Get(ctx, namespacedName, &art)
 			if err != nil {
 				if apierrors.IsNotFound(err) {
 					return nil, err
@@ -217,3 +346,54 @@ func GetSource(ctx context.Context, client ctrlclient.Client, action ActionResou
 		}
 	}
 }
+
+func listArtifactsForKey(ctx context.Context, reader ctrlclient.Reader, indexed bool, namespace, key string, list *artifactv1.ArtifactList) error {
+	if indexed {
+		return reader.List(ctx, list, ctrlclient.MatchingFields{
+			ArtifactOwnerIndexKey: key,
+		})
+	}
+
+	var all artifactv1.ArtifactList
+	if err := reader.List(ctx, &all, ctrlclient.InNamespace(namespace)); err != nil {
+		return err
+	}
+	index := utils.OwnerReferenceIndex()
+	for _, art := range all.Items {
+		for _, k := range index(&art) {
+			if k == key {
+				list.Items = append(list.Items, art)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// withoutStaleArtifacts removes Artifacts found via an owner reference to the
+// referenced source, if the owner reference does not match the live source
+// object. Such Artifacts are reported to be cleaned up. Owners are only
+// verified with WithOwnerVerification.
+func withoutStaleArtifacts(ctx context.Context, opts *Options, ref utils.SourceRefProvider, list []artifactv1.Artifact) []artifactv1.Artifact {
+	log := ctrl.LoggerFrom(ctx)
+	gk := ref.GetGroupKind()
+
+	var result []artifactv1.Artifact
+	for _, art := range list {
+		owner := utils.FindOwnerReference(&art, gk.Group, gk.Kind, ref.GetName())
+		if owner == nil {
+			result = append(result, art)
+			continue
+		}
+		state, _ := checkOwner(ctx, opts, art.Namespace, *owner)
+		switch state {
+		case utils.OwnerValid:
+			result = append(result, art)
+		case utils.OwnerStale:
+			log.Info("ignoring stale artifact, its owner has been recreated", "artifact", ctrlclient.ObjectKeyFromObject(&art), "owner", ref.String(), "uid", owner.UID)
+		case utils.OwnerGone:
+			log.Info("ignoring orphaned artifact, its owner does not exist anymore", "artifact", ctrlclient.ObjectKeyFromObject(&art), "owner", ref.String())
+		}
+	}
+	return result
+}
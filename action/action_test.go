@@ -0,0 +1,116 @@
+package action
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/openfluxcd/artifact/api/commonv1"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var testActionGroupVersion = schema.GroupVersion{Group: "test.example.com", Version: "v1"}
+
+type testAction struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec struct {
+		SourceRef          commonv1.SourceRef             `json:"sourceRef"`
+		MaintenanceWindows []artifactv1.MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+		ServiceAccountName string                         `json:"serviceAccountName,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		LastAppliedRevision string `json:"lastAppliedRevision,omitempty"`
+	} `json:"status"`
+}
+
+func (a *testAction) GetSourceRef() (utils.SourceRefProvider, error) {
+	return &a.Spec.SourceRef, nil
+}
+
+func (a *testAction) DeepCopyObject() runtime.Object {
+	c := *a
+	a.ObjectMeta.DeepCopyInto(&c.ObjectMeta)
+	c.Spec.MaintenanceWindows = append([]artifactv1.MaintenanceWindow(nil), a.Spec.MaintenanceWindows...)
+	return &c
+}
+
+var _ ActionResource = (*testAction)(nil)
+
+type testActionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []testAction `json:"items"`
+}
+
+func (l *testActionList) DeepCopyObject() runtime.Object {
+	c := &testActionList{TypeMeta: l.TypeMeta}
+	l.ListMeta.DeepCopyInto(&c.ListMeta)
+	for i := range l.Items {
+		c.Items = append(c.Items, *l.Items[i].DeepCopyObject().(*testAction))
+	}
+	return c
+}
+
+// saturday noon, the test window opens on monday at 22:00 UTC
+var saturday = time.Date(2024, 5, 4, 12, 0, 0, 0, time.UTC)
+var monday = time.Date(2024, 5, 6, 22, 0, 0, 0, time.UTC)
+
+func newTestAction() *testAction {
+	a := &testAction{}
+	a.Namespace, a.Name = "default", "app"
+	a.Labels = map[string]string{"environment": "production"}
+	a.Spec.SourceRef = commonv1.SourceRef{APIVersion: artifactv1.GroupVersion.String(), Kind: artifactv1.ArtifactKind, Name: "source"}
+	a.Status.LastAppliedRevision = "v1"
+	return a
+}
+
+func newTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = artifactv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	scheme.AddKnownTypeWithName(testActionGroupVersion.WithKind("TestAction"), &testAction{})
+	scheme.AddKnownTypeWithName(testActionGroupVersion.WithKind("TestActionList"), &testActionList{})
+	return scheme
+}
+
+func newTestArtifact(revision string) *artifactv1.Artifact {
+	art := &artifactv1.Artifact{}
+	art.Namespace, art.Name = "default", "source"
+	art.Spec.Revision = revision
+	art.Spec.URL = "http://storage/" + revision + ".tar.gz"
+	return art
+}
+
+// newTestClient returns a fake client with the indices registered by Setup.
+func newTestClient(objs ...ctrlclient.Object) ctrlclient.Client {
+	return fake.NewClientBuilder().WithScheme(newTestScheme()).WithObjects(objs...).
+		WithStatusSubresource(&artifactv1.Artifact{}, &artifactv1.ClusterArtifact{}, &testAction{}).
+		WithIndex(&testAction{}, SourceRefIndexKey, SourceReferenceIndex[*testAction]()).
+		WithIndex(&artifactv1.Artifact{}, ArtifactOwnerIndexKey, utils.OwnerReferenceIndex()).Build()
+}
+
+type failingKindResolver struct{}
+
+func (failingKindResolver) ResolveGroupVersionKind(gvk schema.GroupVersionKind) (schema.GroupVersionKind, error) {
+	return gvk, fmt.Errorf("no kind %s installed", gvk.Kind)
+}
+
+func TestSourceReferenceIndexUnresolved(t *testing.T) {
+	g := NewWithT(t)
+
+	action := newTestAction()
+	expected := SourceReferenceIndex[*testAction]()(action)
+	g.Expect(expected).To(HaveLen(1))
+	g.Expect(SourceReferenceIndex[*testAction](failingKindResolver{})(action)).To(Equal(expected))
+}
@@ -0,0 +1,175 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/utils"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// RevisionNotApprovedError is returned by GetSource if WithRevisionApprovals
+// is configured and no approved revision of the source is available.
+type RevisionNotApprovedError struct {
+	Source   string
+	Revision string
+}
+
+func (e *RevisionNotApprovedError) Error() string {
+	return fmt.Sprintf("revision %s of source '%s' has not been approved and no approved revision is retained", e.Revision, e.Source)
+}
+
+func IsRevisionNotApproved(err error) bool {
+	var e *RevisionNotApprovedError
+	return errors.As(err, &e)
+}
+
+// approvedSourceOf returns the namespace and key of the source approved by a
+// RevisionApproval. The namespace is empty for cluster-scoped sources.
+func approvedSourceOf(approval *artifactv1.RevisionApproval) (string, string) {
+	src := approval.Spec.Source
+	ref := utils.NewSourceRef(src.Group, src.Kind, src.Namespace, src.Name)
+	ns := src.Namespace
+	if ns == "" {
+		ns = approval.Namespace
+	}
+	if utils.IsClusterScoped(ref) {
+		ns = ""
+	}
+	return ns, utils.KeyForReference(approval, ref)
+}
+
+// approves checks whether the approval applies to the given action resource
+// referencing the source with the given key. Approvals without an action are
+// only considered in the namespace of the source, approvals for a dedicated
+// action only in the namespace of the action. Cluster-scoped sources have no
+// namespace, approvals without an action apply to the action resources in the
+// namespace of the approval.
+func approves(approval *artifactv1.RevisionApproval, gk schema.GroupKind, action ActionResource, key string) bool {
+	ns, k := approvedSourceOf(approval)
+	if k != key {
+		return false
+	}
+	if a := approval.Spec.Action; a != nil {
+		return approval.Namespace == action.GetNamespace() &&
+			a.Group == gk.Group && a.Kind == gk.Kind && a.Name == action.GetName()
+	}
+	if ns == "" {
+		return approval.Namespace == action.GetNamespace()
+	}
+	return approval.Namespace == ns
+}
+
+// approvalsFor returns the approvals applying to an action resource, most recent first.
+func approvalsFor(ctx context.Context, client ctrlclient.Client, action ActionResource, ref utils.SourceRefProvider) ([]artifactv1.RevisionApproval, error) {
+	gk := utils.GetGroupKindForObject(client.Scheme(), action)
+	if gk == nil {
+		return nil, fmt.Errorf("unable to determine kind of action resource %T", action)
+	}
+	key := utils.KeyForReference(action, ref)
+
+	namespaces := []string{action.GetNamespace()}
+	if ns := ref.GetNamespace(); ns != "" && ns != action.GetNamespace() {
+		namespaces = append(namespaces, ns)
+	}
+	var result []artifactv1.RevisionApproval
+	for _, ns := range namespaces {
+		var list artifactv1.RevisionApprovalList
+		if err := client.List(ctx, &list, ctrlclient.InNamespace(ns)); err != nil {
+			return nil, fmt.Errorf("unable to list revision approvals in namespace '%s': %w", ns, err)
+		}
+		for _, approval := range list.Items {
+			if approves(&approval, *gk, action, key) {
+				result = append(result, approval)
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[j].CreationTimestamp.Before(&result[i].CreationTimestamp)
+	})
+	return result, nil
+}
+
+func isApproved(approvals []artifactv1.RevisionApproval, art *sourcev1.Artifact) bool {
+	for _, approval := range approvals {
+		if matchesPin(art, approval.Spec.Revision, approval.Spec.Digest) {
+			return true
+		}
+	}
+	return false
+}
+
+// approvedSource returns the source serving the most recently approved
+// revision, which is still retained. See pinnedSource.
+func approvedSource(ctx context.Context, client ctrlclient.Client, reader ctrlclient.Reader, indexed bool, action ActionResource, ref utils.SourceRefProvider, src ArtifactSource) (ArtifactSource, error) {
+	approvals, err := approvalsFor(ctx, client, action, ref)
+	if err != nil {
+		return nil, err
+	}
+	for _, approval := range approvals {
+		approved, err := pinnedSource(ctx, client, reader, indexed, src, approval.Spec.Revision, approval.Spec.Digest)
+		if err == nil {
+			return approved, nil
+		}
+		if !IsPinnedRevisionNotRetained(err) {
+			return nil, err
+		}
+	}
+	obj := src.(ctrlclient.Object)
+	revision := ""
+	if art := src.GetArtifact(); art != nil {
+		revision = art.Revision
+	}
+	return nil, &RevisionNotApprovedError{Source: fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName()), Revision: revision}
+}
+
+// checkApproval returns the reason for holding back the revision change of a
+// source for an action resource, or an empty string if the revision is approved.
+func checkApproval(ctx context.Context, client ctrlclient.Client, opts *Options, action ActionResource, src ArtifactSource) string {
+	ref, err := ResolvedSourceRef(action, opts.KindResolver)
+	if err != nil || ref == nil {
+		return fmt.Sprintf("unable to resolve source reference: %v", err)
+	}
+	approvals, err := approvalsFor(ctx, client, action, ref)
+	if err != nil {
+		return err.Error()
+	}
+	if !isApproved(approvals, src.GetArtifact()) {
+		return fmt.Sprintf("revision %s has not been approved", src.GetArtifact().Revision)
+	}
+	return ""
+}
+
+func requestsForRevisionApprovalOf[T any, P ActionResourcePointerType[T]](client ctrlclient.Client, scheme *runtime.Scheme, opts *Options) handler.MapFunc {
+	return func(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+		approval, ok := obj.(*artifactv1.RevisionApproval)
+		if !ok {
+			ctrl.LoggerFrom(ctx).Error(fmt.Errorf("expected a RevisionApproval, but got a %T", obj),
+				"failed to get reconcile requests for revision approval")
+			return nil
+		}
+		gk := utils.GetGroupKindForType[T, P](scheme)
+		if gk == nil {
+			return nil
+		}
+		ns, key := approvedSourceOf(approval)
+		src := approval.Spec.Source
+
+		var actions []runtime.Object
+		for _, o := range lookupByCoordinates[T, P](ctx, client, scheme, src.Group, src.Kind, ns, src.Name, nil) {
+			if approves(approval, *gk, o.(ActionResource), key) {
+				actions = append(actions, o)
+			}
+		}
+		return opts.RequestMapper(actions)
+	}
+}
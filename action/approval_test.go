@@ -0,0 +1,114 @@
+package action
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newTestApproval(name, revision string) *artifactv1.RevisionApproval {
+	approval := &artifactv1.RevisionApproval{}
+	approval.Namespace, approval.Name = "default", name
+	approval.CreationTimestamp = metav1.NewTime(saturday)
+	approval.Spec.Source = artifactv1.ApprovedSource{Group: artifactv1.GroupVersion.Group, Kind: artifactv1.ArtifactKind, Name: "source"}
+	approval.Spec.Revision = revision
+	return approval
+}
+
+func TestApprovedSource(t *testing.T) {
+	ctx := context.Background()
+
+	art := newTestArtifact("v3")
+	art.Status.History = []artifactv1.ArtifactRevision{
+		{Revision: "v2", URL: "http://storage/v2.tar.gz"},
+		{Revision: "v1", URL: "http://storage/v1.tar.gz"},
+	}
+	newer := newTestApproval("newer", "v2")
+	newer.CreationTimestamp = metav1.NewTime(saturday.Add(time.Hour))
+
+	other := newTestAction()
+	other.Name = "other"
+	dedicated := newTestApproval("dedicated", "v3")
+	dedicated.Spec.Action = &artifactv1.ApprovedAction{Group: testActionGroupVersion.Group, Kind: "TestAction", Name: other.Name}
+
+	tests := []struct {
+		name      string
+		action    *testAction
+		approvals []ctrlclient.Object
+		revision  string
+	}{
+		{name: "not approved", action: newTestAction()},
+		{name: "approved", action: newTestAction(), approvals: []ctrlclient.Object{newTestApproval("v1", "v1")}, revision: "v1"},
+		{name: "most recent approval", action: newTestAction(), approvals: []ctrlclient.Object{newTestApproval("v1", "v1"), newer}, revision: "v2"},
+		{name: "approval for other action", action: newTestAction(), approvals: []ctrlclient.Object{dedicated}},
+		{name: "approval for action", action: other, approvals: []ctrlclient.Object{dedicated}, revision: "v3"},
+		{name: "approved revision not retained", action: newTestAction(), approvals: []ctrlclient.Object{newTestApproval("v0", "v0")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			client := newTestClient(append(tt.approvals, art, tt.action)...)
+			ref, _ := ResolvedSourceRef(tt.action, nil)
+
+			src, err := approvedSource(ctx, client, client, true, tt.action, ref, art)
+			if tt.revision == "" {
+				g.Expect(IsRevisionNotApproved(err)).To(BeTrue())
+				g.Expect(checkApproval(ctx, client, EvalOptions(), tt.action, art)).NotTo(BeEmpty())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(src.GetArtifact().Revision).To(Equal(tt.revision))
+		})
+	}
+}
+
+func TestRevisionChangeHandlerApproval(t *testing.T) {
+	g := NewWithT(t)
+
+	old, new := newTestArtifact("v1"), newTestArtifact("v2")
+	action := newTestAction()
+	client := newTestClient(new, action, newTestApproval("v1", "v1"))
+	h := newRevisionChangeHandler[testAction, *testAction](client, client.Scheme(), EvalOptions(WithRevisionApprovals()), SourceRevisionChangePredicate{})
+
+	// the approval of the old revision does not let the new revision through
+	g.Expect(enqueued(h, old, new)).To(BeEmpty())
+
+	g.Expect(client.Create(context.Background(), newTestApproval("v2", "v2"))).To(Succeed())
+	g.Expect(enqueued(h, old, new)).To(ConsistOf(reconcile.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(action)}))
+
+	// approving a revision enqueues the approved actions
+	requests := requestsForRevisionApprovalOf[testAction, *testAction](client, client.Scheme(), EvalOptions())(context.Background(), newTestApproval("v2", "v2"))
+	g.Expect(requests).To(ConsistOf(reconcile.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(action)}))
+}
+
+func TestApprovedClusterArtifact(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	art := newTestClusterArtifact("v2")
+	art.Spec.AllowedNamespaces = []string{"default", "other"}
+	art.Status.History = []artifactv1.ArtifactRevision{{Revision: "v1", URL: "http://storage/shared/v1.tar.gz"}}
+	action := newClusterArtifactAction()
+	elsewhere := newClusterArtifactAction()
+	elsewhere.Namespace = "other"
+	approval := newTestApproval("v1", "v1")
+	approval.Spec.Source = artifactv1.ApprovedSource{Group: artifactv1.GroupVersion.Group, Kind: artifactv1.ClusterArtifactKind, Name: "shared"}
+	client := newTestClient(art, action, elsewhere, approval)
+
+	src, err := GetSource(ctx, client, action, WithRevisionApprovals())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(src.GetArtifact().Revision).To(Equal("v1"))
+
+	// approvals without action apply to the namespace of the approval only
+	_, err = GetSource(ctx, client, elsewhere, WithRevisionApprovals())
+	g.Expect(IsRevisionNotApproved(err)).To(BeTrue())
+
+	requests := requestsForRevisionApprovalOf[testAction, *testAction](client, client.Scheme(), EvalOptions())(ctx, approval)
+	g.Expect(requests).To(ConsistOf(reconcile.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(action)}))
+}
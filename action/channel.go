@@ -0,0 +1,106 @@
+package action
+
+import (
+	"context"
+	"fmt"
+
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/utils"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// ArtifactChannelGroupKind is the group kind of ArtifactChannel objects.
+var ArtifactChannelGroupKind = schema.GroupKind{Group: artifactv1.GroupVersion.Group, Kind: artifactv1.ArtifactChannelKind}
+
+// lookupChannelTarget reads the Artifact an ArtifactChannel points to.
+func lookupChannelTarget(ctx context.Context, reader ctrlclient.Reader, key ctrlclient.ObjectKey) (*artifactv1.Artifact, error) {
+	var channel artifactv1.ArtifactChannel
+	if err := reader.Get(ctx, key, &channel); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unable to get artifact channel '%s': %w", key, err)
+	}
+	target := ctrlclient.ObjectKey{Namespace: channel.Namespace, Name: channel.Spec.ArtifactRef.Name}
+	var art artifactv1.Artifact
+	if err := reader.Get(ctx, target, &art); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unable to get target '%s' of artifact channel '%s': %w", target, key, err)
+	}
+	return &art, nil
+}
+
+// lookupByChannelTarget finds the action resources referencing an ArtifactChannel pointing to
+// the given Artifact. The result maps the channel names to the referencing action resources.
+func lookupByChannelTarget[T any, P ActionResourcePointerType[T]](ctx context.Context, client ctrlclient.Client, scheme *runtime.Scheme, art *artifactv1.Artifact) map[string][]runtime.Object {
+	var channels artifactv1.ArtifactChannelList
+	if err := client.List(ctx, &channels, ctrlclient.InNamespace(art.Namespace)); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to list artifact channels for revision change")
+		return nil
+	}
+	result := map[string][]runtime.Object{}
+	for _, channel := range channels.Items {
+		if channel.Spec.ArtifactRef.Name != art.Name {
+			continue
+		}
+		result[channel.Name] = lookupByCoordinates[T, P](ctx, client, scheme, ArtifactChannelGroupKind.Group, ArtifactChannelGroupKind.Kind, channel.Namespace, channel.Name, art.GetArtifact())
+	}
+	return result
+}
+
+// newChannelChangeHandler creates the handler for ArtifactChannel objects
+// triggering the action resources of type T referencing them, if a channel
+// is retargeted. The revision of the new target Artifact passes the same
+// trigger pipeline as a revision change of the Artifact itself.
+func newChannelChangeHandler[T any, P ActionResourcePointerType[T]](client ctrlclient.Client, scheme *runtime.Scheme, opts *Options, pred predicate.Predicate) *revisionChangeHandler {
+	return &revisionChangeHandler{
+		scheme:    scheme,
+		opts:      opts,
+		predicate: pred,
+		evaluate: func(ctx context.Context, obj ctrlclient.Object) (ctrlclient.Object, []Decision) {
+			channel, ok := obj.(*artifactv1.ArtifactChannel)
+			if !ok {
+				ctrl.LoggerFrom(ctx).Error(fmt.Errorf("expected an ArtifactChannel, but got a %T", obj),
+					"failed to get reconcile requests for artifact channel change")
+				return obj, nil
+			}
+			return evaluateChannelChange[T, P](ctx, client, scheme, opts, channel)
+		},
+	}
+}
+
+// evaluateChannelChange runs the trigger pipeline for the action resources
+// referencing a channel with the current target of the channel. If the target
+// cannot be read, the action resources are triggered to report the error.
+func evaluateChannelChange[T any, P ActionResourcePointerType[T]](ctx context.Context, client ctrlclient.Client, scheme *runtime.Scheme, opts *Options, channel *artifactv1.ArtifactChannel) (ctrlclient.Object, []Decision) {
+	var decisions []Decision
+	actions := lookupByCoordinates[T, P](ctx, client, scheme, ArtifactChannelGroupKind.Group, ArtifactChannelGroupKind.Kind, channel.Namespace, channel.Name, nil)
+
+	target := ctrlclient.ObjectKey{Namespace: channel.Namespace, Name: channel.Spec.ArtifactRef.Name}
+	var art artifactv1.Artifact
+	if err := client.Get(ctx, target, &art); err != nil {
+		for _, a := range actions {
+			decisions = append(decisions, newDecision(scheme, a, true, StageIndexLookup,
+				fmt.Sprintf("references %s '%s/%s' with unavailable target: %s", artifactv1.ArtifactChannelKind, channel.Namespace, channel.Name, err)))
+		}
+		return channel, decisions
+	}
+
+	for _, a := range actions {
+		decisions = append(decisions, newDecision(scheme, a, true, StageIndexLookup,
+			fmt.Sprintf("references %s '%s/%s' pointing to artifact '%s'", artifactv1.ArtifactChannelKind, channel.Namespace, channel.Name, art.Name)))
+	}
+	return &art, applyGates(ctx, client, opts, &art, decisions)
+}
+
+// isChannelRef checks whether a source reference points to an ArtifactChannel.
+func isChannelRef(ref utils.SourceRefProvider) bool {
+	return ref.GetGroupKind() == ArtifactChannelGroupKind
+}
@@ -0,0 +1,76 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newTestChannel(target string) *artifactv1.ArtifactChannel {
+	channel := &artifactv1.ArtifactChannel{}
+	channel.Namespace, channel.Name = "default", "stable"
+	channel.Spec.ArtifactRef = artifactv1.ArtifactChannelTarget{Name: target}
+	return channel
+}
+
+func newTestChannelAction() *testAction {
+	action := newTestAction()
+	action.Spec.SourceRef.Kind = artifactv1.ArtifactChannelKind
+	action.Spec.SourceRef.Name = "stable"
+	return action
+}
+
+func TestLookupChannelSource(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	action := newTestChannelAction()
+	client := newTestClient(newTestArtifact("v1"), newTestChannel("source"), action)
+	ref, _ := ResolvedSourceRef(action, nil)
+
+	src, err := lookupSource(ctx, client, client, true, EvalOptions(WithArtifactChannels()), action, ref)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(src.GetArtifact().Revision).To(Equal("v1"))
+
+	// channel references are not followed, if channels are disabled
+	_, err = lookupSource(ctx, client, client, true, EvalOptions(), action, ref)
+	g.Expect(err).To(MatchError(ContainSubstring("artifact channels are disabled")))
+}
+
+func TestChannelChangeHandler(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	next := newTestArtifact("v2")
+	next.Name = "next"
+	old, new := newTestChannel("source"), newTestChannel("next")
+	new.Generation = 2
+	action := newTestChannelAction()
+	client := newTestClient(newTestArtifact("v1"), next, new, action)
+	request := reconcile.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(action)}
+
+	h := newChannelChangeHandler[testAction, *testAction](client, client.Scheme(), EvalOptions(WithArtifactChannels()), predicate.GenerationChangedPredicate{})
+	g.Expect(enqueued(h, old, new)).To(ConsistOf(request))
+	// changes not retargeting the channel are ignored
+	g.Expect(enqueued(h, new, new)).To(BeEmpty())
+
+	// the new target passes the trigger pipeline
+	recorder := NewDecisionRecorder(10)
+	h = newChannelChangeHandler[testAction, *testAction](client, client.Scheme(), EvalOptions(WithArtifactChannels(), WithRevisionApprovals(), WithDecisionRecorder(recorder)), predicate.GenerationChangedPredicate{})
+	g.Expect(enqueued(h, old, new)).To(BeEmpty())
+	records := recorder.Records()
+	g.Expect(records).To(HaveLen(1))
+	g.Expect(records[0].Stage).To(Equal(StageApproval))
+	g.Expect(records[0].SourceName).To(Equal("next"))
+	g.Expect(records[0].Revision).To(Equal("v2"))
+
+	approval := newTestApproval("v2", "v2")
+	approval.Spec.Source.Kind, approval.Spec.Source.Name = artifactv1.ArtifactChannelKind, "stable"
+	g.Expect(client.Create(ctx, approval)).To(Succeed())
+	g.Expect(enqueued(h, old, new)).To(ConsistOf(request))
+}
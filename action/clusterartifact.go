@@ -0,0 +1,77 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/fluxcd/pkg/runtime/acl"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// ClusterArtifactGroupKind is the group kind of ClusterArtifact objects.
+var ClusterArtifactGroupKind = schema.GroupKind{Group: artifactv1.GroupVersion.Group, Kind: artifactv1.ClusterArtifactKind}
+
+// allowsNamespace checks whether a ClusterArtifact may be consumed by action
+// resources in the given namespace. The namespace object is only read if
+// required by a namespace selector, this requires permissions to get, list
+// and watch namespaces.
+func allowsNamespace(ctx context.Context, client ctrlclient.Client, art *artifactv1.ClusterArtifact, namespace string) (bool, error) {
+	if slices.Contains(art.Spec.AllowedNamespaces, namespace) {
+		return true, nil
+	}
+	if art.Spec.NamespaceSelector == nil {
+		return false, nil
+	}
+	sel, err := metav1.LabelSelectorAsSelector(art.Spec.NamespaceSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid namespace selector in cluster artifact '%s': %w", art.Name, err)
+	}
+	var ns corev1.Namespace
+	if err := client.Get(ctx, ctrlclient.ObjectKey{Name: namespace}, &ns); err != nil {
+		return false, fmt.Errorf("unable to get namespace '%s': %w", namespace, err)
+	}
+	return sel.Matches(labels.Set(ns.GetLabels())), nil
+}
+
+// checkClusterArtifactAccess verifies that the namespace of an action resource
+// is allowed to consume a ClusterArtifact.
+func checkClusterArtifactAccess(ctx context.Context, client ctrlclient.Client, action ActionResource, art *artifactv1.ClusterArtifact) error {
+	ok, err := allowsNamespace(ctx, client, art, action.GetNamespace())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return acl.AccessDeniedError(
+			fmt.Sprintf("can't access '%s/%s', namespace '%s' is not allowed to consume it",
+				artifactv1.ClusterArtifactKind, art.Name, action.GetNamespace()))
+	}
+	return nil
+}
+
+// ClusterArtifactAccessChangePredicate triggers on changes of the namespaces
+// allowed to consume a ClusterArtifact.
+type ClusterArtifactAccessChangePredicate struct {
+	predicate.Funcs
+}
+
+func (ClusterArtifactAccessChangePredicate) Update(e event.UpdateEvent) bool {
+	oldArt, ok := e.ObjectOld.(*artifactv1.ClusterArtifact)
+	if !ok {
+		return false
+	}
+	newArt, ok := e.ObjectNew.(*artifactv1.ClusterArtifact)
+	if !ok {
+		return false
+	}
+	return !equality.Semantic.DeepEqual(oldArt.Spec.AllowedNamespaces, newArt.Spec.AllowedNamespaces) ||
+		!equality.Semantic.DeepEqual(oldArt.Spec.NamespaceSelector, newArt.Spec.NamespaceSelector)
+}
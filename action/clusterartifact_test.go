@@ -0,0 +1,140 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/acl"
+	. "github.com/onsi/gomega"
+	"github.com/openfluxcd/artifact/api/commonv1"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newTestClusterArtifact(revision string) *artifactv1.ClusterArtifact {
+	art := &artifactv1.ClusterArtifact{}
+	art.Name = "shared"
+	art.Spec.Revision = revision
+	art.Spec.URL = "http://storage/shared/" + revision + ".tar.gz"
+	return art
+}
+
+// newClusterArtifactAction returns an action referencing the ClusterArtifact "shared".
+func newClusterArtifactAction() *testAction {
+	action := newTestAction()
+	action.Spec.SourceRef = commonv1.SourceRef{APIVersion: artifactv1.GroupVersion.String(), Kind: artifactv1.ClusterArtifactKind, Name: "shared"}
+	return action
+}
+
+func TestClusterArtifactAccess(t *testing.T) {
+	tests := []struct {
+		name     string
+		allowed  []string
+		selector *metav1.LabelSelector
+		access   bool
+	}{
+		{name: "nothing allowed"},
+		{name: "allowed namespace", allowed: []string{"other", "default"}, access: true},
+		{name: "other namespace", allowed: []string{"other"}},
+		{name: "matching selector", selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}, access: true},
+		{name: "selector mismatch", selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}}},
+		{name: "empty selector", selector: &metav1.LabelSelector{}, access: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			art := newTestClusterArtifact("v1")
+			art.Spec.AllowedNamespaces = tt.allowed
+			art.Spec.NamespaceSelector = tt.selector
+			action := newClusterArtifactAction()
+			client := newTestClient(art, action, newTestNamespace("default", map[string]string{"team": "a"}))
+
+			src, err := GetSource(context.Background(), client, action)
+			if !tt.access {
+				g.Expect(acl.IsAccessDenied(err)).To(BeTrue())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(src.GetArtifact().URL).To(Equal(art.Spec.URL))
+		})
+	}
+}
+
+func TestClusterArtifactReferenceKeys(t *testing.T) {
+	g := NewWithT(t)
+	key := "openfluxcd.ocm.software/ClusterArtifact//shared"
+
+	action := newClusterArtifactAction()
+	g.Expect(SourceReferenceIndex[*testAction]()(action)).To(Equal([]string{key}))
+	// the namespace of the reference is ignored for cluster-scoped kinds
+	action.Spec.SourceRef.Namespace = "other"
+	g.Expect(SourceReferenceIndex[*testAction]()(action)).To(Equal([]string{key}))
+
+	ref := &commonv1.ClusterSourceRef{Kind: artifactv1.ClusterArtifactKind, Name: "shared"}
+	g.Expect(utils.IsClusterScoped(ref)).To(BeTrue())
+	g.Expect(utils.KeyForReference(action, ref)).To(Equal(key))
+
+	// actions of all namespaces are found for a revision change
+	other := newClusterArtifactAction()
+	other.Namespace = "other"
+	client := newTestClient(action, other)
+	actions := lookupBySourceObj[testAction, *testAction](context.Background(), client, client.Scheme(), newTestClusterArtifact("v2"), nil)
+	g.Expect(actions).To(HaveLen(2))
+}
+
+func TestPinnedClusterArtifact(t *testing.T) {
+	g := NewWithT(t)
+	art := newTestClusterArtifact("v2")
+	art.Spec.AllowedNamespaces = []string{"default"}
+	art.Status.History = []artifactv1.ArtifactRevision{{Revision: "v1", URL: "http://storage/shared/v1.tar.gz"}}
+	action := newClusterArtifactAction()
+	action.Spec.SourceRef.Revision = "v1"
+	client := newTestClient(art, action)
+
+	src, err := GetSource(context.Background(), client, action)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(src.GetArtifact().URL).To(Equal("http://storage/shared/v1.tar.gz"))
+	g.Expect(src.(*artifactv1.ClusterArtifact).Spec.Revision).To(Equal("v1"))
+
+	action.Spec.SourceRef.Revision = "v0"
+	_, err = GetSource(context.Background(), client, action)
+	g.Expect(IsPinnedRevisionNotRetained(err)).To(BeTrue())
+
+	// the pin does not bypass the access check
+	art.Spec.AllowedNamespaces = nil
+	action.Spec.SourceRef.Revision = "v1"
+	_, err = GetSource(context.Background(), newTestClient(art, action), action)
+	g.Expect(acl.IsAccessDenied(err)).To(BeTrue())
+}
+
+func TestClusterArtifactAccessChangePredicate(t *testing.T) {
+	g := NewWithT(t)
+	oldArt := newTestClusterArtifact("v1")
+	newArt := oldArt.DeepCopy()
+	newArt.Spec.Metadata = map[string]string{"key": "value"}
+	g.Expect(ClusterArtifactAccessChangePredicate{}.Update(event.UpdateEvent{ObjectOld: oldArt, ObjectNew: newArt})).To(BeFalse())
+
+	newArt.Spec.AllowedNamespaces = []string{"default"}
+	g.Expect(ClusterArtifactAccessChangePredicate{}.Update(event.UpdateEvent{ObjectOld: oldArt, ObjectNew: newArt})).To(BeTrue())
+
+	newArt = oldArt.DeepCopy()
+	newArt.Spec.NamespaceSelector = &metav1.LabelSelector{}
+	g.Expect(ClusterArtifactAccessChangePredicate{}.Update(event.UpdateEvent{ObjectOld: oldArt, ObjectNew: newArt})).To(BeTrue())
+}
+
+func TestRequestsForNamespaceChangeWithClusterArtifacts(t *testing.T) {
+	g := NewWithT(t)
+	shared := newClusterArtifactAction()
+	shared.Name = "shared"
+	client := newTestClient(shared, newTestAction())
+
+	requests := requestsForNamespaceChangeOf[testAction, *testAction](client, client.Scheme(), EvalOptions())(context.Background(), newTestNamespace("default", nil))
+	g.Expect(requests).To(ConsistOf(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "shared"}}))
+
+	requests = requestsForNamespaceChangeOf[testAction, *testAction](client, client.Scheme(), EvalOptions())(context.Background(), newTestNamespace("other", nil))
+	g.Expect(requests).To(BeEmpty())
+}
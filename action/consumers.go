@@ -0,0 +1,210 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/utils"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// LastAppliedRevisionProvider may be implemented by action resources to
+// provide the last applied revision of their source. If it is not implemented,
+// the field status.lastAppliedRevision is used, if present.
+type LastAppliedRevisionProvider interface {
+	GetLastAppliedRevision() string
+}
+
+// LastAppliedRevision returns the last applied revision of an action resource.
+func LastAppliedRevision(action ctrlclient.Object) string {
+	if p, ok := action.(LastAppliedRevisionProvider); ok {
+		return p.GetLastAppliedRevision()
+	}
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(action)
+	if err != nil {
+		return ""
+	}
+	rev, _, _ := unstructured.NestedString(u, "status", "lastAppliedRevision")
+	return rev
+}
+
+// ListConsumers returns the action resources of type T consuming the given
+// source object, either directly or, for Artifacts, via the owner of the Artifact.
+// With WithOwnerVerification, owners not matching the owner reference are ignored.
+// It requires the indices registered by Setup.
+func ListConsumers[T any, P ActionResourcePointerType[T]](ctx context.Context, client ctrlclient.Client, scheme *runtime.Scheme, src ctrlclient.Object, options ...Option) []runtime.Object {
+	opts := EvalOptions(options...)
+	actions := lookupBySourceObj[T, P](ctx, client, scheme, src, nil)
+	if art, ok := src.(*artifactv1.Artifact); ok {
+		for _, ref := range art.OwnerReferences {
+			if state, _ := checkOwner(ctx, opts, art.Namespace, ref); state != utils.OwnerValid {
+				continue
+			}
+			actions = append(actions, lookupByCoordinates[T, P](ctx, client, scheme, utils.ExtractGroupName(ref.APIVersion), ref.Kind, art.Namespace, ref.Name, nil)...)
+		}
+	}
+	return actions
+}
+
+// ConsumerReconciler publishes the action resources of type T consuming an
+// Artifact in the status of the Artifact. Entries of other consumer kinds are
+// preserved, so that controllers for several action types can maintain the
+// list of the same Artifact. The kind T is recorded in the tracked consumer
+// kinds, so that readers can distinguish missing consumers from missing
+// tracking. With InUseProtection, the Artifact gets a finalizer blocking its
+// deletion as long as there are consumers.
+//
+// The reconciler requires the indices registered by Setup.
+type ConsumerReconciler[T any, P ActionResourcePointerType[T]] struct {
+	Client          ctrlclient.Client
+	Scheme          *runtime.Scheme
+	InUseProtection bool
+	Options         []Option
+}
+
+func (r *ConsumerReconciler[T, P]) SetupWithManager(mgr ctrl.Manager) error {
+	gk := utils.GetGroupKindForType[T, P](r.Scheme)
+	if gk == nil {
+		return fmt.Errorf("action type not registered in scheme")
+	}
+	var _obj T
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(fmt.Sprintf("artifact-consumers-%s", strings.ToLower(gk.Kind))).
+		For(&artifactv1.Artifact{}).
+		Watches(P(&_obj), handler.EnqueueRequestsFromMapFunc(r.artifactsForAction)).
+		Complete(r)
+}
+
+func (r *ConsumerReconciler[T, P]) artifactsForAction(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	return artifactRequestsForAction(ctx, r.Client, EvalOptions(r.Options...), obj)
+}
+
+// artifactRequestsForAction returns requests for the Artifacts owned by the
+// source of an action resource.
+func artifactRequestsForAction(ctx context.Context, client ctrlclient.Client, opts *Options, obj ctrlclient.Object) []reconcile.Request {
+	action, ok := obj.(ActionResource)
+	if !ok {
+		return nil
+	}
+	ref, err := ResolvedSourceRef(action, opts.KindResolver)
+	if err != nil || ref == nil {
+		return nil
+	}
+	key := utils.KeyForReference(action, ref)
+	if key == "" {
+		return nil
+	}
+	var requests []reconcile.Request
+	if ref.GetGroupKind() == artifactv1.GroupVersion.WithKind(artifactv1.ArtifactKind).GroupKind() {
+		ns := ref.GetNamespace()
+		if ns == "" {
+			ns = action.GetNamespace()
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: ns, Name: ref.GetName()}})
+	}
+	var list artifactv1.ArtifactList
+	if err := client.List(ctx, &list, ctrlclient.MatchingFields{ArtifactOwnerIndexKey: key}); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to list artifacts for consumer change")
+		return requests
+	}
+	for _, art := range list.Items {
+		// Artifacts are indexed with their own key, too
+		req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: art.Namespace, Name: art.Name}}
+		if !slices.Contains(requests, req) {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+func (r *ConsumerReconciler[T, P]) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	var art artifactv1.Artifact
+	if err := r.Client.Get(ctx, req.NamespacedName, &art); err != nil {
+		return reconcile.Result{}, ctrlclient.IgnoreNotFound(err)
+	}
+	gk := utils.GetGroupKindForType[T, P](r.Scheme)
+
+	var consumers []artifactv1.ArtifactConsumer
+	for _, c := range art.Status.Consumers {
+		if c.Group != gk.Group || c.Kind != gk.Kind {
+			consumers = append(consumers, c)
+		}
+	}
+	for _, o := range ListConsumers[T, P](ctx, r.Client, r.Scheme, &art, r.Options...) {
+		action := o.(ctrlclient.Object)
+		if !action.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		consumers = append(consumers, artifactv1.ArtifactConsumer{
+			Group:               gk.Group,
+			Kind:                gk.Kind,
+			Namespace:           action.GetNamespace(),
+			Name:                action.GetName(),
+			LastAppliedRevision: LastAppliedRevision(action),
+		})
+	}
+	sort.Slice(consumers, func(i, j int) bool {
+		a, b := consumers[i], consumers[j]
+		return fmt.Sprintf("%s/%s/%s/%s", a.Group, a.Kind, a.Namespace, a.Name) < fmt.Sprintf("%s/%s/%s/%s", b.Group, b.Kind, b.Namespace, b.Name)
+	})
+
+	tracked := art.Status.TrackedConsumerKinds
+	if !slices.Contains(tracked, gk.String()) {
+		tracked = append(slices.Clone(tracked), gk.String())
+		sort.Strings(tracked)
+	}
+
+	if !equality.Semantic.DeepEqual(consumers, art.Status.Consumers) || len(tracked) != len(art.Status.TrackedConsumerKinds) {
+		patch := ctrlclient.MergeFromWithOptions(art.DeepCopy(), ctrlclient.MergeFromWithOptimisticLock{})
+		art.Status.Consumers = consumers
+		art.Status.TrackedConsumerKinds = tracked
+		if err := r.Client.Status().Patch(ctx, &art, patch); err != nil {
+			return reconcile.Result{}, fmt.Errorf("unable to update consumers of artifact: %w", err)
+		}
+	}
+
+	if !r.InUseProtection {
+		return reconcile.Result{}, nil
+	}
+	switch {
+	case art.DeletionTimestamp.IsZero():
+		if controllerutil.AddFinalizer(&art, artifactv1.InUseProtectionFinalizer) {
+			return reconcile.Result{}, r.Client.Update(ctx, &art)
+		}
+	case len(consumers) == 0:
+		if controllerutil.RemoveFinalizer(&art, artifactv1.InUseProtectionFinalizer) {
+			return reconcile.Result{}, r.Client.Update(ctx, &art)
+		}
+	default:
+		ctrl.LoggerFrom(ctx).Info("artifact deletion blocked, it is still in use", "consumers", len(consumers))
+	}
+	return reconcile.Result{}, nil
+}
+
+// checkOwner verifies an owner reference of an Artifact with the reader
+// configured by WithOwnerVerification. Without a reader, or if the owner
+// cannot be read, the reference is considered valid.
+func checkOwner(ctx context.Context, opts *Options, namespace string, ref metav1.OwnerReference) (utils.OwnerState, error) {
+	if opts.OwnerReader == nil {
+		return utils.OwnerValid, nil
+	}
+	state, err := utils.CheckOwner(ctx, opts.OwnerReader, namespace, ref)
+	if err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to verify artifact owner, considering it valid", "owner", ref.Name, "kind", ref.Kind)
+		return utils.OwnerValid, err
+	}
+	return state, nil
+}
@@ -0,0 +1,172 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestOwnerVerification(t *testing.T) {
+	ctx := context.Background()
+
+	// the action references the owner of the Artifact
+	owner := newTestAction()
+	owner.Name, owner.UID = "owner", "current"
+	owner.Spec.SourceRef.Name = "unrelated"
+	action := newTestAction()
+	action.Spec.SourceRef.APIVersion = testActionGroupVersion.String()
+	action.Spec.SourceRef.Kind = "TestAction"
+	action.Spec.SourceRef.Name = "owner"
+	ref, _ := ResolvedSourceRef(action, nil)
+
+	failing := fake.NewClientBuilder().WithScheme(newTestScheme()).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, client ctrlclient.WithWatch, key ctrlclient.ObjectKey, obj ctrlclient.Object, opts ...ctrlclient.GetOption) error {
+			return errors.New("forbidden")
+		},
+	}).Build()
+
+	tests := []struct {
+		name     string
+		uid      types.UID
+		reader   func(ctrlclient.Client) ctrlclient.Reader
+		notFound bool
+	}{
+		{name: "not verified", uid: "previous"},
+		{name: "valid", uid: "current", reader: func(c ctrlclient.Client) ctrlclient.Reader { return c }},
+		{name: "stale", uid: "previous", reader: func(c ctrlclient.Client) ctrlclient.Reader { return c }, notFound: true},
+		{name: "unreadable owner", uid: "previous", reader: func(ctrlclient.Client) ctrlclient.Reader { return failing }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			art := newTestArtifact("v1")
+			art.OwnerReferences = []metav1.OwnerReference{{APIVersion: testActionGroupVersion.String(), Kind: "TestAction", Name: "owner", UID: tt.uid}}
+			client := newTestClient(art, owner, action)
+			var options []Option
+			if tt.reader != nil {
+				options = append(options, WithOwnerVerification(tt.reader(client)))
+			}
+			opts := EvalOptions(options...)
+
+			src, err := lookupSource(ctx, client, client, true, opts, action, ref)
+			consumers := ListConsumers[testAction, *testAction](ctx, client, client.Scheme(), art, options...)
+			decisions := evaluateRevisionChange[testAction, *testAction](ctx, client, client.Scheme(), opts, art, art)
+			if tt.notFound {
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+				g.Expect(consumers).To(BeEmpty())
+				g.Expect(decisions).To(ConsistOf(And(HaveField("Included", false), HaveField("Stage", StageOwnerReference))))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(src.(*artifactv1.Artifact).Name).To(Equal("source"))
+			g.Expect(consumers).To(HaveLen(1))
+			g.Expect(decisions).To(ConsistOf(And(HaveField("Included", true), HaveField("Stage", StageOwnerReference))))
+		})
+	}
+}
+
+func TestConsumerReconciler(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	art := newTestArtifact("v1")
+	art.Status.Consumers = []artifactv1.ArtifactConsumer{{Group: "example.com", Kind: "Other", Namespace: "default", Name: "other"}}
+	art.Status.TrackedConsumerKinds = []string{"Other.example.com"}
+	client := newTestClient(art, newTestAction())
+	r := &ConsumerReconciler[testAction, *testAction]{Client: client, Scheme: client.Scheme()}
+
+	_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(art)})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(client.Get(ctx, ctrlclient.ObjectKeyFromObject(art), art)).To(Succeed())
+	g.Expect(art.Status.TrackedConsumerKinds).To(Equal([]string{"Other.example.com", "TestAction.test.example.com"}))
+	g.Expect(art.Status.Consumers).To(ConsistOf(
+		HaveField("Kind", "Other"),
+		And(HaveField("Kind", "TestAction"), HaveField("Name", "app"), HaveField("LastAppliedRevision", "v1")),
+	))
+}
+
+func TestConsumerReconcilerInUseProtection(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	art := newTestArtifact("v1")
+	action := newTestAction()
+	client := newTestClient(art, action)
+	r := &ConsumerReconciler[testAction, *testAction]{Client: client, Scheme: client.Scheme(), InUseProtection: true}
+	reconcileArtifact := func() {
+		t.Helper()
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(art)})
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+
+	reconcileArtifact()
+	g.Expect(client.Get(ctx, ctrlclient.ObjectKeyFromObject(art), art)).To(Succeed())
+	g.Expect(art.Finalizers).To(ConsistOf(artifactv1.InUseProtectionFinalizer))
+
+	// the deletion is blocked as long as the artifact is consumed
+	g.Expect(client.Delete(ctx, art)).To(Succeed())
+	reconcileArtifact()
+	g.Expect(client.Get(ctx, ctrlclient.ObjectKeyFromObject(art), art)).To(Succeed())
+	g.Expect(art.DeletionTimestamp.IsZero()).To(BeFalse())
+	g.Expect(art.Finalizers).To(ConsistOf(artifactv1.InUseProtectionFinalizer))
+	g.Expect(art.Status.Consumers).To(HaveLen(1))
+
+	g.Expect(client.Delete(ctx, action)).To(Succeed())
+	reconcileArtifact()
+	err := client.Get(ctx, ctrlclient.ObjectKeyFromObject(art), art)
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue(), "unexpected error %v", err)
+}
+
+func TestConsumerReconcilerWithoutInUseProtection(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	art := newTestArtifact("v1")
+	art.Finalizers = []string{artifactv1.InUseProtectionFinalizer}
+	deleted := newTestAction()
+	deleted.Name, deleted.Finalizers = "deleted", []string{"test"}
+	now := metav1.Now()
+	deleted.DeletionTimestamp = &now
+	client := newTestClient(art, newTestAction(), deleted)
+	r := &ConsumerReconciler[testAction, *testAction]{Client: client, Scheme: client.Scheme()}
+
+	_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(art)})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(client.Get(ctx, ctrlclient.ObjectKeyFromObject(art), art)).To(Succeed())
+	// actions being deleted are no consumers anymore
+	g.Expect(art.Status.Consumers).To(ConsistOf(HaveField("Name", "app")))
+	// the finalizer is not managed without in-use protection
+	g.Expect(art.Finalizers).To(ConsistOf(artifactv1.InUseProtectionFinalizer))
+}
+
+func TestArtifactRequestsForAction(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	owned := newTestArtifact("v1")
+	owned.Name = "repo"
+	owned.OwnerReferences = []metav1.OwnerReference{{APIVersion: "source.toolkit.fluxcd.io/v1", Kind: "GitRepository", Name: "repo", UID: "uid"}}
+	client := newTestClient(owned, newTestArtifact("v1"))
+
+	// Artifacts referenced directly
+	requests := artifactRequestsForAction(ctx, client, EvalOptions(), newTestAction())
+	g.Expect(requests).To(ConsistOf(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "source"}}))
+
+	// Artifacts owned by the referenced source
+	action := newTestAction()
+	action.Spec.SourceRef.APIVersion, action.Spec.SourceRef.Kind, action.Spec.SourceRef.Name = "source.toolkit.fluxcd.io/v1", "GitRepository", "repo"
+	requests = artifactRequestsForAction(ctx, client, EvalOptions(), action)
+	g.Expect(requests).To(ConsistOf(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "repo"}}))
+
+	g.Expect(artifactRequestsForAction(ctx, client, EvalOptions(), newTestArtifact("v1"))).To(BeEmpty())
+}
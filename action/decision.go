@@ -0,0 +1,200 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/utils"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Stage names a step of the trigger pipeline deciding whether an action
+// resource is reconciled for a source change.
+type Stage string
+
+const (
+	StageRevisionChange     Stage = "SourceRevisionChangePredicate"
+	StageAllowedSourceKinds Stage = "AllowedSourceKinds"
+	StageIndexLookup        Stage = "IndexLookup"
+	StageOwnerReference     Stage = "OwnerReference"
+	StageTriggerPredicate   Stage = "TriggerPredicate"
+	StageRollout            Stage = "Rollout"
+	StagePinnedRevision     Stage = "PinnedRevision"
+	StageApproval           Stage = "Approval"
+	StageMaintenanceWindow  Stage = "MaintenanceWindow"
+)
+
+// Decision describes whether an action resource is triggered by a source change,
+// and which stage of the trigger pipeline made the decision.
+type Decision struct {
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Included  bool   `json:"included"`
+	Stage     Stage  `json:"stage"`
+	Reason    string `json:"reason"`
+
+	object runtime.Object
+	// deferUntil is the time a revision change held back by the maintenance
+	// windows of the action resource is released.
+	deferUntil time.Time
+}
+
+func newDecision(scheme *runtime.Scheme, obj runtime.Object, included bool, stage Stage, reason string) Decision {
+	o := obj.(ctrlclient.Object)
+	d := Decision{
+		Namespace: o.GetNamespace(),
+		Name:      o.GetName(),
+		Included:  included,
+		Stage:     stage,
+		Reason:    reason,
+		object:    obj,
+	}
+	if gk := utils.GetGroupKindForObject(scheme, o); gk != nil {
+		d.Group, d.Kind = gk.Group, gk.Kind
+	}
+	return d
+}
+
+// evaluateRevisionChange runs the trigger pipeline for a source object. It finds all
+// action resources referencing the source directly, or, for an Artifact, referencing
+// a valid owner of the Artifact, and applies pins, approvals, maintenance windows, the
+// configured TriggerPredicate and rollouts. The evaluation only reads objects, acting
+// on the decisions, like deferring triggers, is left to the caller.
+func evaluateRevisionChange[T any, P ActionResourcePointerType[T]](ctx context.Context, client ctrlclient.Client, scheme *runtime.Scheme, opts *Options, obj ctrlclient.Object, src ArtifactSource) []Decision {
+	log := ctrl.LoggerFrom(ctx)
+
+	var decisions []Decision
+	gk := utils.GetGroupKindForObject(scheme, obj)
+	for _, a := range lookupBySourceObj[T, P](ctx, client, scheme, obj, src.GetArtifact()) {
+		decisions = append(decisions, newDecision(scheme, a, true, StageIndexLookup,
+			fmt.Sprintf("references %s '%s/%s'", gk.Kind, obj.GetNamespace(), obj.GetName())))
+	}
+
+	if art, ok := src.(*artifactv1.Artifact); ok && opts.ArtifactChannelsEnabled() {
+		for channel, actions := range lookupByChannelTarget[T, P](ctx, client, scheme, art) {
+			for _, a := range actions {
+				decisions = append(decisions, newDecision(scheme, a, true, StageIndexLookup,
+					fmt.Sprintf("references %s '%s/%s' pointing to artifact '%s'", artifactv1.ArtifactChannelKind, art.Namespace, channel, art.Name)))
+			}
+		}
+	}
+
+	if art, ok := src.(*artifactv1.Artifact); ok {
+		for _, ref := range art.OwnerReferences {
+			state, _ := checkOwner(ctx, opts, art.Namespace, ref)
+			actions := lookupByCoordinates[T, P](ctx, client, scheme, utils.ExtractGroupName(ref.APIVersion), ref.Kind, art.Namespace, ref.Name, art.GetArtifact())
+			if state != utils.OwnerValid {
+				log.Info("ignoring artifact owner", "artifact", ctrlclient.ObjectKeyFromObject(art), "owner", ref.Name, "kind", ref.Kind, "state", state)
+				for _, a := range actions {
+					decisions = append(decisions, newDecision(scheme, a, false, StageOwnerReference,
+						fmt.Sprintf("owner %s '%s' of artifact '%s' is %s", ref.Kind, ref.Name, art.Name, state)))
+				}
+				continue
+			}
+			for _, a := range actions {
+				decisions = append(decisions, newDecision(scheme, a, true, StageOwnerReference,
+					fmt.Sprintf("references %s '%s/%s' owning artifact '%s'", ref.Kind, art.Namespace, ref.Name, art.Name)))
+			}
+		}
+	}
+
+	return applyGates(ctx, client, opts, src, decisions)
+}
+
+// applyGates excludes the action resources from a revision change of the source,
+// which are held back by pins, approvals, maintenance windows, the configured
+// TriggerPredicate or rollouts.
+func applyGates(ctx context.Context, client ctrlclient.Client, opts *Options, src ArtifactSource, decisions []Decision) []Decision {
+	for i, d := range decisions {
+		if !d.Included {
+			continue
+		}
+		action := d.object.(ActionResource)
+		if revision, digest := pinOf(action); revision != "" || digest != "" {
+			if !matchesPin(src.GetArtifact(), revision, digest) {
+				decisions[i].Included = false
+				decisions[i].Stage = StagePinnedRevision
+				decisions[i].Reason = fmt.Sprintf("source reference is pinned to %s", pinString(revision, digest))
+			}
+			continue
+		}
+		if opts.RevisionApprovalsRequired() {
+			if reason := checkApproval(ctx, client, opts, action, src); reason != "" {
+				decisions[i].Included = false
+				decisions[i].Stage = StageApproval
+				decisions[i].Reason = reason
+				continue
+			}
+		}
+		if opts.MaintenanceWindowsEnabled() {
+			if reason, next := checkMaintenanceWindow(ctx, client, opts, action, src); reason != "" {
+				decisions[i].Included = false
+				decisions[i].Stage = StageMaintenanceWindow
+				decisions[i].Reason = reason
+				decisions[i].deferUntil = next
+			}
+		}
+	}
+
+	for i := range decisions {
+		if decisions[i].Included && !opts.TriggerPredicate(decisions[i].object.(ActionResource), src) {
+			decisions[i].Included = false
+			decisions[i].Stage = StageTriggerPredicate
+			decisions[i].Reason = "rejected by trigger predicate"
+		}
+	}
+	if art, ok := src.(*artifactv1.Artifact); ok && opts.Rollout != nil {
+		holdBackRollout(art, opts.Rollout, decisions)
+	}
+	return decisions
+}
+
+// holdBackRollout excludes the consumers not released by the rollout of the
+// current revision. Until the RolloutReconciler has started the rollout, all
+// consumers are held back, it triggers the consumers of a wave once it is released.
+func holdBackRollout(art *artifactv1.Artifact, policy *RolloutPolicy, decisions []Decision) {
+	if rollout := art.Status.Rollout; rollout != nil && rollout.Revision == art.Spec.Revision {
+		// the rollout has already been started, keep released consumers
+		released := map[string]bool{}
+		for _, n := range rollout.Released {
+			released[n] = true
+		}
+		for i, d := range decisions {
+			if d.Included && rollout.Phase != artifactv1.RolloutCompleted && !released[d.Namespace+"/"+d.Name] {
+				decisions[i].Included = false
+				decisions[i].Stage = StageRollout
+				decisions[i].Reason = fmt.Sprintf("waiting for wave %d of the rollout of revision %s", rollout.Wave, rollout.Revision)
+			}
+		}
+		return
+	}
+
+	var included []ctrlclient.Object
+	for _, d := range decisions {
+		if d.Included {
+			included = append(included, d.object.(ctrlclient.Object))
+		}
+	}
+	waves := policy.Assign(included)
+	waveOf := map[string]int{}
+	for w, wave := range waves {
+		for _, a := range wave {
+			waveOf[objectName(a)] = w
+		}
+	}
+	for i, d := range decisions {
+		if !d.Included {
+			continue
+		}
+		decisions[i].Included = false
+		decisions[i].Stage = StageRollout
+		decisions[i].Reason = fmt.Sprintf("scheduled for wave %d of %d of the rollout of revision %s, waiting for the rollout to be started",
+			waveOf[d.Namespace+"/"+d.Name], len(waves), art.Spec.Revision)
+	}
+}
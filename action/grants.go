@@ -0,0 +1,146 @@
+package action
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fluxcd/pkg/runtime/acl"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// CheckSourceAccessGrants verifies that a cross-namespace reference of the given object,
+// usually an action resource, is permitted by a SourceAccessGrant in the namespace of the
+// referenced source. If not, an acl.AccessDeniedError is returned.
+func CheckSourceAccessGrants(ctx context.Context, client ctrlclient.Client, action ctrlclient.Object, ref utils.SourceRefProvider) error {
+	gk := utils.GetGroupKindForObject(client.Scheme(), action)
+	if gk == nil {
+		return fmt.Errorf("unable to determine kind of %T", action)
+	}
+
+	var grants artifactv1.SourceAccessGrantList
+	if err := client.List(ctx, &grants, ctrlclient.InNamespace(ref.GetNamespace())); err != nil {
+		return fmt.Errorf("unable to list source access grants in namespace '%s': %w", ref.GetNamespace(), err)
+	}
+
+	var ns *corev1.Namespace
+	for _, grant := range grants.Items {
+		if !grantsTo(&grant, ref.GetGroupKind(), ref.GetName()) {
+			continue
+		}
+		for _, from := range grant.Spec.From {
+			if from.Group != gk.Group || from.Kind != gk.Kind {
+				continue
+			}
+			if from.Namespace == action.GetNamespace() {
+				return nil
+			}
+			if from.NamespaceSelector == nil {
+				continue
+			}
+			if ns == nil {
+				ns = &corev1.Namespace{}
+				if err := client.Get(ctx, ctrlclient.ObjectKey{Name: action.GetNamespace()}, ns); err != nil {
+					return fmt.Errorf("unable to get namespace '%s': %w", action.GetNamespace(), err)
+				}
+			}
+			sel, err := metav1.LabelSelectorAsSelector(from.NamespaceSelector)
+			if err != nil {
+				return fmt.Errorf("invalid namespace selector in source access grant '%s/%s': %w", grant.Namespace, grant.Name, err)
+			}
+			if sel.Matches(labels.Set(ns.GetLabels())) {
+				return nil
+			}
+		}
+	}
+	return acl.AccessDeniedError(
+		fmt.Sprintf("can't access '%s/%s/%s', no source access grant permits the reference from %s '%s/%s'",
+			ref.GetGroupKind().Kind, ref.GetNamespace(), ref.GetName(), gk.Kind, action.GetNamespace(), action.GetName()))
+}
+
+func grantsTo(grant *artifactv1.SourceAccessGrant, gk schema.GroupKind, name string) bool {
+	for _, to := range grant.Spec.To {
+		if to.Group == gk.Group && to.Kind == gk.Kind && (to.Name == "" || to.Name == name) {
+			return true
+		}
+	}
+	return false
+}
+
+func requestsForSourceAccessGrantChangeOf[T any, P ActionResourcePointerType[T]](client ctrlclient.Client, scheme *runtime.Scheme, opts *Options) handler.MapFunc {
+	// Queues requests for all action resources of other namespaces referencing a source
+	// covered by the grant. The handler is called for the old and the new object on updates,
+	// so actions losing their access are requeued as well.
+	return func(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+		log := ctrl.LoggerFrom(ctx)
+		grant, ok := obj.(*artifactv1.SourceAccessGrant)
+		if !ok {
+			log.Error(fmt.Errorf("expected a SourceAccessGrant, but got a %T", obj),
+				"failed to get reconcile requests for source access grant change")
+			return nil
+		}
+
+		list := utils.CreateListForType[T, P](scheme)
+		if err := client.List(ctx, list); err != nil {
+			log.Error(err, "failed to list objects for source access grant change")
+			return nil
+		}
+		objs, _ := meta.ExtractList(list)
+
+		var actions []runtime.Object
+		for _, o := range objs {
+			a := o.(ActionResource)
+			if a.GetNamespace() == grant.Namespace {
+				continue
+			}
+			ref, err := ResolvedSourceRef(a, opts.KindResolver)
+			if err != nil || ref == nil {
+				continue
+			}
+			if ref.GetNamespace() == grant.Namespace && grantsTo(grant, ref.GetGroupKind(), ref.GetName()) {
+				actions = append(actions, o)
+			}
+		}
+		return opts.RequestMapper(actions)
+	}
+}
+
+func requestsForNamespaceChangeOf[T any, P ActionResourcePointerType[T]](client ctrlclient.Client, scheme *runtime.Scheme, opts *Options) handler.MapFunc {
+	// Queues requests for the action resources of a namespace, whose access to their source
+	// may depend on the labels of the namespace, because it is granted by a namespace selector
+	// of a SourceAccessGrant or a ClusterArtifact.
+	return func(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+		log := ctrl.LoggerFrom(ctx)
+		list := utils.CreateListForType[T, P](scheme)
+		if err := client.List(ctx, list, ctrlclient.InNamespace(obj.GetName())); err != nil {
+			log.Error(err, "failed to list objects for namespace change")
+			return nil
+		}
+		objs, _ := meta.ExtractList(list)
+
+		var actions []runtime.Object
+		for _, o := range objs {
+			ref, err := ResolvedSourceRef(o.(ActionResource), opts.KindResolver)
+			if err != nil || ref == nil {
+				continue
+			}
+			switch {
+			case ref.GetGroupKind() == ClusterArtifactGroupKind:
+				actions = append(actions, o)
+			case opts.SourceAccessGrantsEnabled() && !utils.IsClusterScoped(ref) && ref.GetNamespace() != obj.GetName():
+				actions = append(actions, o)
+			}
+		}
+		return opts.RequestMapper(actions)
+	}
+}
@@ -0,0 +1,144 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/acl"
+	. "github.com/onsi/gomega"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newTestGrant(from artifactv1.SourceAccessGrantFrom, to artifactv1.SourceAccessGrantTo) *artifactv1.SourceAccessGrant {
+	grant := &artifactv1.SourceAccessGrant{}
+	grant.Namespace, grant.Name = "sources", "grant"
+	grant.Spec.From = []artifactv1.SourceAccessGrantFrom{from}
+	grant.Spec.To = []artifactv1.SourceAccessGrantTo{to}
+	return grant
+}
+
+func newTestNamespace(name string, labels map[string]string) *corev1.Namespace {
+	ns := &corev1.Namespace{}
+	ns.Name, ns.Labels = name, labels
+	return ns
+}
+
+// newCrossNamespaceAction returns an action referencing the Artifact "source" in the namespace "sources".
+func newCrossNamespaceAction() *testAction {
+	action := newTestAction()
+	action.Spec.SourceRef.Namespace = "sources"
+	return action
+}
+
+func TestCheckSourceAccessGrants(t *testing.T) {
+	fromAction := artifactv1.SourceAccessGrantFrom{Group: testActionGroupVersion.Group, Kind: "TestAction", Namespace: "default"}
+	toArtifacts := artifactv1.SourceAccessGrantTo{Group: artifactv1.GroupVersion.Group, Kind: artifactv1.ArtifactKind}
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}
+
+	tests := []struct {
+		name    string
+		grant   *artifactv1.SourceAccessGrant
+		labels  map[string]string
+		allowed bool
+	}{
+		{name: "no grant"},
+		{name: "namespace", grant: newTestGrant(fromAction, toArtifacts), allowed: true},
+		{name: "other namespace",
+			grant: newTestGrant(artifactv1.SourceAccessGrantFrom{Group: fromAction.Group, Kind: fromAction.Kind, Namespace: "other"}, toArtifacts)},
+		{name: "other kind",
+			grant: newTestGrant(artifactv1.SourceAccessGrantFrom{Group: fromAction.Group, Kind: "Other", Namespace: "default"}, toArtifacts)},
+		{name: "named source", allowed: true,
+			grant: newTestGrant(fromAction, artifactv1.SourceAccessGrantTo{Group: toArtifacts.Group, Kind: toArtifacts.Kind, Name: "source"})},
+		{name: "other source",
+			grant: newTestGrant(fromAction, artifactv1.SourceAccessGrantTo{Group: toArtifacts.Group, Kind: toArtifacts.Kind, Name: "other"})},
+		{name: "other source kind",
+			grant: newTestGrant(fromAction, artifactv1.SourceAccessGrantTo{Group: toArtifacts.Group, Kind: artifactv1.ArtifactChannelKind})},
+		{name: "namespace selector", labels: map[string]string{"team": "a"}, allowed: true,
+			grant: newTestGrant(artifactv1.SourceAccessGrantFrom{Group: fromAction.Group, Kind: fromAction.Kind, NamespaceSelector: selector}, toArtifacts)},
+		{name: "namespace selector mismatch", labels: map[string]string{"team": "b"},
+			grant: newTestGrant(artifactv1.SourceAccessGrantFrom{Group: fromAction.Group, Kind: fromAction.Kind, NamespaceSelector: selector}, toArtifacts)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			action := newCrossNamespaceAction()
+			objs := []ctrlclient.Object{newTestNamespace("default", tt.labels), action}
+			if tt.grant != nil {
+				objs = append(objs, tt.grant)
+			}
+			client := newTestClient(objs...)
+			ref, _ := ResolvedSourceRef(action, nil)
+
+			err := CheckSourceAccessGrants(context.Background(), client, action, ref)
+			if tt.allowed {
+				g.Expect(err).ToNot(HaveOccurred())
+			} else {
+				g.Expect(acl.IsAccessDenied(err)).To(BeTrue())
+			}
+		})
+	}
+}
+
+func TestGetSourceWithSourceAccessGrants(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	src := newTestArtifact("v1")
+	src.Namespace = "sources"
+	action := newCrossNamespaceAction()
+	grant := newTestGrant(
+		artifactv1.SourceAccessGrantFrom{Group: testActionGroupVersion.Group, Kind: "TestAction", Namespace: "default"},
+		artifactv1.SourceAccessGrantTo{Group: artifactv1.GroupVersion.Group, Kind: artifactv1.ArtifactKind},
+	)
+
+	_, err := GetSource(ctx, newTestClient(src, action), action, WithSourceAccessGrants())
+	g.Expect(acl.IsAccessDenied(err)).To(BeTrue())
+
+	_, err = GetSource(ctx, newTestClient(src, action), action)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	result, err := GetSource(ctx, newTestClient(src, action, grant), action, WithSourceAccessGrants())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.GetArtifact().Revision).To(Equal("v1"))
+}
+
+func TestRequestsForSourceAccessGrantChange(t *testing.T) {
+	g := NewWithT(t)
+	granted := newCrossNamespaceAction()
+	granted.Name = "granted"
+	other := newCrossNamespaceAction()
+	other.Name = "other"
+	other.Spec.SourceRef.Name = "other"
+	local := newTestAction()
+	local.Namespace, local.Name = "sources", "local"
+	client := newTestClient(granted, other, local)
+	grant := newTestGrant(
+		artifactv1.SourceAccessGrantFrom{Group: testActionGroupVersion.Group, Kind: "TestAction", Namespace: "default"},
+		artifactv1.SourceAccessGrantTo{Group: artifactv1.GroupVersion.Group, Kind: artifactv1.ArtifactKind, Name: "source"},
+	)
+
+	requests := requestsForSourceAccessGrantChangeOf[testAction, *testAction](client, client.Scheme(), EvalOptions())(context.Background(), grant)
+	g.Expect(requests).To(ConsistOf(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "granted"}}))
+}
+
+func TestRequestsForNamespaceChange(t *testing.T) {
+	g := NewWithT(t)
+	cross := newCrossNamespaceAction()
+	cross.Name = "cross"
+	local := newTestAction()
+	local.Name = "local"
+	elsewhere := newCrossNamespaceAction()
+	elsewhere.Namespace, elsewhere.Name = "other", "elsewhere"
+	client := newTestClient(cross, local, elsewhere)
+	ns := newTestNamespace("default", map[string]string{"team": "a"})
+
+	requests := requestsForNamespaceChangeOf[testAction, *testAction](client, client.Scheme(), EvalOptions())(context.Background(), ns)
+	g.Expect(requests).To(BeEmpty())
+
+	requests = requestsForNamespaceChangeOf[testAction, *testAction](client, client.Scheme(), EvalOptions(WithSourceAccessGrants()))(context.Background(), ns)
+	g.Expect(requests).To(ConsistOf(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "cross"}}))
+}
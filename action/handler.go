@@ -0,0 +1,126 @@
+package action
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// evaluator runs the trigger pipeline for a changed object. It returns the
+// source object the decisions have been made for.
+type evaluator func(ctx context.Context, obj ctrlclient.Object) (ctrlclient.Object, []Decision)
+
+// revisionChangeHandler enqueues the action resources triggered by the change
+// of a source object, or of another object pointing to a source. The predicate
+// filters the events, rejected source updates are recorded if a DecisionRecorder
+// is configured.
+//
+// Unlike handler.EnqueueRequestsFromMapFunc, it evaluates only the new object
+// of an update event. Evaluating the old object, too, would let pins,
+// approvals, maintenance windows and rollouts matching the previous revision
+// trigger the action resources for the new one.
+type revisionChangeHandler struct {
+	scheme    *runtime.Scheme
+	opts      *Options
+	predicate predicate.Predicate
+	evaluate  evaluator
+}
+
+var _ handler.EventHandler = (*revisionChangeHandler)(nil)
+
+// newRevisionChangeHandler creates the handler for source objects triggering
+// action resources of type T.
+func newRevisionChangeHandler[T any, P ActionResourcePointerType[T]](client ctrlclient.Client, scheme *runtime.Scheme, opts *Options, pred predicate.Predicate) *revisionChangeHandler {
+	return &revisionChangeHandler{
+		scheme:    scheme,
+		opts:      opts,
+		predicate: pred,
+		evaluate: func(ctx context.Context, obj ctrlclient.Object) (ctrlclient.Object, []Decision) {
+			src, ok := obj.(ArtifactSource)
+			if !ok {
+				ctrl.LoggerFrom(ctx).Error(fmt.Errorf("expected an object conformed with GetArtifact() method, but got a %T", obj),
+					"failed to get reconcile requests for revision change")
+				return obj, nil
+			}
+			// If we do not have an artifact, we have no requests to make
+			if src.GetArtifact() == nil {
+				return obj, nil
+			}
+			return obj, evaluateRevisionChange[T, P](ctx, client, scheme, opts, obj, src)
+		},
+	}
+}
+
+func (h *revisionChangeHandler) Create(ctx context.Context, e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	if h.predicate.Create(e) {
+		h.enqueue(ctx, e.Object, q)
+	}
+}
+
+func (h *revisionChangeHandler) Update(ctx context.Context, e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	if h.predicate.Update(e) {
+		h.enqueue(ctx, e.ObjectNew, q)
+		return
+	}
+	h.recordUnchanged(ctx, e.ObjectNew)
+}
+
+func (h *revisionChangeHandler) Delete(ctx context.Context, e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	if h.predicate.Delete(e) {
+		h.enqueue(ctx, e.Object, q)
+	}
+}
+
+func (h *revisionChangeHandler) Generic(ctx context.Context, e event.GenericEvent, q workqueue.RateLimitingInterface) {
+	if h.predicate.Generic(e) {
+		h.enqueue(ctx, e.Object, q)
+	}
+}
+
+func (h *revisionChangeHandler) enqueue(ctx context.Context, obj ctrlclient.Object, q workqueue.RateLimitingInterface) {
+	for _, req := range h.requests(ctx, obj) {
+		q.Add(req)
+	}
+}
+
+// requests records the decisions for the changed object, defers the triggers
+// held back by maintenance windows and maps the included action resources
+// to requests.
+func (h *revisionChangeHandler) requests(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	src, decisions := h.evaluate(ctx, obj)
+	h.opts.DecisionRecorder.Record(ctx, h.scheme, src, decisions...)
+
+	var actions []runtime.Object
+	for _, d := range decisions {
+		if d.Included {
+			actions = append(actions, d.object)
+		}
+		if !d.deferUntil.IsZero() {
+			h.opts.deferred.Defer(d.object.(ctrlclient.Object), d.deferUntil)
+		}
+	}
+	return h.opts.RequestMapper(actions)
+}
+
+// recordUnchanged records the rejection of a source update by the predicate
+// for all action resources which would otherwise be triggered.
+func (h *revisionChangeHandler) recordUnchanged(ctx context.Context, obj ctrlclient.Object) {
+	if h.opts.DecisionRecorder == nil {
+		return
+	}
+	s, ok := obj.(ArtifactSource)
+	if !ok || s.GetArtifact() == nil {
+		return
+	}
+	src, decisions := h.evaluate(ctx, obj)
+	reject(decisions, StageRevisionChange, fmt.Sprintf("revision %s of the source has not changed", s.GetArtifact().Revision))
+	h.opts.DecisionRecorder.Record(ctx, h.scheme, src, decisions...)
+}
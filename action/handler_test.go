@@ -0,0 +1,96 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"k8s.io/client-go/util/workqueue"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// enqueued returns the requests queued by the handler for an update of an object.
+func enqueued(h *revisionChangeHandler, old, new ctrlclient.Object) []reconcile.Request {
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer q.ShutDown()
+	h.Update(context.Background(), event.UpdateEvent{ObjectOld: old, ObjectNew: new}, q)
+
+	var requests []reconcile.Request
+	for q.Len() > 0 {
+		item, _ := q.Get()
+		requests = append(requests, item.(reconcile.Request))
+		q.Done(item)
+	}
+	return requests
+}
+
+func TestRevisionChangeHandler(t *testing.T) {
+	g := NewWithT(t)
+
+	old, new := newTestArtifact("v1"), newTestArtifact("v2")
+	action := newTestAction()
+	client := newTestClient(new, action)
+	h := newRevisionChangeHandler[testAction, *testAction](client, client.Scheme(), EvalOptions(), SourceRevisionChangePredicate{})
+
+	g.Expect(enqueued(h, old, new)).To(ConsistOf(reconcile.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(action)}))
+
+	// unchanged revisions are filtered by the predicate
+	g.Expect(enqueued(h, new, new)).To(BeEmpty())
+}
+
+func TestRevisionChangeHandlerRollout(t *testing.T) {
+	g := NewWithT(t)
+
+	old, new := newTestArtifact("v1"), newTestArtifact("v2")
+	// the rollout of the previous revision has been completed
+	old.Status.Rollout = &artifactv1.RolloutStatus{Revision: "v1", Phase: artifactv1.RolloutCompleted, Wave: 1, Waves: 1, Released: []string{"default/app"}}
+	new.Status.Rollout = old.Status.Rollout.DeepCopy()
+	action := newTestAction()
+	client := newTestClient(new, action)
+	opts := EvalOptions(WithRollout(&RolloutPolicy{Waves: []RolloutWave{{Percentage: 50}}}))
+	h := newRevisionChangeHandler[testAction, *testAction](client, client.Scheme(), opts, SourceRevisionChangePredicate{})
+
+	// consumers are held back until the rollout of the new revision is started
+	g.Expect(enqueued(h, old, new)).To(BeEmpty())
+	decisions := evaluateRevisionChange[testAction, *testAction](context.Background(), client, client.Scheme(), opts, new, new)
+	g.Expect(decisions).To(HaveLen(1))
+	g.Expect(decisions[0].Stage).To(Equal(StageRollout))
+}
+
+func TestRevisionChangeHandlerRecorder(t *testing.T) {
+	g := NewWithT(t)
+
+	old, new := newTestArtifact("v1"), newTestArtifact("v2")
+	client := newTestClient(new, newTestAction())
+	recorder := NewDecisionRecorder(10)
+	h := newRevisionChangeHandler[testAction, *testAction](client, client.Scheme(), EvalOptions(WithDecisionRecorder(recorder)), SourceRevisionChangePredicate{})
+
+	g.Expect(enqueued(h, old, new)).To(HaveLen(1))
+	g.Expect(enqueued(h, new, new)).To(BeEmpty())
+
+	records := recorder.Query("", "", "default", "app")
+	g.Expect(records).To(HaveLen(2))
+	g.Expect(records[0].Included).To(BeTrue())
+	g.Expect(records[0].Stage).To(Equal(StageIndexLookup))
+	g.Expect(records[0].Revision).To(Equal("v2"))
+	g.Expect(records[1].Included).To(BeFalse())
+	g.Expect(records[1].Stage).To(Equal(StageRevisionChange))
+}
+
+func TestRevisionChangeHandlerPinnedRevision(t *testing.T) {
+	g := NewWithT(t)
+
+	old, new := newTestArtifact("v1"), newTestArtifact("v2")
+	action := newTestAction()
+	action.Spec.SourceRef.Revision = "v1"
+	client := newTestClient(new, action)
+	h := newRevisionChangeHandler[testAction, *testAction](client, client.Scheme(), EvalOptions(), SourceRevisionChangePredicate{})
+
+	// the pin matches the old revision only
+	g.Expect(enqueued(h, old, new)).To(BeEmpty())
+	// but the action is triggered once the pinned revision is published again
+	g.Expect(enqueued(h, new, old)).To(HaveLen(1))
+}
@@ -0,0 +1,173 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	"github.com/openfluxcd/artifact/api/commonv1"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/matchers"
+	"github.com/openfluxcd/artifact/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AnalyzeRevisionChange reports which action resources of type T would be
+// reconciled if the referenced source published the candidate artifact. It
+// runs the same pipeline as the watches registered by Setup without acting on
+// the decisions, so no triggers are deferred. If the source is not an Artifact
+// or a builtin Flux source, the Artifacts owned by it are analyzed. References
+// to cluster-scoped sources, like ClusterArtifacts, have no namespace.
+func AnalyzeRevisionChange[T any, P ActionResourcePointerType[T]](ctx context.Context, client ctrlclient.Client, scheme *runtime.Scheme, ref utils.SourceRefProvider, candidate *sourcev1.Artifact, options ...Option) ([]Decision, error) {
+	opts := EvalOptions(options...)
+	if opts.KindResolver != nil {
+		resolved, err := utils.ResolveSourceRef(opts.KindResolver, ref)
+		if err != nil {
+			return nil, err
+		}
+		ref = resolved
+	}
+	if ref.GetNamespace() == "" && !utils.IsClusterScoped(ref) {
+		return nil, fmt.Errorf("source reference %s requires a namespace", ref)
+	}
+
+	var sources []ctrlclient.Object
+	factory := matchers.BuiltinFluxSourceVersions.ForMapper(client.RESTMapper())
+	if obj := factory.CreateVersion(utils.GetGroupVersionKind(ref)); obj != nil {
+		if err := client.Get(ctx, ref.GetObjectKey(), obj); err != nil {
+			return nil, fmt.Errorf("unable to get source '%s': %w", ref.GetObjectKey(), err)
+		}
+		sources = append(sources, obj)
+	} else {
+		key := utils.KeyForReference(&metav1.ObjectMeta{Namespace: ref.GetNamespace()}, ref)
+		var list artifactv1.ArtifactList
+		if err := listArtifactsForKey(ctx, client, true, ref.GetNamespace(), key, &list); err != nil {
+			return nil, err
+		}
+		arts := withoutStaleArtifacts(ctx, opts, ref, list.Items)
+		if len(arts) == 0 {
+			return nil, fmt.Errorf("no artifact resource found for %s", key)
+		}
+		for i := range arts {
+			sources = append(sources, &arts[i])
+		}
+	}
+
+	var decisions []Decision
+	for _, obj := range sources {
+		current := obj.(ArtifactSource).GetArtifact()
+		hypothetical, err := withArtifact(obj, candidate)
+		if err != nil {
+			return nil, err
+		}
+		result := evaluateRevisionChange[T, P](ctx, client, scheme, opts, hypothetical, hypothetical.(ArtifactSource))
+
+		gk := utils.GetGroupKindForObject(scheme, obj)
+		switch {
+		case gk != nil && opts.AllowedSourceKinds != nil && !opts.AllowedSourceKinds.Match(*gk):
+			reject(result, StageAllowedSourceKinds, fmt.Sprintf("source kind %s is not allowed", gk))
+		case current != nil && current.HasRevision(candidate.Revision):
+			reject(result, StageRevisionChange, fmt.Sprintf("revision %s is already published by %s", candidate.Revision, obj.GetName()))
+		}
+		decisions = append(decisions, result...)
+	}
+	return decisions, nil
+}
+
+func reject(decisions []Decision, stage Stage, reason string) {
+	for i := range decisions {
+		decisions[i].Included = false
+		decisions[i].Stage = stage
+		decisions[i].Reason = reason
+	}
+}
+
+// withArtifact returns a copy of the source object publishing the given artifact.
+func withArtifact(obj ctrlclient.Object, artifact *sourcev1.Artifact) (ctrlclient.Object, error) {
+	switch art := obj.(type) {
+	case *artifactv1.Artifact:
+		art = art.DeepCopy()
+		setArtifact(&art.Spec, artifact)
+		return art, nil
+	case *artifactv1.ClusterArtifact:
+		art = art.DeepCopy()
+		setArtifact(&art.Spec.ArtifactSpec, artifact)
+		return art, nil
+	}
+
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	a, err := runtime.DefaultUnstructuredConverter.ToUnstructured(artifact)
+	if err != nil {
+		return nil, err
+	}
+	if err := unstructured.SetNestedMap(u, a, "status", "artifact"); err != nil {
+		return nil, err
+	}
+	result := obj.DeepCopyObject().(ctrlclient.Object)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func setArtifact(spec *artifactv1.ArtifactSpec, artifact *sourcev1.Artifact) {
+	spec.URL = artifact.URL
+	spec.Revision = artifact.Revision
+	spec.Digest = artifact.Digest
+	spec.LastUpdateTime = artifact.LastUpdateTime
+	spec.Size = artifact.Size
+	spec.Metadata = artifact.Metadata
+}
+
+// ImpactRequest is the request body of the impact analysis endpoint.
+type ImpactRequest struct {
+	SourceRef commonv1.SourceRef `json:"sourceRef"`
+	Artifact  sourcev1.Artifact  `json:"artifact"`
+}
+
+// ImpactHandler serves AnalyzeRevisionChange for POST requests with an ImpactRequest body.
+func ImpactHandler[T any, P ActionResourcePointerType[T]](client ctrlclient.Client, scheme *runtime.Scheme, options ...Option) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var body ImpactRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := body.SourceRef.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		decisions, err := AnalyzeRevisionChange[T, P](req.Context(), client, scheme, &body.SourceRef, &body.Artifact, options...)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(decisions)
+	})
+}
+
+// AddImpactHandlerToManager registers the ImpactHandler on the metrics server of the
+// manager at /debug/artifacts/impact/<kind>.
+func AddImpactHandlerToManager[T any, P ActionResourcePointerType[T]](mgr ctrl.Manager, options ...Option) error {
+	gk := utils.GetGroupKindForType[T, P](mgr.GetScheme())
+	if gk == nil {
+		return fmt.Errorf("action type not registered in scheme")
+	}
+	return mgr.AddMetricsServerExtraHandler(fmt.Sprintf("/debug/artifacts/impact/%s", strings.ToLower(gk.Kind)),
+		ImpactHandler[T, P](mgr.GetClient(), mgr.GetScheme(), options...))
+}
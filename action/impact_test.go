@@ -0,0 +1,94 @@
+package action
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	"github.com/openfluxcd/artifact/api/commonv1"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestAnalyzeRevisionChange(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	pinned := newTestAction()
+	pinned.Name = "pinned"
+	pinned.Spec.SourceRef.Revision = "v1"
+	client := newTestClient(newTestArtifact("v1"), newTestAction(), pinned)
+	ref := &commonv1.SourceRef{APIVersion: artifactv1.GroupVersion.String(), Kind: artifactv1.ArtifactKind, Name: "source", Namespace: "default"}
+
+	decisions, err := AnalyzeRevisionChange[testAction, *testAction](ctx, client, client.Scheme(), ref, &sourcev1.Artifact{Revision: "v2"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(decisions).To(ConsistOf(
+		And(HaveField("Name", "app"), HaveField("Included", true), HaveField("Stage", StageIndexLookup)),
+		And(HaveField("Name", "pinned"), HaveField("Included", false), HaveField("Stage", StagePinnedRevision)),
+	))
+
+	// the current revision does not trigger anything
+	decisions, err = AnalyzeRevisionChange[testAction, *testAction](ctx, client, client.Scheme(), ref, &sourcev1.Artifact{Revision: "v1"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(decisions).To(HaveEach(And(HaveField("Included", false), HaveField("Stage", StageRevisionChange))))
+
+	// the source itself is not modified
+	var art artifactv1.Artifact
+	g.Expect(client.Get(ctx, ref.GetObjectKey(), &art)).To(Succeed())
+	g.Expect(art.Spec.Revision).To(Equal("v1"))
+}
+
+func TestAnalyzeRevisionChangeOfClusterArtifact(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	art := newTestClusterArtifact("v1")
+	other := newClusterArtifactAction()
+	other.Namespace = "other"
+	client := newTestClient(art, newClusterArtifactAction(), other)
+	ref := &commonv1.SourceRef{APIVersion: artifactv1.GroupVersion.String(), Kind: artifactv1.ClusterArtifactKind, Name: "shared"}
+
+	decisions, err := AnalyzeRevisionChange[testAction, *testAction](ctx, client, client.Scheme(), ref, &sourcev1.Artifact{Revision: "v2"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(decisions).To(ConsistOf(
+		And(HaveField("Namespace", "default"), HaveField("Included", true)),
+		And(HaveField("Namespace", "other"), HaveField("Included", true)),
+	))
+
+	decisions, err = AnalyzeRevisionChange[testAction, *testAction](ctx, client, client.Scheme(), ref, &sourcev1.Artifact{Revision: "v1"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(decisions).To(HaveEach(And(HaveField("Included", false), HaveField("Stage", StageRevisionChange))))
+
+	// namespaced sources still require a namespace
+	ref = &commonv1.SourceRef{APIVersion: artifactv1.GroupVersion.String(), Kind: artifactv1.ArtifactKind, Name: "source"}
+	_, err = AnalyzeRevisionChange[testAction, *testAction](ctx, client, client.Scheme(), ref, &sourcev1.Artifact{Revision: "v2"})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestEvaluateRevisionChangeIsPure(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	action := newTestAction()
+	action.Spec.MaintenanceWindows = []artifactv1.MaintenanceWindow{{Schedule: "0 22 * * MON-FRI", Duration: metav1.Duration{Duration: 4 * time.Hour}}}
+	old, new := newTestArtifact("v1"), newTestArtifact("v2")
+	client := newTestClient(new, action)
+	clock := clocktesting.NewFakeClock(saturday)
+	opts := EvalOptions(WithMaintenanceWindows(), WithClock(clock))
+	opts.deferred = newDeferredTriggers(clock)
+
+	decisions := evaluateRevisionChange[testAction, *testAction](ctx, client, client.Scheme(), opts, new, new)
+	g.Expect(decisions).To(HaveLen(1))
+	g.Expect(decisions[0].Stage).To(Equal(StageMaintenanceWindow))
+	g.Expect(decisions[0].deferUntil).To(Equal(monday))
+	// the deferral is only reported
+	g.Expect(opts.deferred.Pending()).To(Equal(0))
+
+	// and scheduled by the event handler
+	h := newRevisionChangeHandler[testAction, *testAction](client, client.Scheme(), opts, SourceRevisionChangePredicate{})
+	g.Expect(enqueued(h, old, new)).To(BeEmpty())
+	g.Expect(opts.deferred.Pending()).To(Equal(1))
+}
@@ -0,0 +1,94 @@
+package action
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/fluxcd/pkg/runtime/acl"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ServiceAccountProvider may be implemented by action resources to provide
+// the name of the service account used to read their source. If it is not
+// implemented, the field spec.serviceAccountName is used, if present.
+type ServiceAccountProvider interface {
+	GetServiceAccountName() string
+}
+
+// Impersonator provides clients impersonating the service account of an
+// action resource, similar to the --default-service-account model of Flux.
+// Actions without a service account use the DefaultServiceAccount of their
+// namespace. If there is no default either, the controller's own client is used.
+type Impersonator struct {
+	config                *rest.Config
+	scheme                *runtime.Scheme
+	mapper                meta.RESTMapper
+	defaultServiceAccount string
+
+	lock    sync.Mutex
+	clients map[string]ctrlclient.Client
+}
+
+func NewImpersonator(cfg *rest.Config, scheme *runtime.Scheme, mapper meta.RESTMapper, defaultServiceAccount string) *Impersonator {
+	return &Impersonator{
+		config:                cfg,
+		scheme:                scheme,
+		mapper:                mapper,
+		defaultServiceAccount: defaultServiceAccount,
+		clients:               map[string]ctrlclient.Client{},
+	}
+}
+
+// ClientFor returns the client impersonating the service account of the
+// given action, or nil if the controller's client should be used.
+func (i *Impersonator) ClientFor(action ctrlclient.Object) (ctrlclient.Client, error) {
+	sa := ServiceAccountName(action)
+	if sa == "" {
+		sa = i.defaultServiceAccount
+	}
+	if sa == "" {
+		return nil, nil
+	}
+	user := fmt.Sprintf("system:serviceaccount:%s:%s", action.GetNamespace(), sa)
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if c := i.clients[user]; c != nil {
+		return c, nil
+	}
+	cfg := rest.CopyConfig(i.config)
+	cfg.Impersonate = rest.ImpersonationConfig{UserName: user}
+	c, err := ctrlclient.New(cfg, ctrlclient.Options{Scheme: i.scheme, Mapper: i.mapper})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create client impersonating %s: %w", user, err)
+	}
+	i.clients[user] = c
+	return c, nil
+}
+
+// ServiceAccountName returns the service account name of an action resource.
+func ServiceAccountName(action ctrlclient.Object) string {
+	if p, ok := action.(ServiceAccountProvider); ok {
+		return p.GetServiceAccountName()
+	}
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(action)
+	if err != nil {
+		return ""
+	}
+	name, _, _ := unstructured.NestedString(u, "spec", "serviceAccountName")
+	return name
+}
+
+// mapAccessError maps authorization failures of source reads to access denied errors.
+func mapAccessError(err error, action ctrlclient.Object) error {
+	if apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err) {
+		return acl.AccessDeniedError(fmt.Sprintf("can't access source of '%s/%s': %s", action.GetNamespace(), action.GetName(), err))
+	}
+	return err
+}
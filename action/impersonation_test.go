@@ -0,0 +1,128 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/acl"
+	. "github.com/onsi/gomega"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+// newTestAPIServer serves the Artifact "default/source" to the given user,
+// other users are denied.
+func newTestAPIServer(t *testing.T, user string) *rest.Config {
+	art := newTestArtifact("v1")
+	art.APIVersion, art.Kind = artifactv1.GroupVersion.String(), artifactv1.ArtifactKind
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/apis/openfluxcd.ocm.software/v1alpha1/namespaces/default/artifacts/source" {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(apierrors.NewNotFound(schema.GroupResource{}, r.URL.Path).ErrStatus)
+			return
+		}
+		if r.Header.Get("Impersonate-User") != user {
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(apierrors.NewForbidden(artifactv1.GroupVersion.WithResource("artifacts").GroupResource(), "source", errors.New("denied")).ErrStatus)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(art)
+	}))
+	t.Cleanup(srv.Close)
+	return &rest.Config{Host: srv.URL}
+}
+
+func newTestImpersonator(cfg *rest.Config, defaultServiceAccount string) *Impersonator {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{artifactv1.GroupVersion})
+	mapper.Add(artifactv1.GroupVersion.WithKind(artifactv1.ArtifactKind), meta.RESTScopeNamespace)
+	return NewImpersonator(cfg, newTestScheme(), mapper, defaultServiceAccount)
+}
+
+func TestImpersonatorClientFor(t *testing.T) {
+	g := NewWithT(t)
+	action := newTestAction()
+
+	c, err := newTestImpersonator(&rest.Config{}, "").ClientFor(action)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(c).To(BeNil())
+
+	i := newTestImpersonator(&rest.Config{}, "default-reader")
+	defaulted, err := i.ClientFor(action)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(defaulted).ToNot(BeNil())
+
+	// clients are cached per service account
+	other := newTestAction()
+	other.Name = "other"
+	c, err = i.ClientFor(other)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(c).To(BeIdenticalTo(defaulted))
+
+	action.Spec.ServiceAccountName = "reader"
+	g.Expect(ServiceAccountName(action)).To(Equal("reader"))
+	c, err = i.ClientFor(action)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(c).ToNot(BeIdenticalTo(defaulted))
+}
+
+func TestGetSourceWithImpersonation(t *testing.T) {
+	cfg := newTestAPIServer(t, "system:serviceaccount:default:reader")
+
+	tests := []struct {
+		name           string
+		serviceAccount string
+		defaultAccount string
+		denied         bool
+	}{
+		{name: "service account", serviceAccount: "reader"},
+		{name: "default service account", defaultAccount: "reader"},
+		{name: "denied service account", serviceAccount: "other", defaultAccount: "reader", denied: true},
+		{name: "denied default service account", defaultAccount: "other", denied: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			action := newTestAction()
+			action.Spec.ServiceAccountName = tt.serviceAccount
+			// the controller's client does not know the source
+			client := newTestClient(action)
+
+			src, err := GetSource(context.Background(), client, action, WithImpersonation(newTestImpersonator(cfg, tt.defaultAccount)))
+			if tt.denied {
+				g.Expect(acl.IsAccessDenied(err)).To(BeTrue(), "unexpected error %v", err)
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(src.GetArtifact().Revision).To(Equal("v1"))
+		})
+	}
+
+	t.Run("without service account", func(t *testing.T) {
+		g := NewWithT(t)
+		action := newTestAction()
+		client := newTestClient(action, newTestArtifact("v2"))
+		src, err := GetSource(context.Background(), client, action, WithImpersonation(newTestImpersonator(cfg, "")))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(src.GetArtifact().Revision).To(Equal("v2"))
+	})
+}
+
+func TestMapAccessError(t *testing.T) {
+	g := NewWithT(t)
+	action := newTestAction()
+	gr := artifactv1.GroupVersion.WithResource("artifacts").GroupResource()
+
+	g.Expect(acl.IsAccessDenied(mapAccessError(apierrors.NewForbidden(gr, "source", errors.New("denied")), action))).To(BeTrue())
+	g.Expect(acl.IsAccessDenied(mapAccessError(apierrors.NewUnauthorized("unauthorized"), action))).To(BeTrue())
+
+	notFound := apierrors.NewNotFound(gr, "source")
+	g.Expect(mapAccessError(notFound, action)).To(BeIdenticalTo(notFound))
+}
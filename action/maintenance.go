@@ -0,0 +1,453 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/schedule"
+	"github.com/openfluxcd/artifact/utils"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// MaintenanceWindowProvider may be implemented by action resources to
+// provide their own maintenance windows. If it is not implemented, the field
+// spec.maintenanceWindows is used, if present.
+type MaintenanceWindowProvider interface {
+	GetMaintenanceWindows() []artifactv1.MaintenanceWindow
+}
+
+// MaintenanceWindows returns the maintenance windows configured at an action resource.
+func MaintenanceWindows(action ctrlclient.Object) []artifactv1.MaintenanceWindow {
+	if p, ok := action.(MaintenanceWindowProvider); ok {
+		return p.GetMaintenanceWindows()
+	}
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(action)
+	if err != nil {
+		return nil
+	}
+	list, found, err := unstructured.NestedSlice(u, "spec", "maintenanceWindows")
+	if err != nil || !found {
+		return nil
+	}
+	var windows []artifactv1.MaintenanceWindow
+	for _, e := range list {
+		m, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var w artifactv1.MaintenanceWindow
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &w); err == nil {
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
+
+// DeferredRevisionError is returned by GetSource if WithMaintenanceWindows is
+// configured, the maintenance windows of the action resource are closed and
+// the last applied revision of the source is not retained. Reconcilers should
+// requeue the action resource at NextWindow.
+type DeferredRevisionError struct {
+	Source     string
+	Revision   string
+	NextWindow time.Time
+}
+
+func (e *DeferredRevisionError) Error() string {
+	if e.NextWindow.IsZero() {
+		return fmt.Sprintf("revision %s of source '%s' is deferred, no maintenance window is scheduled", e.Revision, e.Source)
+	}
+	return fmt.Sprintf("revision %s of source '%s' is deferred until the next maintenance window at %s", e.Revision, e.Source, e.NextWindow.Format(time.RFC3339))
+}
+
+func IsDeferredRevision(err error) bool {
+	var e *DeferredRevisionError
+	return errors.As(err, &e)
+}
+
+// policyApplies checks whether a MaintenancePolicy applies to an action resource
+// referencing the given source.
+func policyApplies(policy *artifactv1.MaintenancePolicy, action ctrlclient.Object, ref utils.SourceRefProvider) (bool, error) {
+	if policy.Namespace != action.GetNamespace() {
+		return false, nil
+	}
+	if policy.Spec.ActionSelector != nil {
+		sel, err := metav1.LabelSelectorAsSelector(policy.Spec.ActionSelector)
+		if err != nil {
+			return false, fmt.Errorf("invalid action selector in maintenance policy '%s/%s': %w", policy.Namespace, policy.Name, err)
+		}
+		if !sel.Matches(labels.Set(action.GetLabels())) {
+			return false, nil
+		}
+	}
+	if len(policy.Spec.Sources) == 0 {
+		return true, nil
+	}
+	gk := ref.GetGroupKind()
+	for _, s := range policy.Spec.Sources {
+		if s.Group == gk.Group && s.Kind == gk.Kind && (s.Name == "" || s.Name == ref.GetName()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// windowsFor returns the maintenance windows of an action resource, which are
+// its own windows and those of all applying MaintenancePolicy objects.
+func windowsFor(ctx context.Context, client ctrlclient.Client, action ActionResource, ref utils.SourceRefProvider) (schedule.Windows, error) {
+	specs := MaintenanceWindows(action)
+
+	var policies artifactv1.MaintenancePolicyList
+	if err := client.List(ctx, &policies, ctrlclient.InNamespace(action.GetNamespace())); err != nil {
+		return nil, fmt.Errorf("unable to list maintenance policies in namespace '%s': %w", action.GetNamespace(), err)
+	}
+	for _, policy := range policies.Items {
+		ok, err := policyApplies(&policy, action, ref)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			specs = append(specs, policy.Spec.Windows...)
+		}
+	}
+	return schedule.NewWindows(specs...)
+}
+
+// maintenanceSource returns the source to serve with respect to the maintenance
+// windows of an action resource. While the windows are closed, the last applied
+// revision is served, if it is still retained. See pinnedSource.
+func maintenanceSource(ctx context.Context, client ctrlclient.Client, reader ctrlclient.Reader, indexed bool, opts *Options, action ActionResource, ref utils.SourceRefProvider, src ArtifactSource) (ArtifactSource, error) {
+	windows, err := windowsFor(ctx, client, action, ref)
+	if err != nil {
+		return nil, err
+	}
+	now := opts.Clock.Now()
+	if windows.Contains(now) || src.GetArtifact() == nil {
+		return src, nil
+	}
+	lastApplied := LastAppliedRevision(action)
+	if lastApplied != "" {
+		served, err := pinnedSource(ctx, client, reader, indexed, src, lastApplied, "")
+		if err == nil {
+			return served, nil
+		}
+		if !IsPinnedRevisionNotRetained(err) {
+			return nil, err
+		}
+	}
+	obj := src.(ctrlclient.Object)
+	return nil, &DeferredRevisionError{
+		Source:     fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName()),
+		Revision:   src.GetArtifact().Revision,
+		NextWindow: windows.NextOpen(now),
+	}
+}
+
+// checkMaintenanceWindow returns the reason for deferring the revision change of
+// a source for an action resource together with the time the change is released,
+// or an empty string if the maintenance windows of the action are open.
+func checkMaintenanceWindow(ctx context.Context, client ctrlclient.Client, opts *Options, action ActionResource, src ArtifactSource) (string, time.Time) {
+	ref, err := ResolvedSourceRef(action, opts.KindResolver)
+	if err != nil || ref == nil {
+		return fmt.Sprintf("unable to resolve source reference: %v", err), time.Time{}
+	}
+	windows, err := windowsFor(ctx, client, action, ref)
+	if err != nil {
+		return err.Error(), time.Time{}
+	}
+	now := opts.Clock.Now()
+	if windows.Contains(now) {
+		return "", time.Time{}
+	}
+	next := windows.NextOpen(now)
+	if next.IsZero() {
+		return "maintenance windows are closed and not scheduled to open", next
+	}
+	return fmt.Sprintf("deferred until the next maintenance window at %s", next.Format(time.RFC3339)), next
+}
+
+// deferredTriggers keeps revision changes deferred until the next maintenance
+// window and emits generic events for the action resources once the window
+// opens. It is started as a Runnable of the manager.
+//
+// The pending triggers are kept in memory only. When started, after a restart
+// or a change of the leader, they are restored by the resync function from
+// the action resources whose source publishes a revision other than their
+// last applied one.
+type deferredTriggers struct {
+	clock  clock.Clock
+	events chan event.GenericEvent
+	resync func(ctx context.Context, d *deferredTriggers)
+
+	lock    sync.Mutex
+	pending map[types.NamespacedName]deferredTrigger
+	wakeup  chan struct{}
+}
+
+type deferredTrigger struct {
+	object ctrlclient.Object
+	at     time.Time
+}
+
+func newDeferredTriggers(c clock.Clock) *deferredTriggers {
+	return &deferredTriggers{
+		clock:   c,
+		events:  make(chan event.GenericEvent),
+		pending: map[types.NamespacedName]deferredTrigger{},
+		wakeup:  make(chan struct{}, 1),
+	}
+}
+
+// Defer schedules a trigger for the action resource at the given time. An
+// already pending trigger is kept, if it is released earlier.
+func (d *deferredTriggers) Defer(obj ctrlclient.Object, at time.Time) {
+	if d == nil || at.IsZero() {
+		return
+	}
+	key := ctrlclient.ObjectKeyFromObject(obj)
+
+	d.lock.Lock()
+	if p, ok := d.pending[key]; ok && !p.at.After(at) {
+		d.lock.Unlock()
+		return
+	}
+	d.pending[key] = deferredTrigger{object: obj.DeepCopyObject().(ctrlclient.Object), at: at}
+	d.lock.Unlock()
+
+	select {
+	case d.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// Pending returns the number of pending triggers.
+func (d *deferredTriggers) Pending() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return len(d.pending)
+}
+
+// due removes and returns all triggers due at the given time, together
+// with the time the next trigger is due.
+func (d *deferredTriggers) due(now time.Time) ([]ctrlclient.Object, time.Time) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	var objs []ctrlclient.Object
+	var next time.Time
+	for key, p := range d.pending {
+		if !p.at.After(now) {
+			objs = append(objs, p.object)
+			delete(d.pending, key)
+		} else if next.IsZero() || p.at.Before(next) {
+			next = p.at
+		}
+	}
+	return objs, next
+}
+
+// Start implements manager.Runnable.
+func (d *deferredTriggers) Start(ctx context.Context) error {
+	if d.resync != nil {
+		d.resync(ctx, d)
+	}
+	for {
+		objs, next := d.due(d.clock.Now())
+		for _, obj := range objs {
+			select {
+			case d.events <- event.GenericEvent{Object: obj}:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		var timer clock.Timer
+		var fired <-chan time.Time
+		if !next.IsZero() {
+			timer = d.clock.NewTimer(next.Sub(d.clock.Now()))
+			fired = timer.C()
+		}
+		select {
+		case <-ctx.Done():
+		case <-d.wakeup:
+		case <-fired:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// resyncDeferredTriggers returns the function restoring the deferred triggers
+// of the action resources of type T.
+func resyncDeferredTriggers[T any, P ActionResourcePointerType[T]](client ctrlclient.Client, scheme *runtime.Scheme, opts *Options) func(ctx context.Context, d *deferredTriggers) {
+	return func(ctx context.Context, d *deferredTriggers) {
+		log := ctrl.LoggerFrom(ctx)
+		list := utils.CreateListForType[T, P](scheme)
+		if err := client.List(ctx, list); err != nil {
+			log.Error(err, "failed to list action resources to restore deferred triggers")
+			return
+		}
+		objs, _ := meta.ExtractList(list)
+		for _, o := range objs {
+			action := o.(ActionResource)
+			if revision, digest := pinOf(action); revision != "" || digest != "" {
+				continue
+			}
+			ref, err := ResolvedSourceRef(action, opts.KindResolver)
+			if err != nil || ref == nil {
+				continue
+			}
+			src, err := lookupSource(ctx, client, client, true, opts, action, ref)
+			if err != nil || src.GetArtifact() == nil || src.GetArtifact().HasRevision(LastAppliedRevision(action)) {
+				continue
+			}
+			if reason, next := checkMaintenanceWindow(ctx, client, opts, action, src); reason != "" {
+				d.Defer(action, next)
+			}
+		}
+		log.Info("restored deferred triggers", "pending", d.Pending())
+	}
+}
+
+// MaintenancePolicyReconciler publishes the action resources of type T with
+// revision changes deferred by a MaintenancePolicy in the status of the policy.
+// Entries of other action kinds are preserved, so that controllers for several
+// action types can maintain the status of the same policy. Revision changes
+// deferred by the own maintenance windows of an action resource are not
+// published, see WithMaintenanceWindows.
+//
+// The reconciler requires the indices registered by Setup.
+type MaintenancePolicyReconciler[T any, P ActionResourcePointerType[T]] struct {
+	Client  ctrlclient.Client
+	Scheme  *runtime.Scheme
+	Options []Option
+
+	// Interval is the maximum time between two evaluations of a policy,
+	// defaults to one minute.
+	Interval time.Duration
+}
+
+func (r *MaintenancePolicyReconciler[T, P]) SetupWithManager(mgr ctrl.Manager) error {
+	gk := utils.GetGroupKindForType[T, P](r.Scheme)
+	if gk == nil {
+		return fmt.Errorf("action type not registered in scheme")
+	}
+	var _obj T
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(fmt.Sprintf("maintenance-policy-%s", strings.ToLower(gk.Kind))).
+		For(&artifactv1.MaintenancePolicy{}).
+		Watches(P(&_obj), handler.EnqueueRequestsFromMapFunc(r.policiesForAction)).
+		Complete(r)
+}
+
+func (r *MaintenancePolicyReconciler[T, P]) policiesForAction(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	var list artifactv1.MaintenancePolicyList
+	if err := r.Client.List(ctx, &list, ctrlclient.InNamespace(obj.GetNamespace())); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to list maintenance policies for action change")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, p := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: p.Namespace, Name: p.Name}})
+	}
+	return requests
+}
+
+func (r *MaintenancePolicyReconciler[T, P]) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	opts := EvalOptions(r.Options...)
+	interval := r.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	var policy artifactv1.MaintenancePolicy
+	if err := r.Client.Get(ctx, req.NamespacedName, &policy); err != nil {
+		return reconcile.Result{}, ctrlclient.IgnoreNotFound(err)
+	}
+	windows, err := schedule.NewWindows(policy.Spec.Windows...)
+	if err != nil {
+		return reconcile.Result{}, reconcile.TerminalError(err)
+	}
+	gk := utils.GetGroupKindForType[T, P](r.Scheme)
+
+	var pending []artifactv1.DeferredRevision
+	for _, p := range policy.Status.Pending {
+		if p.Group != gk.Group || p.Kind != gk.Kind {
+			pending = append(pending, p)
+		}
+	}
+
+	now := opts.Clock.Now()
+	requeue := interval
+	if !windows.Contains(now) {
+		next := windows.NextOpen(now)
+		if !next.IsZero() && next.Sub(now) < requeue {
+			requeue = next.Sub(now)
+		}
+		list := utils.CreateListForType[T, P](r.Scheme)
+		if err := r.Client.List(ctx, list, ctrlclient.InNamespace(policy.Namespace)); err != nil {
+			return reconcile.Result{}, fmt.Errorf("unable to list action resources: %w", err)
+		}
+		objs, _ := meta.ExtractList(list)
+		for _, o := range objs {
+			action := o.(ActionResource)
+			ref, err := ResolvedSourceRef(action, opts.KindResolver)
+			if err != nil || ref == nil {
+				continue
+			}
+			if ok, err := policyApplies(&policy, action, ref); err != nil || !ok {
+				continue
+			}
+			src, err := lookupSource(ctx, r.Client, r.Client, true, opts, action, ref)
+			if err != nil || src.GetArtifact() == nil || src.GetArtifact().HasRevision(LastAppliedRevision(action)) {
+				continue
+			}
+			d := artifactv1.DeferredRevision{
+				Group:    gk.Group,
+				Kind:     gk.Kind,
+				Name:     action.GetName(),
+				Revision: src.GetArtifact().Revision,
+			}
+			if !next.IsZero() {
+				d.NextWindow = &metav1.Time{Time: next}
+			}
+			pending = append(pending, d)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		a, b := pending[i], pending[j]
+		return fmt.Sprintf("%s/%s/%s", a.Group, a.Kind, a.Name) < fmt.Sprintf("%s/%s/%s", b.Group, b.Kind, b.Name)
+	})
+
+	if !equality.Semantic.DeepEqual(pending, policy.Status.Pending) || policy.Status.ObservedGeneration != policy.Generation {
+		patch := ctrlclient.MergeFromWithOptions(policy.DeepCopy(), ctrlclient.MergeFromWithOptimisticLock{})
+		policy.Status.Pending = pending
+		policy.Status.ObservedGeneration = policy.Generation
+		if err := r.Client.Status().Patch(ctx, &policy, patch); err != nil {
+			return reconcile.Result{}, fmt.Errorf("unable to update status of maintenance policy: %w", err)
+		}
+	}
+	return reconcile.Result{RequeueAfter: requeue}, nil
+}
@@ -0,0 +1,130 @@
+package action
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestMaintenanceSource(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	art := &artifactv1.Artifact{}
+	art.Namespace, art.Name = "default", "source"
+	art.Spec.Revision = "v2"
+	art.Spec.URL = "http://storage/v2.tar.gz"
+	art.Status.History = []artifactv1.ArtifactRevision{{Revision: "v1", URL: "http://storage/v1.tar.gz"}}
+
+	policy := &artifactv1.MaintenancePolicy{}
+	policy.Namespace, policy.Name = "default", "nightly"
+	policy.Spec.ActionSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"environment": "production"}}
+	policy.Spec.Windows = []artifactv1.MaintenanceWindow{{Schedule: "0 22 * * MON-FRI", Duration: metav1.Duration{Duration: 4 * time.Hour}}}
+
+	client := fake.NewClientBuilder().WithScheme(newTestScheme()).WithObjects(policy).
+		WithIndex(&artifactv1.Artifact{}, ArtifactOwnerIndexKey, utils.OwnerReferenceIndex()).Build()
+	clock := clocktesting.NewFakeClock(saturday)
+	opts := EvalOptions(WithMaintenanceWindows(), WithClock(clock))
+	action := newTestAction()
+	ref, _ := action.GetSourceRef()
+
+	// outside of the window the last applied revision is served from the history
+	src, err := maintenanceSource(ctx, client, client, true, opts, action, ref, art)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(src.GetArtifact().Revision).To(Equal("v1"))
+	g.Expect(src.GetArtifact().URL).To(Equal("http://storage/v1.tar.gz"))
+
+	reason, next := checkMaintenanceWindow(ctx, client, opts, action, art)
+	g.Expect(reason).To(ContainSubstring("deferred"))
+	g.Expect(next).To(Equal(monday))
+
+	// without a retained revision, the revision change is reported as deferred
+	art.Status.History = nil
+	_, err = maintenanceSource(ctx, client, client, true, opts, action, ref, art)
+	g.Expect(IsDeferredRevision(err)).To(BeTrue())
+	g.Expect(err.(*DeferredRevisionError).NextWindow).To(Equal(monday))
+
+	// inside the window the current revision is served
+	clock.SetTime(monday.Add(time.Hour))
+	src, err = maintenanceSource(ctx, client, client, true, opts, action, ref, art)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(src.GetArtifact().Revision).To(Equal("v2"))
+	reason, _ = checkMaintenanceWindow(ctx, client, opts, action, art)
+	g.Expect(reason).To(BeEmpty())
+
+	// the policy does not apply to other actions
+	clock.SetTime(saturday)
+	other := newTestAction()
+	other.Labels = nil
+	reason, _ = checkMaintenanceWindow(ctx, client, opts, other, art)
+	g.Expect(reason).To(BeEmpty())
+
+	// but windows of the action itself do
+	other.Spec.MaintenanceWindows = []artifactv1.MaintenanceWindow{{Schedule: "0 6 * * *", Duration: metav1.Duration{Duration: time.Hour}}}
+	reason, next = checkMaintenanceWindow(ctx, client, opts, other, art)
+	g.Expect(reason).NotTo(BeEmpty())
+	g.Expect(next).To(Equal(time.Date(2024, 5, 5, 6, 0, 0, 0, time.UTC)))
+}
+
+func TestDeferredTriggers(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := clocktesting.NewFakeClock(saturday)
+	d := newDeferredTriggers(clock)
+	go func() {
+		_ = d.Start(ctx)
+	}()
+
+	action := newTestAction()
+	d.Defer(action, monday)
+	// a later release does not postpone the pending trigger
+	d.Defer(action, monday.Add(time.Hour))
+	g.Expect(d.Pending()).To(Equal(1))
+	g.Eventually(clock.HasWaiters).Should(BeTrue())
+	g.Consistently(d.events, 100*time.Millisecond).ShouldNot(Receive())
+
+	clock.SetTime(monday)
+	var e interface{}
+	g.Eventually(d.events, time.Second).Should(Receive(&e))
+	g.Expect(d.Pending()).To(Equal(0))
+}
+
+func TestDeferredTriggersResync(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	deferred := newTestAction()
+	deferred.Spec.MaintenanceWindows = []artifactv1.MaintenanceWindow{{Schedule: "0 22 * * MON-FRI", Duration: metav1.Duration{Duration: 4 * time.Hour}}}
+	applied := deferred.DeepCopyObject().(*testAction)
+	applied.Name, applied.Status.LastAppliedRevision = "applied", "v2"
+	pinned := deferred.DeepCopyObject().(*testAction)
+	pinned.Name, pinned.Spec.SourceRef.Revision = "pinned", "v1"
+	client := newTestClient(newTestArtifact("v2"), deferred, applied, pinned)
+
+	// the pending triggers are restored from the action resources on start
+	clock := clocktesting.NewFakeClock(saturday)
+	opts := EvalOptions(WithMaintenanceWindows(), WithClock(clock))
+	d := newDeferredTriggers(clock)
+	d.resync = resyncDeferredTriggers[testAction, *testAction](client, client.Scheme(), opts)
+	go func() {
+		_ = d.Start(ctx)
+	}()
+	g.Eventually(clock.HasWaiters).Should(BeTrue())
+	g.Expect(d.Pending()).To(Equal(1))
+
+	clock.SetTime(monday)
+	var e event.GenericEvent
+	g.Eventually(d.events, time.Second).Should(Receive(&e))
+	g.Expect(e.Object.GetName()).To(Equal("app"))
+}
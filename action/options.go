@@ -3,8 +3,11 @@ package action
 import (
 	"github.com/fluxcd/pkg/runtime/predicates"
 	"github.com/openfluxcd/artifact/matchers"
+	"github.com/openfluxcd/artifact/utils"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -18,15 +21,48 @@ type TriggerPredicate func(action ActionResource, art ArtifactSource) bool
 type Options struct {
 	ForOptions           []builder.ForOption
 	NoCrossNamespaceRefs *bool
+	SourceAccessGrants   *bool
+	ReadySourcesOnly     *bool
+	RevisionApprovals    *bool
+	MaintenanceWindows   *bool
+	ArtifactChannels     *bool
 	AllowedSourceKinds   SourceMatcher
+	KindResolver         utils.KindResolver
+	OwnerReader          ctrlclient.Reader
+	Impersonator         *Impersonator
+	DecisionRecorder     *DecisionRecorder
+	Rollout              *RolloutPolicy
+	Clock                clock.Clock
 	TriggerPredicate     TriggerPredicate
 	RequestMapper        RequestMapper
+
+	deferred *deferredTriggers
 }
 
 func (o *Options) CrossNamespaceRefsForbidden() bool {
 	return o.NoCrossNamespaceRefs != nil && *o.NoCrossNamespaceRefs
 }
 
+func (o *Options) SourceAccessGrantsEnabled() bool {
+	return o.SourceAccessGrants != nil && *o.SourceAccessGrants
+}
+
+func (o *Options) ReadySourcesRequired() bool {
+	return o.ReadySourcesOnly != nil && *o.ReadySourcesOnly
+}
+
+func (o *Options) RevisionApprovalsRequired() bool {
+	return o.RevisionApprovals != nil && *o.RevisionApprovals
+}
+
+func (o *Options) MaintenanceWindowsEnabled() bool {
+	return o.MaintenanceWindows != nil && *o.MaintenanceWindows
+}
+
+func (o *Options) ArtifactChannelsEnabled() bool {
+	return o.ArtifactChannels != nil && *o.ArtifactChannels
+}
+
 func (o *Options) Apply(opts *Options) {
 	if o.AllowedSourceKinds != nil {
 		opts.AllowedSourceKinds = o.AllowedSourceKinds
@@ -34,6 +70,39 @@ func (o *Options) Apply(opts *Options) {
 	if o.NoCrossNamespaceRefs != nil {
 		opts.NoCrossNamespaceRefs = o.NoCrossNamespaceRefs
 	}
+	if o.SourceAccessGrants != nil {
+		opts.SourceAccessGrants = o.SourceAccessGrants
+	}
+	if o.ReadySourcesOnly != nil {
+		opts.ReadySourcesOnly = o.ReadySourcesOnly
+	}
+	if o.RevisionApprovals != nil {
+		opts.RevisionApprovals = o.RevisionApprovals
+	}
+	if o.MaintenanceWindows != nil {
+		opts.MaintenanceWindows = o.MaintenanceWindows
+	}
+	if o.ArtifactChannels != nil {
+		opts.ArtifactChannels = o.ArtifactChannels
+	}
+	if o.Clock != nil {
+		opts.Clock = o.Clock
+	}
+	if o.KindResolver != nil {
+		opts.KindResolver = o.KindResolver
+	}
+	if o.OwnerReader != nil {
+		opts.OwnerReader = o.OwnerReader
+	}
+	if o.Impersonator != nil {
+		opts.Impersonator = o.Impersonator
+	}
+	if o.DecisionRecorder != nil {
+		opts.DecisionRecorder = o.DecisionRecorder
+	}
+	if o.Rollout != nil {
+		opts.Rollout = o.Rollout
+	}
 	if o.RequestMapper != nil {
 		opts.RequestMapper = o.RequestMapper
 	}
@@ -55,6 +124,9 @@ func EvalOptions(optList ...Option) *Options {
 	if opts.RequestMapper == nil {
 		opts.RequestMapper = DefaultRequestMapper
 	}
+	if opts.Clock == nil {
+		opts.Clock = clock.RealClock{}
+	}
 	if opts.TriggerPredicate == nil {
 		opts.TriggerPredicate = TriggerAlwaysPredicate
 	}
@@ -80,6 +152,104 @@ func (o nocrossnamespacerefs) Apply(opts *Options) {
 	opts.NoCrossNamespaceRefs = &b
 }
 
+type sourceaccessgrants bool
+
+func WithSourceAccessGrants(b ...bool) Option {
+	if len(b) == 0 {
+		return sourceaccessgrants(true)
+	}
+	return sourceaccessgrants(b[0])
+}
+
+func (o sourceaccessgrants) Apply(opts *Options) {
+	b := bool(o)
+	opts.SourceAccessGrants = &b
+}
+
+type readysourcesonly bool
+
+func WithReadySourcesOnly(b ...bool) Option {
+	if len(b) == 0 {
+		return readysourcesonly(true)
+	}
+	return readysourcesonly(b[0])
+}
+
+func (o readysourcesonly) Apply(opts *Options) {
+	b := bool(o)
+	opts.ReadySourcesOnly = &b
+}
+
+type revisionapprovals bool
+
+// WithRevisionApprovals requires new revisions of sources to be approved by
+// a RevisionApproval before they are served to action resources.
+func WithRevisionApprovals(b ...bool) Option {
+	if len(b) == 0 {
+		return revisionapprovals(true)
+	}
+	return revisionapprovals(b[0])
+}
+
+func (o revisionapprovals) Apply(opts *Options) {
+	b := bool(o)
+	opts.RevisionApprovals = &b
+}
+
+type maintenancewindows bool
+
+// WithMaintenanceWindows defers revision changes of sources for action
+// resources until one of their maintenance windows is open. The windows are
+// configured at the action resource (see MaintenanceWindowProvider) or by
+// MaintenancePolicy objects.
+//
+// Deferred revisions are reported differently for both kinds of windows.
+// GetSource returns a DeferredRevisionError in either case, which reconcilers
+// should surface in the status of the action resource. Only deferrals by a
+// MaintenancePolicy are additionally published in the status of the policy by
+// the MaintenancePolicyReconciler, deferrals by the own windows of an action
+// resource are not published anywhere else.
+func WithMaintenanceWindows(b ...bool) Option {
+	if len(b) == 0 {
+		return maintenancewindows(true)
+	}
+	return maintenancewindows(b[0])
+}
+
+func (o maintenancewindows) Apply(opts *Options) {
+	b := bool(o)
+	opts.MaintenanceWindows = &b
+}
+
+type artifactchannels bool
+
+// WithArtifactChannels triggers action resources referencing an ArtifactChannel
+// if the channel is retargeted or its target Artifact changes.
+func WithArtifactChannels(b ...bool) Option {
+	if len(b) == 0 {
+		return artifactchannels(true)
+	}
+	return artifactchannels(b[0])
+}
+
+func (o artifactchannels) Apply(opts *Options) {
+	b := bool(o)
+	opts.ArtifactChannels = &b
+}
+
+type clockoption struct {
+	clock.Clock
+}
+
+// WithClock sets the clock used to evaluate time dependent conditions.
+func WithClock(c clock.Clock) Option {
+	return &clockoption{c}
+}
+
+func (o *clockoption) Apply(opts *Options) {
+	opts.Clock = o.Clock
+}
+
 type allowedsourcekinds struct {
 	SourceMatcher
 }
@@ -92,6 +262,76 @@ func (o *allowedsourcekinds) Apply(opts *Options) {
 	opts.AllowedSourceKinds = o.SourceMatcher
 }
 
+type kindresolver struct {
+	utils.KindResolver
+}
+
+func WithKindResolver(r utils.KindResolver) Option {
+	return &kindresolver{r}
+}
+
+func (o *kindresolver) Apply(opts *Options) {
+	opts.KindResolver = o.KindResolver
+}
+
+type ownerreader struct {
+	ctrlclient.Reader
+}
+
+// WithOwnerVerification verifies the owner references of Artifacts against
+// the live owner objects. Artifacts whose owner is gone or has been recreated
+// do not trigger the action resources referencing the owner and are not
+// served to them. The owners are read as metadata with the given reader,
+// which should be uncached, like the API reader of the manager, to avoid
+// informers for all owner kinds. It requires get permissions for the owner
+// kinds. Without it, all owner references are considered valid.
+func WithOwnerVerification(r ctrlclient.Reader) Option {
+	return &ownerreader{r}
+}
+
+func (o *ownerreader) Apply(opts *Options) {
+	opts.OwnerReader = o.Reader
+}
+
+type impersonator struct {
+	*Impersonator
+}
+
+func WithImpersonation(i *Impersonator) Option {
+	return &impersonator{i}
+}
+
+func (o *impersonator) Apply(opts *Options) {
+	opts.Impersonator = o.Impersonator
+}
+
+type decisionrecorder struct {
+	*DecisionRecorder
+}
+
+// WithDecisionRecorder records the decisions of the trigger pipeline.
+func WithDecisionRecorder(r *DecisionRecorder) Option {
+	return &decisionrecorder{r}
+}
+
+func (o *decisionrecorder) Apply(opts *Options) {
+	opts.DecisionRecorder = o.DecisionRecorder
+}
+
+type rollout struct {
+	*RolloutPolicy
+}
+
+// WithRollout rolls out new revisions of Artifacts progressively to their
+// consumers. It requires a RolloutReconciler.
+func WithRollout(p *RolloutPolicy) Option {
+	return &rollout{p}
+}
+
+func (o *rollout) Apply(opts *Options) {
+	opts.Rollout = o.RolloutPolicy
+}
+
 type WithRequestMapper RequestMapper
 
 func (o WithRequestMapper) Apply(opts *Options) {
@@ -0,0 +1,122 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/utils"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PinnedRevisionNotRetainedError is returned by GetSource if the source
+// reference of an action resource is pinned to a revision or digest which is
+// neither published by the source nor retained anymore.
+type PinnedRevisionNotRetainedError struct {
+	Source   string
+	Revision string
+	Digest   string
+}
+
+func (e *PinnedRevisionNotRetainedError) Error() string {
+	return fmt.Sprintf("pinned revision %s of source '%s' is no longer retained", pinString(e.Revision, e.Digest), e.Source)
+}
+
+func IsPinnedRevisionNotRetained(err error) bool {
+	var e *PinnedRevisionNotRetainedError
+	return errors.As(err, &e)
+}
+
+func pinString(revision, digest string) string {
+	switch {
+	case revision == "":
+		return digest
+	case digest != "":
+		return fmt.Sprintf("%s (%s)", revision, digest)
+	}
+	return revision
+}
+
+// pinOf returns the pinned revision and digest of the source reference of an action resource.
+func pinOf(action ActionResource) (revision, digest string) {
+	ref, err := action.GetSourceRef()
+	if err != nil || ref == nil {
+		return "", ""
+	}
+	return utils.GetPin(ref)
+}
+
+// matchesPin checks whether an artifact matches the pinned revision and digest.
+func matchesPin(art *sourcev1.Artifact, revision, digest string) bool {
+	if art == nil {
+		return false
+	}
+	return (revision == "" || art.HasRevision(revision)) && (digest == "" || art.Digest == digest)
+}
+
+// pinnedSource returns the source serving the pinned revision or digest. This is
+// the source itself, if it still publishes the pinned revision, an Artifact
+// built from the revision history of an Artifact source, or a retained copy,
+// which is an Artifact owned by the source publishing the pinned revision.
+func pinnedSource(ctx context.Context, client ctrlclient.Client, reader ctrlclient.Reader, indexed bool, src ArtifactSource, revision, digest string) (ArtifactSource, error) {
+	if matchesPin(src.GetArtifact(), revision, digest) {
+		return src, nil
+	}
+
+	obj := src.(ctrlclient.Object)
+	switch art := src.(type) {
+	case *artifactv1.Artifact:
+		if h := retainedRevision(art.Status.History, revision, digest); h != nil {
+			art = art.DeepCopy()
+			setRevision(&art.Spec, h)
+			return art, nil
+		}
+	case *artifactv1.ClusterArtifact:
+		if h := retainedRevision(art.Status.History, revision, digest); h != nil {
+			art = art.DeepCopy()
+			setRevision(&art.Spec.ArtifactSpec, h)
+			return art, nil
+		}
+	}
+
+	if gk := utils.GetGroupKindForObject(client.Scheme(), obj); gk != nil {
+		key := fmt.Sprintf("%s/%s/%s/%s", gk.Group, gk.Kind, obj.GetNamespace(), obj.GetName())
+		var list artifactv1.ArtifactList
+		if err := listArtifactsForKey(ctx, reader, indexed, obj.GetNamespace(), key, &list); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			if matchesPin(list.Items[i].GetArtifact(), revision, digest) {
+				return &list.Items[i], nil
+			}
+		}
+	}
+	return nil, &PinnedRevisionNotRetainedError{
+		Source:   fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName()),
+		Revision: revision,
+		Digest:   digest,
+	}
+}
+
+// retainedRevision returns the entry of a revision history matching the pinned revision and digest.
+func retainedRevision(history []artifactv1.ArtifactRevision, revision, digest string) *artifactv1.ArtifactRevision {
+	for i, h := range history {
+		if matchesPin(&sourcev1.Artifact{Revision: h.Revision, Digest: h.Digest}, revision, digest) {
+			return &history[i]
+		}
+	}
+	return nil
+}
+
+func setRevision(spec *artifactv1.ArtifactSpec, h *artifactv1.ArtifactRevision) {
+	spec.URL = h.URL
+	spec.Revision = h.Revision
+	spec.Digest = h.Digest
+	spec.LastUpdateTime = h.LastUpdateTime
+	spec.Size = h.Size
+	spec.MediaType = h.MediaType
+	// the metadata describes the current revision only
+	spec.Metadata = nil
+}
@@ -0,0 +1,79 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPinnedSource(t *testing.T) {
+	ctx := context.Background()
+
+	art := newTestArtifact("v3")
+	art.Spec.Digest = "sha256:v3"
+	art.Spec.MediaType = artifactv1.MediaTypeTarGzip
+	art.Spec.Metadata = map[string]string{"org.opencontainers.image.revision": "v3"}
+	art.Status.History = []artifactv1.ArtifactRevision{
+		{Revision: "v2", Digest: "sha256:v2", URL: "http://storage/v2.zip", MediaType: artifactv1.MediaTypeZip},
+	}
+	// a copy of an older revision retained as Artifact owned by the source
+	retained := newTestArtifact("v1")
+	retained.Name = "source-v1"
+	retained.Spec.Digest = "sha256:v1"
+	retained.OwnerReferences = []metav1.OwnerReference{{APIVersion: artifactv1.GroupVersion.String(), Kind: artifactv1.ArtifactKind, Name: "source", UID: "uid"}}
+	client := newTestClient(art, retained)
+
+	tests := []struct {
+		name      string
+		revision  string
+		digest    string
+		url       string
+		mediaType string
+		retained  bool
+	}{
+		{name: "current revision", revision: "v3", url: "http://storage/v3.tar.gz", mediaType: artifactv1.MediaTypeTarGzip, retained: true},
+		{name: "history", revision: "v2", url: "http://storage/v2.zip", mediaType: artifactv1.MediaTypeZip, retained: true},
+		{name: "history by digest", digest: "sha256:v2", url: "http://storage/v2.zip", mediaType: artifactv1.MediaTypeZip, retained: true},
+		{name: "retained copy", revision: "v1", url: "http://storage/v1.tar.gz", retained: true},
+		{name: "digest mismatch", revision: "v2", digest: "sha256:v3"},
+		{name: "not retained", revision: "v0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			src, err := pinnedSource(ctx, client, client, true, art, tt.revision, tt.digest)
+			if !tt.retained {
+				g.Expect(IsPinnedRevisionNotRetained(err)).To(BeTrue())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(src.GetArtifact().URL).To(Equal(tt.url))
+			g.Expect(src.(*artifactv1.Artifact).Spec.GetMediaType()).To(Equal(tt.mediaType))
+		})
+	}
+
+	// the served copy does not modify the source and drops the metadata of the current revision
+	g := NewWithT(t)
+	src, err := pinnedSource(ctx, client, client, true, art, "v2", "")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(src.(*artifactv1.Artifact).Spec.Metadata).To(BeEmpty())
+	g.Expect(art.Spec.Revision).To(Equal("v3"))
+}
+
+func TestRevisionOf(t *testing.T) {
+	g := NewWithT(t)
+
+	art := newTestArtifact("v1")
+	art.Spec.Metadata = map[string]string{artifactv1.MediaTypeMetadataKey: artifactv1.MediaTypeZip}
+	h := artifactv1.RevisionOf(&art.Spec)
+	g.Expect(h.MediaType).To(Equal(artifactv1.MediaTypeZip))
+
+	// the media type survives serving the revision from the history
+	art = newTestArtifact("v2")
+	setRevision(&art.Spec, &h)
+	g.Expect(art.Spec.Revision).To(Equal("v1"))
+	g.Expect(art.Spec.GetMediaType()).To(Equal(artifactv1.MediaTypeZip))
+}
@@ -35,3 +35,15 @@ func (SourceRevisionChangePredicate) Update(e event.UpdateEvent) bool {
 
 	return false
 }
+
+// SourceReadyChangePredicate triggers on sources becoming ready.
+type SourceReadyChangePredicate struct {
+	predicate.Funcs
+}
+
+func (SourceReadyChangePredicate) Update(e event.UpdateEvent) bool {
+	if e.ObjectOld == nil || e.ObjectNew == nil {
+		return false
+	}
+	return CheckSourceReady(e.ObjectOld) != nil && CheckSourceReady(e.ObjectNew) == nil
+}
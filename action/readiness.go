@@ -0,0 +1,70 @@
+package action
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SourceNotReadyError is returned by GetSource if WithReadySourcesOnly is
+// configured and the source is not ready.
+type SourceNotReadyError struct {
+	Source  string
+	Reason  string
+	Message string
+}
+
+func (e *SourceNotReadyError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("source '%s' is not ready: %s", e.Source, e.Reason)
+	}
+	return fmt.Sprintf("source '%s' is not ready: %s", e.Source, e.Message)
+}
+
+func IsSourceNotReady(err error) bool {
+	var e *SourceNotReadyError
+	return errors.As(err, &e)
+}
+
+// CheckSourceReady evaluates the Ready, Stalled and Reconciling conditions
+// of a source object. Sources not providing conditions are considered
+// ready. Artifacts and ClusterArtifacts are considered ready as long as
+// they have no conditions.
+func CheckSourceReady(obj ctrlclient.Object) error {
+	getter, ok := obj.(conditions.Getter)
+	if !ok {
+		return nil
+	}
+	switch obj.(type) {
+	case *artifactv1.Artifact, *artifactv1.ClusterArtifact:
+		if len(getter.GetConditions()) == 0 {
+			return nil
+		}
+	}
+
+	name := fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName())
+	ready := conditions.Get(getter, meta.ReadyCondition)
+	switch {
+	case ready == nil:
+		return &SourceNotReadyError{Source: name, Reason: meta.ProgressingReason, Message: "source has not been reconciled yet"}
+	case conditions.IsStalled(getter):
+		return &SourceNotReadyError{Source: name, Reason: conditions.GetReason(getter, meta.StalledCondition), Message: conditions.GetMessage(getter, meta.StalledCondition)}
+	case ready.Status != metav1.ConditionTrue:
+		return &SourceNotReadyError{Source: name, Reason: ready.Reason, Message: ready.Message}
+	case observedOutdated(ready, obj) || conditions.IsReconciling(getter):
+		return &SourceNotReadyError{Source: name, Reason: meta.ProgressingReason, Message: "source is being reconciled"}
+	}
+	return nil
+}
+
+// observedOutdated checks whether the condition was observed for an older
+// generation. Sources not setting the observed generation of their conditions
+// are not considered outdated.
+func observedOutdated(cond *metav1.Condition, obj ctrlclient.Object) bool {
+	return cond.ObservedGeneration != 0 && cond.ObservedGeneration < obj.GetGeneration()
+}
@@ -0,0 +1,52 @@
+package action
+
+import (
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	. "github.com/onsi/gomega"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckSourceReady(t *testing.T) {
+	ready := func(status metav1.ConditionStatus, observed int64) metav1.Condition {
+		return metav1.Condition{Type: meta.ReadyCondition, Status: status, Reason: meta.SucceededReason, ObservedGeneration: observed}
+	}
+
+	tests := []struct {
+		name       string
+		generation int64
+		conditions []metav1.Condition
+		ready      bool
+	}{
+		{name: "without conditions", generation: 2, ready: true},
+		{name: "ready", generation: 2, conditions: []metav1.Condition{ready(metav1.ConditionTrue, 2)}, ready: true},
+		{name: "not ready", generation: 2, conditions: []metav1.Condition{ready(metav1.ConditionFalse, 2)}},
+		{name: "outdated", generation: 2, conditions: []metav1.Condition{ready(metav1.ConditionTrue, 1)}},
+		{name: "without observed generation", generation: 2, conditions: []metav1.Condition{ready(metav1.ConditionTrue, 0)}, ready: true},
+		{name: "reconciling", generation: 2, conditions: []metav1.Condition{
+			ready(metav1.ConditionTrue, 2),
+			{Type: meta.ReconcilingCondition, Status: metav1.ConditionTrue, Reason: meta.ProgressingReason},
+		}},
+		{name: "missing ready condition", generation: 2, conditions: []metav1.Condition{
+			{Type: meta.ReconcilingCondition, Status: metav1.ConditionFalse, Reason: meta.SucceededReason},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			art := &artifactv1.Artifact{}
+			art.Namespace, art.Name = "default", "source"
+			art.Generation = tt.generation
+			art.Status.Conditions = tt.conditions
+
+			err := CheckSourceReady(art)
+			if tt.ready {
+				g.Expect(err).NotTo(HaveOccurred())
+			} else {
+				g.Expect(IsSourceNotReady(err)).To(BeTrue())
+			}
+		})
+	}
+}
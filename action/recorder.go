@@ -0,0 +1,147 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/openfluxcd/artifact/utils"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultDecisionPath is the path of the debug endpoint registered by
+// AddDecisionHandlerToManager.
+const DefaultDecisionPath = "/debug/artifacts/decisions"
+
+// DefaultDecisionRecorderSize is the capacity used by NewDecisionRecorder
+// for a non-positive size.
+const DefaultDecisionRecorderSize = 1024
+
+// DecisionRecord is a Decision of the trigger pipeline together with the
+// source event it was made for.
+type DecisionRecord struct {
+	Decision `json:",inline"`
+
+	Time            time.Time `json:"time"`
+	SourceGroup     string    `json:"sourceGroup,omitempty"`
+	SourceKind      string    `json:"sourceKind"`
+	SourceNamespace string    `json:"sourceNamespace"`
+	SourceName      string    `json:"sourceName"`
+	Revision        string    `json:"revision,omitempty"`
+}
+
+// DecisionRecorder keeps the latest decisions of the trigger pipeline in a
+// bounded ring buffer, so that it can be explained why an action resource
+// has or has not been reconciled for a source change. Every recorded
+// decision is additionally logged with verbosity 1. The zero value keeps
+// DefaultDecisionRecorderSize records.
+type DecisionRecorder struct {
+	// Now returns the current time, defaults to time.Now.
+	Now func() time.Time
+
+	lock    sync.Mutex
+	records []DecisionRecord
+	next    int
+	full    bool
+}
+
+// NewDecisionRecorder creates a recorder keeping the given number of records.
+func NewDecisionRecorder(size int) *DecisionRecorder {
+	if size <= 0 {
+		size = DefaultDecisionRecorderSize
+	}
+	return &DecisionRecorder{records: make([]DecisionRecord, size)}
+}
+
+// Record records decisions made for a change of the given source object.
+func (r *DecisionRecorder) Record(ctx context.Context, scheme *runtime.Scheme, src ctrlclient.Object, decisions ...Decision) {
+	if r == nil || len(decisions) == 0 {
+		return
+	}
+	log := ctrl.LoggerFrom(ctx).V(1)
+
+	now := time.Now
+	if r.Now != nil {
+		now = r.Now
+	}
+	rec := DecisionRecord{
+		Time:            now(),
+		SourceNamespace: src.GetNamespace(),
+		SourceName:      src.GetName(),
+	}
+	if gvk := src.GetObjectKind().GroupVersionKind(); gvk.Kind != "" {
+		rec.SourceGroup, rec.SourceKind = gvk.Group, gvk.Kind
+	} else if gk := utils.GetGroupKindForObject(scheme, src); gk != nil {
+		rec.SourceGroup, rec.SourceKind = gk.Group, gk.Kind
+	}
+	if s, ok := src.(ArtifactSource); ok && s.GetArtifact() != nil {
+		rec.Revision = s.GetArtifact().Revision
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if len(r.records) == 0 {
+		r.records = make([]DecisionRecord, DefaultDecisionRecorderSize)
+	}
+	for _, d := range decisions {
+		rec.Decision = d
+		rec.Decision.object = nil
+		r.records[r.next] = rec
+		r.next = (r.next + 1) % len(r.records)
+		if r.next == 0 {
+			r.full = true
+		}
+		log.Info("trigger decision", "action", d.Namespace+"/"+d.Name, "kind", d.Kind,
+			"source", rec.SourceNamespace+"/"+rec.SourceName, "sourceKind", rec.SourceKind, "revision", rec.Revision,
+			"included", d.Included, "stage", d.Stage, "reason", d.Reason)
+	}
+}
+
+// Records returns the recorded decisions, oldest first.
+func (r *DecisionRecorder) Records() []DecisionRecord {
+	return r.Query("", "", "", "")
+}
+
+// Query returns the recorded decisions for matching action resources, oldest
+// first. Empty arguments match all values.
+func (r *DecisionRecorder) Query(group, kind, namespace, name string) []DecisionRecord {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var ordered []DecisionRecord
+	if r.full {
+		ordered = append(ordered, r.records[r.next:]...)
+	}
+	ordered = append(ordered, r.records[:r.next]...)
+
+	result := []DecisionRecord{}
+	for _, rec := range ordered {
+		if (group == "" || rec.Group == group) &&
+			(kind == "" || rec.Kind == kind) &&
+			(namespace == "" || rec.Namespace == namespace) &&
+			(name == "" || rec.Name == name) {
+			result = append(result, rec)
+		}
+	}
+	return result
+}
+
+// Handler serves the recorded decisions as JSON. The query parameters group,
+// kind, namespace and name select the action resources.
+func (r *DecisionRecorder) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(r.Query(q.Get("group"), q.Get("kind"), q.Get("namespace"), q.Get("name")))
+	})
+}
+
+// AddDecisionHandlerToManager registers the handler of the recorder at
+// DefaultDecisionPath on the metrics server of the manager.
+func AddDecisionHandlerToManager(mgr ctrl.Manager, r *DecisionRecorder) error {
+	return mgr.AddMetricsServerExtraHandler(DefaultDecisionPath, r.Handler())
+}
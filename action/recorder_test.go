@@ -0,0 +1,80 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func newTestDecision(namespace, name string) Decision {
+	return Decision{Group: testActionGroupVersion.Group, Kind: "TestAction", Namespace: namespace, Name: name, Included: true, Stage: StageIndexLookup}
+}
+
+func TestDecisionRecorder(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := newTestScheme()
+	r := NewDecisionRecorder(3)
+	r.Now = func() time.Time { return saturday }
+
+	r.Record(ctx, scheme, newTestArtifact("v1"), newTestDecision("default", "a"), newTestDecision("other", "b"))
+	records := r.Records()
+	g.Expect(records).To(HaveLen(2))
+	g.Expect(records[0]).To(And(
+		HaveField("Name", "a"),
+		HaveField("Time", saturday),
+		HaveField("SourceKind", "Artifact"),
+		HaveField("SourceNamespace", "default"),
+		HaveField("SourceName", "source"),
+		HaveField("Revision", "v1"),
+	))
+
+	// the oldest records are replaced
+	r.Record(ctx, scheme, newTestArtifact("v2"), newTestDecision("default", "c"), newTestDecision("default", "d"))
+	g.Expect(r.Records()).To(HaveExactElements(HaveField("Name", "b"), HaveField("Name", "c"), HaveField("Name", "d")))
+	r.Record(ctx, scheme, newTestArtifact("v3"), newTestDecision("default", "e"))
+	g.Expect(r.Records()).To(HaveExactElements(HaveField("Name", "c"), HaveField("Name", "d"), HaveField("Name", "e")))
+
+	// nothing is recorded without decisions
+	r.Record(ctx, scheme, newTestArtifact("v4"))
+	g.Expect(r.Records()).To(HaveLen(3))
+}
+
+func TestDecisionRecorderQuery(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	r := NewDecisionRecorder(0)
+	other := newTestDecision("other", "a")
+	other.Kind = "Other"
+	r.Record(ctx, newTestScheme(), newTestArtifact("v1"), newTestDecision("default", "a"), newTestDecision("other", "a"), newTestDecision("default", "b"), other)
+
+	g.Expect(r.Query("", "", "", "")).To(HaveLen(4))
+	g.Expect(r.Query("", "TestAction", "", "")).To(HaveLen(3))
+	g.Expect(r.Query(testActionGroupVersion.Group, "TestAction", "default", "")).To(HaveLen(2))
+	g.Expect(r.Query("", "TestAction", "", "a")).To(HaveExactElements(HaveField("Namespace", "default"), HaveField("Namespace", "other")))
+	g.Expect(r.Query("", "", "default", "c")).To(BeEmpty())
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/?kind=Other", nil))
+	var records []DecisionRecord
+	g.Expect(json.NewDecoder(rec.Body).Decode(&records)).To(Succeed())
+	g.Expect(records).To(ConsistOf(And(HaveField("Kind", "Other"), HaveField("Namespace", "other"))))
+}
+
+func TestDecisionRecorderZeroValue(t *testing.T) {
+	g := NewWithT(t)
+	r := &DecisionRecorder{}
+	g.Expect(r.Records()).To(BeEmpty())
+
+	r.Record(context.Background(), newTestScheme(), newTestArtifact("v1"), newTestDecision("default", "a"))
+	g.Expect(r.Records()).To(ConsistOf(HaveField("Name", "a")))
+	g.Expect(r.records).To(HaveLen(DefaultDecisionRecorderSize))
+
+	// a nil recorder records nothing
+	var none *DecisionRecorder
+	none.Record(context.Background(), newTestScheme(), newTestArtifact("v1"), newTestDecision("default", "a"))
+}
@@ -0,0 +1,323 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/utils"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// RolloutWave selects the action resources of a wave of a progressive
+// rollout, either by a label selector or by a percentage of all consumers.
+type RolloutWave struct {
+	// Selector selects the action resources of the wave.
+	Selector labels.Selector
+	// Percentage is the cumulative percentage of all consumers released
+	// with this wave. It is used if no selector is given. Consumers are
+	// assigned by a hash of their name, so that they keep their wave if
+	// consumers are added or removed during a rollout. The share of a wave
+	// is therefore only approximated for small numbers of consumers.
+	Percentage int
+}
+
+// RolloutPolicy describes the waves of a progressive rollout of new Artifact
+// revisions to their consumers. Consumers not selected by any wave form
+// an implicit last wave. The waves of a rollout are released one after the
+// other, a wave is released once all consumers of the previous waves report
+// success for the revision. The rollout is halted if a consumer fails.
+//
+// A consumer reports success if its last applied revision is the rolled out
+// revision and its Ready condition is not False. It fails if its Ready
+// condition is False for the last attempted revision.
+// See LastAppliedRevision and LastAttemptedRevision.
+type RolloutPolicy struct {
+	Waves []RolloutWave
+}
+
+// Assign distributes the action resources to the waves of the policy. The
+// wave of an action resource only depends on its name and labels, not on the
+// other action resources.
+func (p *RolloutPolicy) Assign(actions []ctrlclient.Object) [][]ctrlclient.Object {
+	remaining := append([]ctrlclient.Object(nil), actions...)
+	sort.Slice(remaining, func(i, j int) bool {
+		return objectName(remaining[i]) < objectName(remaining[j])
+	})
+
+	var waves [][]ctrlclient.Object
+	for _, w := range p.Waves {
+		var wave, rest []ctrlclient.Object
+		for _, a := range remaining {
+			var selected bool
+			if w.Selector != nil {
+				selected = w.Selector.Matches(labels.Set(a.GetLabels()))
+			} else {
+				selected = rolloutBucket(a) < w.Percentage
+			}
+			if selected {
+				wave = append(wave, a)
+			} else {
+				rest = append(rest, a)
+			}
+		}
+		waves = append(waves, wave)
+		remaining = rest
+	}
+	return append(waves, remaining)
+}
+
+// rolloutBucket maps an action resource to one of 100 buckets by the hash of
+// its name.
+func rolloutBucket(action metav1.Object) int {
+	h := fnv.New32a()
+	h.Write([]byte(objectName(action)))
+	return int(h.Sum32() % 100)
+}
+
+// LastAttemptedRevisionProvider may be implemented by action resources to
+// provide the last revision of their source they attempted to apply. If it is
+// not implemented, the field status.lastAttemptedRevision is used, if present.
+type LastAttemptedRevisionProvider interface {
+	GetLastAttemptedRevision() string
+}
+
+// LastAttemptedRevision returns the last attempted revision of an action resource.
+func LastAttemptedRevision(action ctrlclient.Object) string {
+	if p, ok := action.(LastAttemptedRevisionProvider); ok {
+		return p.GetLastAttemptedRevision()
+	}
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(action)
+	if err != nil {
+		return ""
+	}
+	rev, _, _ := unstructured.NestedString(u, "status", "lastAttemptedRevision")
+	return rev
+}
+
+type rolloutState int
+
+const (
+	rolloutPending rolloutState = iota
+	rolloutSucceeded
+	rolloutFailed
+)
+
+func actionRolloutState(action ctrlclient.Object, revision string) rolloutState {
+	var ready *metav1.Condition
+	if getter, ok := action.(conditions.Getter); ok {
+		ready = conditions.Get(getter, meta.ReadyCondition)
+	} else if u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(action); err == nil {
+		list, _, _ := unstructured.NestedSlice(u, "status", "conditions")
+		for _, c := range list {
+			var cond metav1.Condition
+			if m, ok := c.(map[string]interface{}); ok &&
+				runtime.DefaultUnstructuredConverter.FromUnstructured(m, &cond) == nil && cond.Type == meta.ReadyCondition {
+				ready = &cond
+			}
+		}
+	}
+
+	failed := ready != nil && ready.Status == metav1.ConditionFalse
+	switch {
+	case failed && LastAttemptedRevision(action) == revision:
+		return rolloutFailed
+	case !failed && LastAppliedRevision(action) == revision:
+		return rolloutSucceeded
+	}
+	return rolloutPending
+}
+
+// evaluateRollout determines the progress of the rollout of a revision to the given waves.
+func evaluateRollout(revision string, waves [][]ctrlclient.Object) *artifactv1.RolloutStatus {
+	status := &artifactv1.RolloutStatus{
+		Revision: revision,
+		Phase:    artifactv1.RolloutCompleted,
+		Waves:    len(waves),
+	}
+	for i, wave := range waves {
+		status.Wave = i
+		pending := 0
+		for _, a := range wave {
+			status.Released = append(status.Released, objectName(a))
+			switch actionRolloutState(a, revision) {
+			case rolloutFailed:
+				status.Failed = append(status.Failed, objectName(a))
+			case rolloutPending:
+				pending++
+			}
+		}
+		switch {
+		case len(status.Failed) > 0:
+			status.Phase = artifactv1.RolloutHalted
+			status.Message = fmt.Sprintf("rollout halted in wave %d, %d consumer(s) failed", i, len(status.Failed))
+			return status
+		case pending > 0:
+			status.Phase = artifactv1.RolloutProgressing
+			status.Message = fmt.Sprintf("waiting for %d consumer(s) of wave %d", pending, i)
+			return status
+		}
+	}
+	status.Wave = len(waves)
+	status.Message = fmt.Sprintf("revision applied by all consumers in %d wave(s)", len(waves))
+	return status
+}
+
+func objectName(obj metav1.Object) string {
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// RolloutPendingError is returned by GetSource if WithRollout is configured
+// and the wave of the action resource has not been released yet.
+type RolloutPendingError struct {
+	Source   string
+	Revision string
+	Message  string
+}
+
+func (e *RolloutPendingError) Error() string {
+	return fmt.Sprintf("revision %s of source '%s' is not yet released for this consumer: %s", e.Revision, e.Source, e.Message)
+}
+
+func IsRolloutPending(err error) bool {
+	var e *RolloutPendingError
+	return errors.As(err, &e)
+}
+
+// checkRollout verifies that the current revision of an Artifact is released
+// for the action resource. As long as the RolloutReconciler has not started
+// the rollout of the current revision, no consumer is served.
+func checkRollout(action metav1.Object, art *artifactv1.Artifact) error {
+	rollout := art.Status.Rollout
+	if rollout == nil || rollout.Revision != art.Spec.Revision {
+		return &RolloutPendingError{Source: objectName(art), Revision: art.Spec.Revision, Message: "the rollout has not been started yet"}
+	}
+	if rollout.Phase == artifactv1.RolloutCompleted {
+		return nil
+	}
+	for _, n := range rollout.Released {
+		if n == objectName(action) {
+			return nil
+		}
+	}
+	return &RolloutPendingError{Source: objectName(art), Revision: art.Spec.Revision, Message: rollout.Message}
+}
+
+// RolloutReconciler drives the progressive rollout of Artifact revisions to
+// the action resources of type T according to the RolloutPolicy configured
+// with WithRollout. The watches registered by Setup hold back all consumers
+// of a new revision. The reconciler publishes the progress in the status of
+// the Artifact and triggers the consumers of each released wave, starting
+// with the first one, by requesting a reconciliation with the annotation
+// reconcile.fluxcd.io/requestedAt.
+//
+// Only one RolloutReconciler may handle the consumers of an Artifact. The
+// reconciler requires the indices registered by Setup.
+type RolloutReconciler[T any, P ActionResourcePointerType[T]] struct {
+	Client  ctrlclient.Client
+	Scheme  *runtime.Scheme
+	Options []Option
+
+	// Now returns the current time, defaults to time.Now.
+	Now func() time.Time
+}
+
+func (r *RolloutReconciler[T, P]) SetupWithManager(mgr ctrl.Manager) error {
+	gk := utils.GetGroupKindForType[T, P](r.Scheme)
+	if gk == nil {
+		return fmt.Errorf("action type not registered in scheme")
+	}
+	if EvalOptions(r.Options...).Rollout == nil {
+		return fmt.Errorf("no rollout policy configured")
+	}
+	var _obj T
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(fmt.Sprintf("artifact-rollout-%s", strings.ToLower(gk.Kind))).
+		For(&artifactv1.Artifact{}).
+		Watches(P(&_obj), handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+			return artifactRequestsForAction(ctx, r.Client, EvalOptions(r.Options...), obj)
+		})).
+		Complete(r)
+}
+
+func (r *RolloutReconciler[T, P]) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+	opts := EvalOptions(r.Options...)
+
+	var art artifactv1.Artifact
+	if err := r.Client.Get(ctx, req.NamespacedName, &art); err != nil {
+		return reconcile.Result{}, ctrlclient.IgnoreNotFound(err)
+	}
+	if art.Spec.Revision == "" || !art.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+
+	var consumers []ctrlclient.Object
+	for _, o := range ListConsumers[T, P](ctx, r.Client, r.Scheme, &art, r.Options...) {
+		if action := o.(ctrlclient.Object); action.GetDeletionTimestamp().IsZero() {
+			consumers = append(consumers, action)
+		}
+	}
+	waves := opts.Rollout.Assign(consumers)
+	status := evaluateRollout(art.Spec.Revision, waves)
+
+	// the consumers of a new revision are held back until the first wave is released here
+	released := -1
+	if prev := art.Status.Rollout; prev != nil && prev.Revision == status.Revision {
+		released = prev.Wave
+	}
+
+	// publish the released waves before triggering their consumers, which
+	// are only served once they are listed in the status
+	if !equality.Semantic.DeepEqual(status, art.Status.Rollout) {
+		patch := ctrlclient.MergeFromWithOptions(art.DeepCopy(), ctrlclient.MergeFromWithOptimisticLock{})
+		art.Status.Rollout = status
+		if err := r.Client.Status().Patch(ctx, &art, patch); err != nil {
+			return reconcile.Result{}, fmt.Errorf("unable to update rollout status of artifact: %w", err)
+		}
+	}
+
+	for i := released + 1; i <= status.Wave && i < len(waves); i++ {
+		log.Info("releasing rollout wave", "revision", status.Revision, "wave", i, "consumers", len(waves[i]))
+		for _, a := range waves[i] {
+			if err := r.requestReconcile(ctx, a); err != nil {
+				// the consumer is served anyway, it picks up the revision with its next reconciliation
+				log.Error(err, "failed to trigger consumer of released wave", "consumer", objectName(a))
+			}
+		}
+	}
+	return reconcile.Result{}, nil
+}
+
+func (r *RolloutReconciler[T, P]) requestReconcile(ctx context.Context, action ctrlclient.Object) error {
+	now := time.Now
+	if r.Now != nil {
+		now = r.Now
+	}
+	patch := ctrlclient.MergeFrom(action.DeepCopyObject().(ctrlclient.Object))
+	annotations := action.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[meta.ReconcileRequestAnnotation] = now().Format(time.RFC3339Nano)
+	action.SetAnnotations(annotations)
+	if err := r.Client.Patch(ctx, action, patch); err != nil {
+		return fmt.Errorf("unable to request reconciliation of '%s': %w", objectName(action), err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package action
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	. "github.com/onsi/gomega"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestCheckRollout(t *testing.T) {
+	action := newTestAction()
+
+	tests := []struct {
+		name    string
+		rollout *artifactv1.RolloutStatus
+		pending bool
+	}{
+		{name: "not started", pending: true},
+		{name: "previous revision", rollout: &artifactv1.RolloutStatus{Revision: "v1", Phase: artifactv1.RolloutCompleted}, pending: true},
+		{name: "released", rollout: &artifactv1.RolloutStatus{Revision: "v2", Phase: artifactv1.RolloutProgressing, Released: []string{"default/app"}}},
+		{name: "not released", rollout: &artifactv1.RolloutStatus{Revision: "v2", Phase: artifactv1.RolloutProgressing, Released: []string{"default/other"}}, pending: true},
+		{name: "completed", rollout: &artifactv1.RolloutStatus{Revision: "v2", Phase: artifactv1.RolloutCompleted}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			art := newTestArtifact("v2")
+			art.Status.Rollout = tt.rollout
+			g.Expect(IsRolloutPending(checkRollout(action, art))).To(Equal(tt.pending))
+		})
+	}
+}
+
+func TestRolloutReconciler(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	art := newTestArtifact("v2")
+	canary, other := newTestAction(), newTestAction()
+	canary.Name, other.Name = "b", "d"
+	client := newTestClient(art, canary, other)
+	r := &RolloutReconciler[testAction, *testAction]{
+		Client:  client,
+		Scheme:  client.Scheme(),
+		Options: []Option{WithRollout(&RolloutPolicy{Waves: []RolloutWave{{Percentage: 50}}})},
+		Now:     func() time.Time { return saturday },
+	}
+	req := reconcile.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(art)}
+	requested := func(name string) string {
+		var a testAction
+		g.Expect(client.Get(ctx, ctrlclient.ObjectKey{Namespace: "default", Name: name}, &a)).To(Succeed())
+		return a.Annotations[meta.ReconcileRequestAnnotation]
+	}
+
+	// the first wave is released and triggered by the reconciler
+	_, err := r.Reconcile(ctx, req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(client.Get(ctx, req.NamespacedName, art)).To(Succeed())
+	g.Expect(art.Status.Rollout.Revision).To(Equal("v2"))
+	g.Expect(art.Status.Rollout.Wave).To(Equal(0))
+	g.Expect(art.Status.Rollout.Released).To(ConsistOf("default/b"))
+	g.Expect(requested("b")).NotTo(BeEmpty())
+	g.Expect(requested("d")).To(BeEmpty())
+	g.Expect(checkRollout(canary, art)).To(Succeed())
+	g.Expect(IsRolloutPending(checkRollout(other, art))).To(BeTrue())
+
+	// a consumer added during the rollout does not shift the released wave
+	added := newTestAction()
+	added.Name = "a"
+	g.Expect(client.Create(ctx, added)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(client.Get(ctx, req.NamespacedName, art)).To(Succeed())
+	g.Expect(art.Status.Rollout.Wave).To(Equal(0))
+	g.Expect(art.Status.Rollout.Released).To(ConsistOf("default/b"))
+	g.Expect(requested("a")).To(BeEmpty())
+
+	// the next wave is released once the canary applied the revision
+	g.Expect(client.Get(ctx, ctrlclient.ObjectKeyFromObject(canary), canary)).To(Succeed())
+	canary.Status.LastAppliedRevision = "v2"
+	g.Expect(client.Status().Update(ctx, canary)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(client.Get(ctx, req.NamespacedName, art)).To(Succeed())
+	g.Expect(art.Status.Rollout.Wave).To(Equal(1))
+	g.Expect(art.Status.Rollout.Released).To(ConsistOf("default/a", "default/b", "default/d"))
+	g.Expect(requested("a")).NotTo(BeEmpty())
+	g.Expect(requested("d")).NotTo(BeEmpty())
+}
+
+func TestRolloutPolicyAssign(t *testing.T) {
+	g := NewWithT(t)
+	consumers := func(names ...string) []ctrlclient.Object {
+		var list []ctrlclient.Object
+		for _, n := range names {
+			a := newTestAction()
+			a.Name = n
+			if n == "canary" {
+				a.Labels = map[string]string{"rollout": "canary"}
+			}
+			list = append(list, a)
+		}
+		return list
+	}
+	names := func(waves [][]ctrlclient.Object) [][]string {
+		result := make([][]string, len(waves))
+		for i, w := range waves {
+			result[i] = []string{}
+			for _, a := range w {
+				result[i] = append(result[i], a.GetName())
+			}
+		}
+		return result
+	}
+	policy := &RolloutPolicy{Waves: []RolloutWave{
+		{Selector: labels.SelectorFromSet(labels.Set{"rollout": "canary"})},
+		{Percentage: 50},
+	}}
+
+	g.Expect(names(policy.Assign(consumers("canary", "b", "d")))).To(Equal([][]string{{"canary"}, {"b"}, {"d"}}))
+
+	// consumers keep their wave if other consumers are added or removed
+	g.Expect(names(policy.Assign(consumers("a", "canary", "b", "c", "d")))).To(Equal([][]string{{"canary"}, {"b", "c"}, {"a", "d"}}))
+	g.Expect(names(policy.Assign(consumers("d", "a")))).To(Equal([][]string{{}, {}, {"a", "d"}}))
+}
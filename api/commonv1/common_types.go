@@ -9,25 +9,58 @@ import (
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-// CrossNamespaceSourceReference contains enough information to let you locate the
+const (
+	// ArtifactGroup is the API group of the Artifact and ClusterArtifact kinds.
+	ArtifactGroup = "openfluxcd.ocm.software"
+
+	// ArtifactVersion is the served API version of the Artifact and
+	// ClusterArtifact kinds.
+	ArtifactVersion = "v1alpha1"
+
+	// ClusterArtifactKind is the kind of ClusterArtifacts, the default
+	// kind of a ClusterSourceRef without APIVersion.
+	ClusterArtifactKind = "ClusterArtifact"
+)
+
+// SourceRef contains enough information to let you locate the
 // typed Kubernetes resource object at cluster level.
 type SourceRef struct {
-	// API version of the referent.
+	// API version of the referent in the form group/version.
 	// +optional
+	// +kubebuilder:validation:MaxLength=316
+	// +kubebuilder:validation:Pattern="^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/[a-z0-9]+$"
 	APIVersion string `json:"apiVersion,omitempty"`
 
 	// Kind of the referent.
 	// +required
+	// +kubebuilder:validation:MinLength=1
 	Kind string `json:"kind"`
 
 	// Name of the referent.
 	// +required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern="^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
 	Name string `json:"name"`
 
 	// Namespace of the referent, defaults to the namespace of the Kubernetes
 	// resource object that contains the reference.
 	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern="^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
 	Namespace string `json:"namespace,omitempty"`
+
+	// Revision pins the reference to a revision of the referent. As long as
+	// it is set, newer revisions are ignored.
+	// +optional
+	// +kubebuilder:validation:MaxLength=1024
+	Revision string `json:"revision,omitempty"`
+
+	// Digest pins the reference to the artifact with the given digest in the
+	// form <algorithm>:<encoded>. As long as it is set, newer revisions are ignored.
+	// +optional
+	// +kubebuilder:validation:Pattern="^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$"
+	Digest string `json:"digest,omitempty"`
 }
 
 func (s *SourceRef) GetObjectKey() ctrlclient.ObjectKey {
@@ -38,22 +71,44 @@ func (s *SourceRef) GetObjectKey() ctrlclient.ObjectKey {
 }
 
 func (s *SourceRef) GetGroupKind() schema.GroupKind {
+	return s.GetGroupVersionKind().GroupKind()
+}
+
+// GetGroupVersionKind returns the group version kind of the referent.
+// If no APIVersion is given, the group of builtin source kinds is
+// guessed and the version is left empty.
+func (s *SourceRef) GetGroupVersionKind() schema.GroupVersionKind {
 	if s.APIVersion == "" {
 		if _, ok := matchers.BuiltinFluxSourceKinds[schema.GroupKind{
 			Group: sourcev1.GroupVersion.Group,
 			Kind:  s.Kind,
 		}]; ok {
-			return schema.GroupKind{
+			return schema.GroupVersionKind{
 				Group: sourcev1.GroupVersion.Group,
 				Kind:  s.Kind,
 			}
 		}
 	}
 
-	return schema.GroupKind{
-		Group: utils.ExtractGroupName(s.APIVersion),
-		Kind:  s.Kind,
+	gv, err := schema.ParseGroupVersion(s.APIVersion)
+	if err != nil {
+		return schema.GroupVersionKind{
+			Group: utils.ExtractGroupName(s.APIVersion),
+			Kind:  s.Kind,
+		}
 	}
+	return gv.WithKind(s.Kind)
+}
+
+var _ utils.VersionedSourceRefProvider = (*SourceRef)(nil)
+var _ utils.PinnedSourceRefProvider = (*SourceRef)(nil)
+
+func (s *SourceRef) GetPinnedRevision() string {
+	return s.Revision
+}
+
+func (s *SourceRef) GetPinnedDigest() string {
+	return s.Digest
 }
 
 func (s *SourceRef) GetName() string {
@@ -70,3 +125,96 @@ func (s *SourceRef) String() string {
 	}
 	return fmt.Sprintf("%s/%s/%s", s.GetGroupKind().Group, s.GetGroupKind().Kind, s.GetName())
 }
+
+// ClusterSourceRef contains enough information to let you locate a
+// cluster-scoped Kubernetes resource object, like a ClusterArtifact.
+type ClusterSourceRef struct {
+	// API version of the referent in the form group/version.
+	// +optional
+	// +kubebuilder:validation:MaxLength=316
+	// +kubebuilder:validation:Pattern="^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/[a-z0-9]+$"
+	APIVersion string `json:"apiVersion,omitempty"`
+
+	// Kind of the referent.
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
+
+	// Name of the referent.
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern="^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
+	Name string `json:"name"`
+
+	// Revision pins the reference to a revision of the referent. As long as
+	// it is set, newer revisions are ignored.
+	// +optional
+	// +kubebuilder:validation:MaxLength=1024
+	Revision string `json:"revision,omitempty"`
+
+	// Digest pins the reference to the artifact with the given digest in the
+	// form <algorithm>:<encoded>. As long as it is set, newer revisions are ignored.
+	// +optional
+	// +kubebuilder:validation:Pattern="^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$"
+	Digest string `json:"digest,omitempty"`
+}
+
+var _ utils.VersionedSourceRefProvider = (*ClusterSourceRef)(nil)
+var _ utils.PinnedSourceRefProvider = (*ClusterSourceRef)(nil)
+var _ utils.ClusterScopedSourceRefProvider = (*ClusterSourceRef)(nil)
+
+func (s *ClusterSourceRef) GetObjectKey() ctrlclient.ObjectKey {
+	return ctrlclient.ObjectKey{
+		Name: s.Name,
+	}
+}
+
+func (s *ClusterSourceRef) GetGroupKind() schema.GroupKind {
+	return s.GetGroupVersionKind().GroupKind()
+}
+
+// GetGroupVersionKind returns the group version kind of the referent.
+// If no APIVersion is given, the group of the ClusterArtifact kind is
+// guessed and the version is left empty.
+func (s *ClusterSourceRef) GetGroupVersionKind() schema.GroupVersionKind {
+	if s.APIVersion == "" && s.Kind == ClusterArtifactKind {
+		return schema.GroupVersionKind{
+			Group: ArtifactGroup,
+			Kind:  s.Kind,
+		}
+	}
+	gv, err := schema.ParseGroupVersion(s.APIVersion)
+	if err != nil {
+		return schema.GroupVersionKind{
+			Group: utils.ExtractGroupName(s.APIVersion),
+			Kind:  s.Kind,
+		}
+	}
+	return gv.WithKind(s.Kind)
+}
+
+func (s *ClusterSourceRef) GetPinnedRevision() string {
+	return s.Revision
+}
+
+func (s *ClusterSourceRef) GetPinnedDigest() string {
+	return s.Digest
+}
+
+// IsClusterScoped returns true, the referent of a ClusterSourceRef has no namespace.
+func (s *ClusterSourceRef) IsClusterScoped() bool {
+	return true
+}
+
+func (s *ClusterSourceRef) GetName() string {
+	return s.Name
+}
+
+func (s *ClusterSourceRef) GetNamespace() string {
+	return ""
+}
+
+func (s *ClusterSourceRef) String() string {
+	return fmt.Sprintf("%s/%s/%s", s.GetGroupKind().Group, s.GetGroupKind().Kind, s.GetName())
+}
@@ -0,0 +1,72 @@
+package commonv1_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/openfluxcd/artifact/api/commonv1"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/utils"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestArtifactConstants(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(commonv1.ArtifactGroup).To(Equal(artifactv1.GroupVersion.Group))
+	g.Expect(commonv1.ArtifactVersion).To(Equal(artifactv1.GroupVersion.Version))
+	g.Expect(commonv1.ClusterArtifactKind).To(Equal(artifactv1.ClusterArtifactKind))
+}
+
+func TestClusterSourceRefDefault(t *testing.T) {
+	g := NewWithT(t)
+
+	ref := commonv1.ClusterSourceRef{Kind: artifactv1.ClusterArtifactKind, Name: "shared"}
+	g.Expect(ref.GetGroupKind()).To(Equal(schema.GroupKind{Group: artifactv1.GroupVersion.Group, Kind: artifactv1.ClusterArtifactKind}))
+
+	ref.Default()
+	g.Expect(ref.APIVersion).To(Equal(artifactv1.GroupVersion.String()))
+	g.Expect(ref.GetGroupVersionKind()).To(Equal(artifactv1.GroupVersion.WithKind(artifactv1.ClusterArtifactKind)))
+
+	other := commonv1.ClusterSourceRef{Kind: "Bucket", Name: "shared"}
+	other.Default()
+	g.Expect(other.APIVersion).To(BeEmpty())
+}
+
+func TestSourceRefGroupVersionKind(t *testing.T) {
+	tests := []struct {
+		name string
+		ref  commonv1.SourceRef
+		gvk  schema.GroupVersionKind
+	}{
+		{name: "versioned",
+			ref: commonv1.SourceRef{APIVersion: "source.toolkit.fluxcd.io/v1beta2", Kind: "GitRepository", Name: "repo"},
+			gvk: schema.GroupVersionKind{Group: "source.toolkit.fluxcd.io", Version: "v1beta2", Kind: "GitRepository"}},
+		{name: "builtin kind without api version",
+			ref: commonv1.SourceRef{Kind: "GitRepository", Name: "repo"},
+			gvk: schema.GroupVersionKind{Group: "source.toolkit.fluxcd.io", Kind: "GitRepository"}},
+		{name: "other kind without api version",
+			ref: commonv1.SourceRef{Kind: "Other", Name: "repo"},
+			gvk: schema.GroupVersionKind{Kind: "Other"}},
+		{name: "invalid api version",
+			ref: commonv1.SourceRef{APIVersion: "example.com/v1/extra", Kind: "Other", Name: "repo"},
+			gvk: schema.GroupVersionKind{Group: "example.com", Kind: "Other"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(tt.ref.GetGroupVersionKind()).To(Equal(tt.gvk))
+			g.Expect(tt.ref.GetGroupKind()).To(Equal(tt.gvk.GroupKind()))
+			g.Expect(utils.GetGroupVersionKind(&tt.ref)).To(Equal(tt.gvk))
+
+			// the version is kept when the namespace is defaulted
+			normalized := utils.NormalizedSourceRef(&tt.ref, "default")
+			g.Expect(normalized.GetNamespace()).To(Equal("default"))
+			g.Expect(utils.GetGroupVersionKind(normalized)).To(Equal(tt.gvk))
+		})
+	}
+
+	g := NewWithT(t)
+	unversioned := utils.NewSourceRef("source.toolkit.fluxcd.io", "GitRepository", "default", "repo")
+	g.Expect(utils.GetGroupVersionKind(unversioned)).To(Equal(schema.GroupVersionKind{Group: "source.toolkit.fluxcd.io", Kind: "GitRepository"}))
+}
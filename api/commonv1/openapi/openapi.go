@@ -0,0 +1,37 @@
+// Package openapi provides the OpenAPI v3 schemas of the source references
+// of the commonv1 package, to be embedded into the schema of CRDs which are
+// not generated by controller-gen.
+package openapi
+
+import (
+	_ "embed"
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed sourceref.schema.yaml
+var sourceRefSchema []byte
+
+// SourceRefSchema returns the OpenAPI v3 schema of a SourceRef including
+// its validation.
+func SourceRefSchema() (*apiextensionsv1.JSONSchemaProps, error) {
+	var props apiextensionsv1.JSONSchemaProps
+	if err := yaml.Unmarshal(sourceRefSchema, &props); err != nil {
+		return nil, fmt.Errorf("invalid source ref schema: %w", err)
+	}
+	return &props, nil
+}
+
+// ClusterSourceRefSchema returns the OpenAPI v3 schema of a ClusterSourceRef.
+// It is the schema of a SourceRef without the namespace.
+func ClusterSourceRefSchema() (*apiextensionsv1.JSONSchemaProps, error) {
+	props, err := SourceRefSchema()
+	if err != nil {
+		return nil, err
+	}
+	props.Description = "ClusterSourceRef contains enough information to let you locate a\ncluster-scoped Kubernetes resource object, like a ClusterArtifact."
+	delete(props.Properties, "namespace")
+	return props, nil
+}
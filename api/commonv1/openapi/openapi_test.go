@@ -0,0 +1,40 @@
+package openapi
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// generatedSpec returns the spec schema of the CRD generated by controller-gen
+// from testdata/holder.
+func generatedSpec(t *testing.T) apiextensionsv1.JSONSchemaProps {
+	data, err := os.ReadFile("testdata/crd/holder.openfluxcd.ocm.software_holders.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var crd apiextensionsv1.CustomResourceDefinition
+	if err := yaml.Unmarshal(data, &crd); err != nil {
+		t.Fatal(err)
+	}
+	return crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+}
+
+func TestSourceRefSchema(t *testing.T) {
+	g := NewWithT(t)
+
+	props, err := SourceRefSchema()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(*props).To(Equal(generatedSpec(t).Properties["sourceRef"]))
+}
+
+func TestClusterSourceRefSchema(t *testing.T) {
+	g := NewWithT(t)
+
+	props, err := ClusterSourceRefSchema()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(*props).To(Equal(generatedSpec(t).Properties["clusterSourceRef"]))
+}
@@ -0,0 +1,24 @@
+// Package holder contains a CRD embedding the source references, used to
+// compare the schema fragments with the schema generated by controller-gen.
+// +kubebuilder:object:generate=false
+// +groupName=holder.openfluxcd.ocm.software
+// +versionName=v1
+package holder
+
+import (
+	"github.com/openfluxcd/artifact/api/commonv1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type HolderSpec struct {
+	SourceRef        commonv1.SourceRef        `json:"sourceRef"`
+	ClusterSourceRef commonv1.ClusterSourceRef `json:"clusterSourceRef"`
+}
+
+// +kubebuilder:object:root=true
+type Holder struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec HolderSpec `json:"spec,omitempty"`
+}
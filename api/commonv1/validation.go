@@ -0,0 +1,95 @@
+package commonv1
+
+import (
+	"regexp"
+	"strings"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	"github.com/openfluxcd/artifact/matchers"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Validate checks the reference with the same rules as the CRD schema.
+func (s *SourceRef) Validate() error {
+	return s.ValidateField(nil).ToAggregate()
+}
+
+// ValidateField checks the reference with the same rules as the CRD schema
+// and reports errors relative to the given field path.
+func (s *SourceRef) ValidateField(fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	if s.APIVersion != "" {
+		gv, err := schema.ParseGroupVersion(s.APIVersion)
+		switch {
+		case err != nil || gv.Group == "" || !strings.Contains(s.APIVersion, "/"):
+			errs = append(errs, field.Invalid(fldPath.Child("apiVersion"), s.APIVersion, "must have the form group/version"))
+		default:
+			for _, msg := range validation.IsDNS1123Subdomain(gv.Group) {
+				errs = append(errs, field.Invalid(fldPath.Child("apiVersion"), s.APIVersion, "invalid group: "+msg))
+			}
+			for _, msg := range validation.IsDNS1123Label(gv.Version) {
+				errs = append(errs, field.Invalid(fldPath.Child("apiVersion"), s.APIVersion, "invalid version: "+msg))
+			}
+		}
+	}
+	if s.Kind == "" {
+		errs = append(errs, field.Required(fldPath.Child("kind"), "kind must not be empty"))
+	}
+	if s.Name == "" {
+		errs = append(errs, field.Required(fldPath.Child("name"), "name must not be empty"))
+	} else {
+		for _, msg := range validation.IsDNS1123Label(s.Name) {
+			errs = append(errs, field.Invalid(fldPath.Child("name"), s.Name, msg))
+		}
+	}
+	if s.Namespace != "" {
+		for _, msg := range validation.IsDNS1123Label(s.Namespace) {
+			errs = append(errs, field.Invalid(fldPath.Child("namespace"), s.Namespace, msg))
+		}
+	}
+	if len(s.Revision) > 1024 {
+		errs = append(errs, field.TooLong(fldPath.Child("revision"), s.Revision, 1024))
+	}
+	if s.Digest != "" && !digestRegexp.MatchString(s.Digest) {
+		errs = append(errs, field.Invalid(fldPath.Child("digest"), s.Digest, "must have the form <algorithm>:<encoded>"))
+	}
+	return errs
+}
+
+var digestRegexp = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$`)
+
+// Default sets the namespace of the referent to the given namespace if it
+// is not set, and completes the APIVersion for builtin source kinds.
+func (s *SourceRef) Default(namespace string) {
+	if s.Namespace == "" {
+		s.Namespace = namespace
+	}
+	if s.APIVersion == "" {
+		gk := schema.GroupKind{Group: sourcev1.GroupVersion.Group, Kind: s.Kind}
+		if versions := matchers.BuiltinFluxSourceVersions.Versions(gk); len(versions) > 0 {
+			s.APIVersion = gk.WithVersion(versions[0]).GroupVersion().String()
+		}
+	}
+}
+
+// Validate checks the reference with the same rules as the CRD schema.
+func (s *ClusterSourceRef) Validate() error {
+	return s.ValidateField(nil).ToAggregate()
+}
+
+// ValidateField checks the reference with the same rules as the CRD schema
+// and reports errors relative to the given field path.
+func (s *ClusterSourceRef) ValidateField(fldPath *field.Path) field.ErrorList {
+	ref := SourceRef{APIVersion: s.APIVersion, Kind: s.Kind, Name: s.Name, Revision: s.Revision, Digest: s.Digest}
+	return ref.ValidateField(fldPath)
+}
+
+// Default completes the APIVersion for the ClusterArtifact kind.
+func (s *ClusterSourceRef) Default() {
+	if s.APIVersion == "" && s.Kind == ClusterArtifactKind {
+		s.APIVersion = ArtifactGroup + "/" + ArtifactVersion
+	}
+}
@@ -24,6 +24,14 @@ import (
 const (
 	// ArtifactKind is the string representation of an Artifact.
 	ArtifactKind = "Artifact"
+
+	// MediaTypeMetadataKey is the Metadata key holding the media type of
+	// Artifacts created before the MediaType field has been introduced.
+	MediaTypeMetadataKey = "openfluxcd.ocm.software/media-type"
+
+	// InUseProtectionFinalizer blocks the deletion of an Artifact as long as
+	// it has consumers.
+	InUseProtectionFinalizer = "openfluxcd.ocm.software/in-use-protection"
 )
 
 // ArtifactSpec defines the desired state of Artifact
@@ -56,12 +64,183 @@ type ArtifactSpec struct {
 	// Metadata holds upstream information such as OCI annotations.
 	// +optional
 	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// MediaType is the media type of the content, e.g. application/tar+gzip,
+	// application/tar+zstd, application/zip, application/octet-stream for a
+	// raw file or application/vnd.cncf.helm.chart.content.v1.tar+gzip for a
+	// Helm chart. It defaults to application/tar+gzip.
+	// +optional
+	// +kubebuilder:validation:MaxLength=255
+	// +kubebuilder:validation:Pattern="^[a-zA-Z0-9][a-zA-Z0-9!#$&^_.+-]*/[a-zA-Z0-9][a-zA-Z0-9!#$&^_.+-]*$"
+	MediaType string `json:"mediaType,omitempty"`
+}
+
+const (
+	// MediaTypeTarGzip is the media type of gzip compressed tar archives.
+	MediaTypeTarGzip = "application/tar+gzip"
+	// MediaTypeTarZstd is the media type of zstd compressed tar archives.
+	MediaTypeTarZstd = "application/tar+zstd"
+	// MediaTypeZip is the media type of zip archives.
+	MediaTypeZip = "application/zip"
+	// MediaTypeRaw is the media type of a single file used as is.
+	MediaTypeRaw = "application/octet-stream"
+	// MediaTypeHelmChart is the media type of packaged Helm charts.
+	MediaTypeHelmChart = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+)
+
+// GetMediaType returns the media type of the content. If the field is not
+// set, the MediaTypeMetadataKey of the Metadata is used.
+func (s *ArtifactSpec) GetMediaType() string {
+	if s.MediaType != "" {
+		return s.MediaType
+	}
+	return s.Metadata[MediaTypeMetadataKey]
 }
 
 // ArtifactStatus defines the observed state of Artifact
 type ArtifactStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// ObservedGeneration is the last observed generation of the Artifact
+	// object.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions holds the conditions for the Artifact. If set, the Ready
+	// condition signals whether the Artifact may be consumed.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Consumers lists the action resources consuming the Artifact.
+	// +optional
+	Consumers []ArtifactConsumer `json:"consumers,omitempty"`
+
+	// TrackedConsumerKinds lists the kinds of action resources, whose
+	// consumers are maintained in Consumers, in the form Kind.group.
+	// Consumers of other kinds are not listed, even if they exist.
+	// +optional
+	TrackedConsumerKinds []string `json:"trackedConsumerKinds,omitempty"`
+
+	// History lists previously published revisions of the Artifact which
+	// are still retained by the storage, most recent first. It is maintained
+	// by the controller producing the Artifact and used to serve source
+	// references pinned to an older revision.
+	// +optional
+	History []ArtifactRevision `json:"history,omitempty"`
+
+	// Rollout describes the progressive rollout of the current revision
+	// to the consumers of the Artifact.
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+}
+
+// ArtifactRevision describes a retained revision of an Artifact.
+type ArtifactRevision struct {
+	// URL is the HTTP address of the retained revision.
+	// +required
+	URL string `json:"url"`
+
+	// Revision is the revision of the Artifact.
+	// +required
+	Revision string `json:"revision"`
+
+	// Digest is the digest of the file in the form of '<algorithm>:<checksum>'.
+	// +optional
+	Digest string `json:"digest,omitempty"`
+
+	// LastUpdateTime is the timestamp of the revision.
+	// +required
+	LastUpdateTime metav1.Time `json:"lastUpdateTime"`
+
+	// Size is the number of bytes in the file.
+	// +optional
+	Size *int64 `json:"size,omitempty"`
+
+	// MediaType is the media type of the content of the revision.
+	// +optional
+	// +kubebuilder:validation:MaxLength=255
+	// +kubebuilder:validation:Pattern="^[a-zA-Z0-9][a-zA-Z0-9!#$&^_.+-]*/[a-zA-Z0-9][a-zA-Z0-9!#$&^_.+-]*$"
+	MediaType string `json:"mediaType,omitempty"`
+}
+
+// RevisionOf returns the history entry for the artifact described by the spec.
+func RevisionOf(s *ArtifactSpec) ArtifactRevision {
+	return ArtifactRevision{
+		URL:            s.URL,
+		Revision:       s.Revision,
+		Digest:         s.Digest,
+		LastUpdateTime: s.LastUpdateTime,
+		Size:           s.Size,
+		MediaType:      s.GetMediaType(),
+	}
+}
+
+// RolloutPhase is the phase of a progressive rollout.
+type RolloutPhase string
+
+const (
+	// RolloutProgressing means that the consumers of a wave are still
+	// applying the revision.
+	RolloutProgressing RolloutPhase = "Progressing"
+	// RolloutHalted means that a consumer failed to apply the revision and
+	// no further waves are released.
+	RolloutHalted RolloutPhase = "Halted"
+	// RolloutCompleted means that all consumers applied the revision.
+	RolloutCompleted RolloutPhase = "Completed"
+)
+
+// RolloutStatus describes the progressive rollout of a revision.
+type RolloutStatus struct {
+	// Revision is the revision being rolled out.
+	// +required
+	Revision string `json:"revision"`
+
+	// Phase of the rollout.
+	// +required
+	Phase RolloutPhase `json:"phase"`
+
+	// Wave is the index of the current wave, starting with 0.
+	// +required
+	Wave int `json:"wave"`
+
+	// Waves is the number of waves of the rollout.
+	// +required
+	Waves int `json:"waves"`
+
+	// Released lists the consumers of the released waves as namespace/name.
+	// +optional
+	Released []string `json:"released,omitempty"`
+
+	// Failed lists the consumers which failed to apply the revision as
+	// namespace/name.
+	// +optional
+	Failed []string `json:"failed,omitempty"`
+
+	// Message is a human-readable description of the rollout progress.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ArtifactConsumer describes an action resource consuming an Artifact.
+type ArtifactConsumer struct {
+	// Group of the consuming resource.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Kind of the consuming resource.
+	// +required
+	Kind string `json:"kind"`
+
+	// Namespace of the consuming resource.
+	// +required
+	Namespace string `json:"namespace"`
+
+	// Name of the consuming resource.
+	// +required
+	Name string `json:"name"`
+
+	// LastAppliedRevision is the revision of the Artifact last applied
+	// by the consuming resource.
+	// +optional
+	LastAppliedRevision string `json:"lastAppliedRevision,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -100,3 +279,18 @@ func (a *Artifact) GetArtifact() *sourcev1.Artifact {
 		Metadata:       a.Spec.Metadata,
 	}
 }
+
+// GetMediaType returns the media type of the content, see ArtifactSpec.GetMediaType.
+func (a *Artifact) GetMediaType() string {
+	return a.Spec.GetMediaType()
+}
+
+// GetConditions returns the status conditions of the object.
+func (a *Artifact) GetConditions() []metav1.Condition {
+	return a.Status.Conditions
+}
+
+// SetConditions sets the status conditions on the object.
+func (a *Artifact) SetConditions(conditions []metav1.Condition) {
+	a.Status.Conditions = conditions
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 openfluxcd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ArtifactChannelKind is the string representation of an ArtifactChannel.
+	ArtifactChannelKind = "ArtifactChannel"
+)
+
+// ArtifactChannelSpec defines the Artifact a channel points to.
+type ArtifactChannelSpec struct {
+	// ArtifactRef references the Artifact in the namespace of the channel
+	// the channel points to. Changing it retargets the channel and triggers
+	// the action resources referencing the channel.
+	// +required
+	ArtifactRef ArtifactChannelTarget `json:"artifactRef"`
+}
+
+// ArtifactChannelTarget references an Artifact.
+type ArtifactChannelTarget struct {
+	// Name of the Artifact.
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	Name string `json:"name"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Artifact",type=string,JSONPath=`.spec.artifactRef.name`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ArtifactChannel is a named pointer to an Artifact, e.g. stable or latest.
+// Action resources may reference a channel instead of the Artifact itself.
+type ArtifactChannel struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ArtifactChannelSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ArtifactChannelList contains a list of ArtifactChannel
+type ArtifactChannelList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ArtifactChannel `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ArtifactChannel{}, &ArtifactChannelList{})
+}
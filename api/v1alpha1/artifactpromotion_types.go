@@ -0,0 +1,152 @@
+/*
+Copyright 2024 openfluxcd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ArtifactPromotionKind is the string representation of an ArtifactPromotion.
+	ArtifactPromotionKind = "ArtifactPromotion"
+
+	// PromotedFromAnnotation records the source Artifact of a promoted
+	// Artifact as namespace/name.
+	PromotedFromAnnotation = "openfluxcd.ocm.software/promoted-from"
+	// PromotedByAnnotation records the ArtifactPromotion which created a
+	// promoted Artifact as namespace/name.
+	PromotedByAnnotation = "openfluxcd.ocm.software/promoted-by"
+	// PromotedAtAnnotation records the time of the promotion in RFC 3339 format.
+	PromotedAtAnnotation = "openfluxcd.ocm.software/promoted-at"
+	// PromotionApprovedByAnnotation records who approved the promotion.
+	PromotionApprovedByAnnotation = "openfluxcd.ocm.software/promotion-approved-by"
+)
+
+// ArtifactPromotionSpec describes the promotion of a revision of an Artifact
+// into the namespace of the ArtifactPromotion.
+type ArtifactPromotionSpec struct {
+	// Source references the Artifact to promote.
+	// +required
+	Source PromotionSource `json:"source"`
+
+	// Revision is the revision of the source Artifact to promote.
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=1024
+	Revision string `json:"revision"`
+
+	// Digest is the digest of the source Artifact to promote.
+	// +required
+	// +kubebuilder:validation:Pattern="^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$"
+	Digest string `json:"digest"`
+
+	// TargetName is the name of the promoted Artifact in the namespace of the
+	// ArtifactPromotion, defaults to the name of the source Artifact.
+	// +optional
+	// +kubebuilder:validation:MaxLength=253
+	TargetName string `json:"targetName,omitempty"`
+
+	// Approved is the manual approval of the promotion. The revision is not
+	// promoted before it is set to true.
+	// +optional
+	Approved bool `json:"approved,omitempty"`
+
+	// ApprovedBy documents who approved the promotion.
+	// +optional
+	ApprovedBy string `json:"approvedBy,omitempty"`
+
+	// SoakTime is the minimum time the revision must have been published by
+	// the source Artifact before it is promoted.
+	// +optional
+	SoakTime *metav1.Duration `json:"soakTime,omitempty"`
+}
+
+// PromotionSource references the Artifact to promote.
+type PromotionSource struct {
+	// Namespace of the Artifact.
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	Namespace string `json:"namespace"`
+
+	// Name of the Artifact.
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	Name string `json:"name"`
+}
+
+// ArtifactPromotionStatus defines the observed state of ArtifactPromotion
+type ArtifactPromotionStatus struct {
+	// ObservedGeneration is the last observed generation of the promotion.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions holds the conditions of the promotion. The Ready condition
+	// signals whether the revision has been promoted, its reason describes
+	// the condition the promotion is waiting for.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// PromotedAt is the time the revision has been promoted.
+	// +optional
+	PromotedAt *metav1.Time `json:"promotedAt,omitempty"`
+}
+
+// GetConditions returns the status conditions of the object.
+func (p *ArtifactPromotion) GetConditions() []metav1.Condition {
+	return p.Status.Conditions
+}
+
+// SetConditions sets the status conditions on the object.
+func (p *ArtifactPromotion) SetConditions(conditions []metav1.Condition) {
+	p.Status.Conditions = conditions
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.source.name`
+// +kubebuilder:printcolumn:name="Revision",type=string,JSONPath=`.spec.revision`
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].message"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ArtifactPromotion copies a revision of an Artifact from another namespace
+// into the namespace of the promotion, once the promotion is approved, the
+// revision has soaked for the configured time and all consumers of the source
+// Artifact in its namespace are healthy. The access to the source Artifact
+// must be permitted by a SourceAccessGrant in its namespace.
+type ArtifactPromotion struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ArtifactPromotionSpec   `json:"spec,omitempty"`
+	Status ArtifactPromotionStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ArtifactPromotionList contains a list of ArtifactPromotion
+type ArtifactPromotionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ArtifactPromotion `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ArtifactPromotion{}, &ArtifactPromotionList{})
+}
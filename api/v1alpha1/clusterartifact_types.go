@@ -0,0 +1,100 @@
+/*
+Copyright 2024 openfluxcd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ClusterArtifactKind is the string representation of a ClusterArtifact.
+	ClusterArtifactKind = "ClusterArtifact"
+)
+
+// ClusterArtifactSpec defines the desired state of ClusterArtifact
+type ClusterArtifactSpec struct {
+	ArtifactSpec `json:",inline"`
+
+	// AllowedNamespaces lists the namespaces whose action resources may
+	// consume the ClusterArtifact.
+	// +optional
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+
+	// NamespaceSelector selects the namespaces whose action resources may
+	// consume the ClusterArtifact by their labels. An empty selector
+	// selects all namespaces.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Revision",type=string,JSONPath=`.spec.revision`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ClusterArtifact is a cluster-scoped Artifact for content shared by many
+// namespaces. It can only be consumed by action resources in the namespaces
+// allowed by its spec, if neither AllowedNamespaces nor a NamespaceSelector
+// is given, it cannot be consumed at all.
+type ClusterArtifact struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterArtifactSpec `json:"spec,omitempty"`
+	Status ArtifactStatus      `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterArtifactList contains a list of ClusterArtifact
+type ClusterArtifactList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterArtifact `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterArtifact{}, &ClusterArtifactList{})
+}
+
+func (a *ClusterArtifact) GetArtifact() *sourcev1.Artifact {
+	return &sourcev1.Artifact{
+		URL:            a.Spec.URL,
+		Revision:       a.Spec.Revision,
+		Digest:         a.Spec.Digest,
+		LastUpdateTime: a.Spec.LastUpdateTime,
+		Size:           a.Spec.Size,
+		Metadata:       a.Spec.Metadata,
+	}
+}
+
+// GetMediaType returns the media type of the content, see ArtifactSpec.GetMediaType.
+func (a *ClusterArtifact) GetMediaType() string {
+	return a.Spec.GetMediaType()
+}
+
+// GetConditions returns the status conditions of the object.
+func (a *ClusterArtifact) GetConditions() []metav1.Condition {
+	return a.Status.Conditions
+}
+
+// SetConditions sets the status conditions on the object.
+func (a *ClusterArtifact) SetConditions(conditions []metav1.Condition) {
+	a.Status.Conditions = conditions
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2024 openfluxcd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// MaintenancePolicyKind is the string representation of a MaintenancePolicy.
+	MaintenancePolicyKind = "MaintenancePolicy"
+)
+
+// MaintenanceWindow describes recurring time windows in which action
+// resources may pick up new revisions of their sources.
+type MaintenanceWindow struct {
+	// Schedule is a cron expression with the five fields minute, hour,
+	// day of month, month and day of week, describing the start of the window.
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Duration is the length of the window.
+	// +required
+	Duration metav1.Duration `json:"duration"`
+
+	// TimeZone is the IANA name of the time zone of the schedule,
+	// defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// MaintenancePolicySpec defines the maintenance windows for action resources
+// in the namespace of the policy.
+type MaintenancePolicySpec struct {
+	// ActionSelector selects the action resources by their labels. If it is
+	// not set, the policy applies to all action resources in the namespace.
+	// +optional
+	ActionSelector *metav1.LabelSelector `json:"actionSelector,omitempty"`
+
+	// Sources restricts the policy to action resources referencing one of
+	// the given sources. If empty, the policy applies to all sources.
+	// +optional
+	Sources []MaintenancePolicySource `json:"sources,omitempty"`
+
+	// Windows are the maintenance windows. New revisions are passed to the
+	// selected action resources only while one of the windows is open.
+	// +required
+	// +kubebuilder:validation:MinItems=1
+	Windows []MaintenanceWindow `json:"windows"`
+}
+
+// MaintenancePolicySource describes sources a MaintenancePolicy applies to.
+type MaintenancePolicySource struct {
+	// Group is the API group of the source.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Kind is the kind of the source.
+	// +required
+	Kind string `json:"kind"`
+
+	// Name of the source. If empty, all sources of the given kind match.
+	// +optional
+	Name string `json:"name,omitempty"`
+}
+
+// MaintenancePolicyStatus defines the observed state of MaintenancePolicy
+type MaintenancePolicyStatus struct {
+	// ObservedGeneration is the last observed generation of the policy.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Pending lists the action resources with revision changes deferred
+	// until the next maintenance window.
+	// +optional
+	Pending []DeferredRevision `json:"pending,omitempty"`
+}
+
+// DeferredRevision describes a revision change deferred for an action resource.
+type DeferredRevision struct {
+	// Group of the action resource.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Kind of the action resource.
+	// +required
+	Kind string `json:"kind"`
+
+	// Name of the action resource.
+	// +required
+	Name string `json:"name"`
+
+	// Revision is the deferred revision of the source.
+	// +required
+	Revision string `json:"revision"`
+
+	// NextWindow is the start of the next maintenance window.
+	// +optional
+	NextWindow *metav1.Time `json:"nextWindow,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// MaintenancePolicy is the Schema for the maintenancepolicies API
+type MaintenancePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MaintenancePolicySpec   `json:"spec,omitempty"`
+	Status MaintenancePolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MaintenancePolicyList contains a list of MaintenancePolicy
+type MaintenancePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MaintenancePolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MaintenancePolicy{}, &MaintenancePolicyList{})
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 openfluxcd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// RevisionApprovalKind is the string representation of a RevisionApproval.
+	RevisionApprovalKind = "RevisionApproval"
+)
+
+// RevisionApprovalSpec approves a revision of a source for the action
+// resources referencing it.
+type RevisionApprovalSpec struct {
+	// Source is the source as referenced by the action resources.
+	// +required
+	Source ApprovedSource `json:"source"`
+
+	// Action restricts the approval to a single action resource in the
+	// namespace of the approval. Without it, the approval applies to all
+	// action resources referencing the source and must be created in the
+	// namespace of the source. For cluster-scoped sources, like
+	// ClusterArtifacts, it applies to the action resources in the namespace
+	// of the approval.
+	// +optional
+	Action *ApprovedAction `json:"action,omitempty"`
+
+	// Revision is the approved revision.
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	Revision string `json:"revision"`
+
+	// Digest additionally restricts the approval to the artifact with the
+	// given digest.
+	// +optional
+	// +kubebuilder:validation:Pattern="^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$"
+	Digest string `json:"digest,omitempty"`
+
+	// ApprovedBy documents who approved the revision.
+	// +optional
+	ApprovedBy string `json:"approvedBy,omitempty"`
+}
+
+// ApprovedSource identifies a source.
+type ApprovedSource struct {
+	// Group is the API group of the source.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Kind is the kind of the source.
+	// +required
+	Kind string `json:"kind"`
+
+	// Namespace of the source, defaults to the namespace of the approval.
+	// It is ignored for cluster-scoped sources.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the source.
+	// +required
+	Name string `json:"name"`
+}
+
+// ApprovedAction identifies an action resource in the namespace of the approval.
+type ApprovedAction struct {
+	// Group is the API group of the action resource.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Kind is the kind of the action resource.
+	// +required
+	Kind string `json:"kind"`
+
+	// Name of the action resource.
+	// +required
+	Name string `json:"name"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.source.name`
+// +kubebuilder:printcolumn:name="Revision",type=string,JSONPath=`.spec.revision`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// RevisionApproval is the Schema for the revisionapprovals API
+type RevisionApproval struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RevisionApprovalSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RevisionApprovalList contains a list of RevisionApproval
+type RevisionApprovalList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RevisionApproval `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RevisionApproval{}, &RevisionApprovalList{})
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2024 openfluxcd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SourceAccessGrantKind is the string representation of a SourceAccessGrant.
+	SourceAccessGrantKind = "SourceAccessGrant"
+)
+
+// SourceAccessGrantSpec defines which action resources of other namespaces
+// are allowed to reference sources in the namespace of the grant.
+type SourceAccessGrantSpec struct {
+	// From describes the referencing action resources the grant applies to.
+	// +required
+	// +kubebuilder:validation:MinItems=1
+	From []SourceAccessGrantFrom `json:"from"`
+
+	// To describes the sources in the namespace of the grant that may be
+	// referenced.
+	// +required
+	// +kubebuilder:validation:MinItems=1
+	To []SourceAccessGrantTo `json:"to"`
+}
+
+// SourceAccessGrantFrom describes action resources that are granted access.
+type SourceAccessGrantFrom struct {
+	// Group is the API group of the referencing action resource.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Kind is the kind of the referencing action resource.
+	// +required
+	Kind string `json:"kind"`
+
+	// Namespace of the referencing action resource.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// NamespaceSelector selects the namespaces of the referencing action
+	// resources by their labels.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// SourceAccessGrantTo describes sources that may be referenced.
+type SourceAccessGrantTo struct {
+	// Group is the API group of the referenced source.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Kind is the kind of the referenced source.
+	// +required
+	Kind string `json:"kind"`
+
+	// Name of the referenced source. If empty, all sources of the given
+	// kind may be referenced.
+	// +optional
+	Name string `json:"name,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SourceAccessGrant is the Schema for the sourceaccessgrants API
+type SourceAccessGrant struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SourceAccessGrantSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SourceAccessGrantList contains a list of SourceAccessGrant
+type SourceAccessGrantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SourceAccessGrant `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SourceAccessGrant{}, &SourceAccessGrantList{})
+}
@@ -0,0 +1,202 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// DefaultIgnoreFile is the name of the ignore file read from the root of the
+// archived content.
+const DefaultIgnoreFile = ".sourceignore"
+
+// DefaultExcludes are always excluded from archives.
+var DefaultExcludes = []string{".git/", ".hg/", ".svn/", ".bzr/"}
+
+// Result describes a built archive.
+type Result struct {
+	// Digest of the archive, as used for ArtifactSpec.Digest.
+	Digest string
+
+	// Size of the archive in bytes.
+	Size int64
+}
+
+// Builder builds reproducible gzip compressed tar archives, archiving the
+// same content always results in the same bytes and thus the same digest.
+//
+// Only regular files are archived, in lexical order of their paths. The
+// modification times, owners and permissions are normalized, files keep only
+// whether they are executable. Empty directories, symbolic links and other
+// file types are omitted.
+type Builder struct {
+	// Include are patterns in the style of .sourceignore files. If given,
+	// only files matched by them, or within directories matched by them,
+	// are archived.
+	Include []string
+
+	// Exclude are patterns in the style of .sourceignore files, matched files
+	// and directories are not archived. They are applied in addition to the
+	// DefaultExcludes and the patterns of the ignore file.
+	Exclude []string
+
+	// IgnoreFile is the name of the file in the root of the content with
+	// additional exclude patterns. It defaults to DefaultIgnoreFile, the
+	// file is optional.
+	IgnoreFile string
+}
+
+// BuildDir archives the content of a directory.
+func (b *Builder) BuildDir(dir string, w io.Writer) (*Result, error) {
+	return b.Build(os.DirFS(dir), w)
+}
+
+// BuildFile archives the content of fsys into the file at path.
+func (b *Builder) BuildFile(fsys fs.FS, path string) (*Result, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	res, err := b.Build(fsys, f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Build writes the archive of the content of fsys to w.
+func (b *Builder) Build(fsys fs.FS, w io.Writer) (*Result, error) {
+	files, err := b.files(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	digester := digest.SHA256.Digester()
+	counter := &countingWriter{}
+	zw := gzip.NewWriter(io.MultiWriter(w, digester.Hash(), counter))
+	tw := tar.NewWriter(zw)
+	for _, name := range files {
+		if err := addFile(tw, fsys, name); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return &Result{Digest: digester.Digest().String(), Size: counter.n}, nil
+}
+
+// files returns the sorted paths of the regular files to archive.
+func (b *Builder) files(fsys fs.FS) ([]string, error) {
+	exclude := ParsePatterns(DefaultExcludes...)
+	ignoreFile := b.IgnoreFile
+	if ignoreFile == "" {
+		ignoreFile = DefaultIgnoreFile
+	}
+	if f, err := fsys.Open(ignoreFile); err == nil {
+		patterns, err := ReadPatterns(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %w", ignoreFile, err)
+		}
+		exclude = append(exclude, patterns...)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("unable to read %s: %w", ignoreFile, err)
+	}
+	exclude = append(exclude, ParsePatterns(b.Exclude...)...)
+	include := ParsePatterns(b.Include...)
+
+	var files []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		if exclude.Match(name, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && (len(include) == 0 || included(include, name)) {
+			files = append(files, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list files: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// included checks whether the file or one of its parent directories is
+// matched by the include patterns.
+func included(include Patterns, name string) bool {
+	if include.Match(name, false) {
+		return true
+	}
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if include.Match(dir, true) {
+			return true
+		}
+	}
+	return false
+}
+
+func addFile(tw *tar.Writer, fsys fs.FS, name string) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	mode := int64(0o644)
+	if info.Mode().Perm()&0o111 != 0 {
+		mode = 0o755
+	}
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     info.Size(),
+		Mode:     mode,
+		ModTime:  time.Unix(0, 0),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("unable to archive %q: %w", name, err)
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("unable to archive %q: %w", name, err)
+	}
+	return nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
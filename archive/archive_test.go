@@ -0,0 +1,103 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/go-digest"
+	"github.com/openfluxcd/artifact/fetch"
+)
+
+func content(modTime time.Time, mode os.FileMode) fstest.MapFS {
+	return fstest.MapFS{
+		"deploy/app.yaml":     {Data: []byte("kind: ConfigMap\n"), ModTime: modTime, Mode: mode},
+		"deploy/run.sh":       {Data: []byte("#!/bin/sh\n"), ModTime: modTime, Mode: mode | 0o111},
+		"docs/README.md":      {Data: []byte("# docs\n"), ModTime: modTime, Mode: mode},
+		"tmp/cache/data.bin":  {Data: []byte("cache"), ModTime: modTime, Mode: mode},
+		".git/HEAD":           {Data: []byte("ref: refs/heads/main\n"), ModTime: modTime, Mode: mode},
+		".sourceignore":       {Data: []byte("# generated\ntmp/\n*.md\n!docs/README.md\n"), ModTime: modTime, Mode: mode},
+		"deploy/values.local": {Data: []byte("local: true\n"), ModTime: modTime, Mode: mode},
+	}
+}
+
+func files(t *testing.T, data []byte) []string {
+	t.Helper()
+	dir := t.TempDir()
+	g := NewWithT(t)
+	g.Expect(fetch.UntarGzip(context.Background(), nil, bytes.NewReader(data), dir)).To(Succeed())
+	var list []string
+	g.Expect(filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			list = append(list, filepath.ToSlash(rel))
+		}
+		return err
+	})).To(Succeed())
+	return list
+}
+
+func TestBuildReproducible(t *testing.T) {
+	g := NewWithT(t)
+
+	var first, second bytes.Buffer
+	res, err := (&Builder{}).Build(content(time.Now(), 0o600), &first)
+	g.Expect(err).NotTo(HaveOccurred())
+	_, err = (&Builder{}).Build(content(time.Unix(12345, 0), 0o664), &second)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(first.Bytes()).To(Equal(second.Bytes()))
+	g.Expect(res.Digest).To(Equal(digest.SHA256.FromBytes(first.Bytes()).String()))
+	g.Expect(res.Size).To(Equal(int64(first.Len())))
+}
+
+func TestBuildPatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		builder  Builder
+		expected []string
+	}{
+		{
+			name:     "ignore file",
+			expected: []string{".sourceignore", "deploy/app.yaml", "deploy/run.sh", "deploy/values.local", "docs/README.md"},
+		},
+		{
+			name:     "exclude",
+			builder:  Builder{Exclude: []string{"*.local", "/docs"}},
+			expected: []string{".sourceignore", "deploy/app.yaml", "deploy/run.sh"},
+		},
+		{
+			name:     "include",
+			builder:  Builder{Include: []string{"deploy/", "**/*.md"}, Exclude: []string{"**/*.sh"}},
+			expected: []string{"deploy/app.yaml", "deploy/values.local", "docs/README.md"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			var buf bytes.Buffer
+			_, err := tt.builder.Build(content(time.Now(), 0o644), &buf)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(files(t, buf.Bytes())).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestPatternsMatch(t *testing.T) {
+	g := NewWithT(t)
+	patterns := ParsePatterns("*.yaml", "!keep.yaml", "/build/", "a/**/z")
+
+	g.Expect(patterns.Match("deep/dir/app.yaml", false)).To(BeTrue())
+	g.Expect(patterns.Match("dir/keep.yaml", false)).To(BeFalse())
+	g.Expect(patterns.Match("build", true)).To(BeTrue())
+	g.Expect(patterns.Match("build", false)).To(BeFalse())
+	g.Expect(patterns.Match("sub/build", true)).To(BeFalse())
+	g.Expect(patterns.Match("a/z", false)).To(BeTrue())
+	g.Expect(patterns.Match("a/b/c/z", false)).To(BeTrue())
+	g.Expect(patterns.Match("b/a/z", false)).To(BeFalse())
+}
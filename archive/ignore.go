@@ -0,0 +1,119 @@
+package archive
+
+import (
+	"bufio"
+	"io"
+	"path"
+	"strings"
+)
+
+// pattern is a single pattern in the style of .sourceignore and .gitignore
+// files.
+type pattern struct {
+	segments []string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// Patterns is an ordered list of patterns in the style of .sourceignore
+// files. The last pattern matching a path decides, patterns prefixed with
+// '!' negate a previous match.
+type Patterns []pattern
+
+// ParsePatterns parses patterns in the style of .sourceignore files.
+//
+//   - blank lines and lines starting with '#' are ignored
+//   - a leading '!' negates the pattern
+//   - a trailing '/' only matches directories
+//   - patterns containing a '/' other than a trailing one are relative to
+//     the root, all other patterns match a name at any depth
+//   - '*', '?' and '[...]' match within a path element as in path.Match,
+//     a '**' element matches any number of directories
+func ParsePatterns(lines ...string) Patterns {
+	var list Patterns
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var p pattern
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			p.anchored = true
+			line = strings.TrimLeft(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		p.segments = strings.Split(line, "/")
+		list = append(list, p)
+	}
+	return list
+}
+
+// ReadPatterns parses the patterns of a .sourceignore file.
+func ReadPatterns(r io.Reader) (Patterns, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ParsePatterns(lines...), nil
+}
+
+// Match reports whether the slash separated path relative to the root is
+// matched by the patterns.
+func (l Patterns) Match(name string, isDir bool) bool {
+	elems := strings.Split(name, "/")
+	matched := false
+	for _, p := range l {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.match(elems) {
+			matched = !p.negate
+		}
+	}
+	return matched
+}
+
+func (p pattern) match(elems []string) bool {
+	if p.anchored {
+		return matchSegments(p.segments, elems)
+	}
+	return len(elems) > 0 && matchSegments(p.segments, elems[len(elems)-1:])
+}
+
+func matchSegments(segments, elems []string) bool {
+	for len(segments) > 0 {
+		if segments[0] == "**" {
+			for i := 0; i <= len(elems); i++ {
+				if matchSegments(segments[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		if ok, err := path.Match(segments[0], elems[0]); err != nil || !ok {
+			return false
+		}
+		segments, elems = segments[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
@@ -20,12 +20,15 @@ import (
 	"crypto/tls"
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -35,6 +38,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	openfluxcdv1alpha1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/gc"
+	"github.com/openfluxcd/artifact/graph"
+	"github.com/openfluxcd/artifact/promotion"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -50,12 +56,23 @@ func init() {
 	// +kubebuilder:scaffold:scheme
 }
 
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var enableGC bool
+	var gcTTL time.Duration
+	var gcInterval time.Duration
+	var gcDryRun bool
+	var gcOwnerKinds string
+	var storagePath string
+	var enableDebugEndpoints bool
+	var enablePromotion bool
+	var promotionConsumerKinds string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metric endpoint binds to. "+
 		"Use the port :8080. If not set, it will be 0 in order to disable the metrics server")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -66,6 +83,30 @@ func main() {
 		"If set the metrics endpoint is served securely")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.BoolVar(&enableGC, "artifact-gc", false,
+		"If set, Artifacts whose owner does not exist anymore are garbage collected.")
+	flag.DurationVar(&gcTTL, "artifact-gc-ttl", 0,
+		"The time after which Artifacts without owner are garbage collected if not updated. Zero disables expiration.")
+	flag.DurationVar(&gcInterval, "artifact-gc-interval", 10*time.Minute,
+		"The interval in which the owners of Artifacts are checked by the garbage collection.")
+	flag.BoolVar(&gcDryRun, "artifact-gc-dry-run", false,
+		"If set, the garbage collection only reports the Artifacts to be deleted.")
+	flag.StringVar(&gcOwnerKinds, "artifact-gc-owner-kinds", "",
+		"Comma separated list of owner kinds in the form Kind.group checked by the garbage collection. "+
+			"It defaults to the Flux source kinds, the controller requires get permissions for other kinds.")
+	flag.StringVar(&storagePath, "storage-path", "",
+		"The local directory serving the Artifact content. If set, the content of garbage collected Artifacts is deleted.")
+	flag.BoolVar(&enableDebugEndpoints, "debug-endpoints", false,
+		"If set, debug endpoints like the dependency graph and, with --artifact-gc, the garbage collection "+
+			"report are served by the metrics server. The dependency graph of this controller is limited to "+
+			"Artifacts, ClusterArtifacts and the consumers recorded in their status, Flux sources are not shown.")
+	flag.BoolVar(&enablePromotion, "artifact-promotion", false,
+		"If set, ArtifactPromotions copy Artifacts between namespaces.")
+	flag.StringVar(&promotionConsumerKinds, "artifact-promotion-consumer-kinds", "",
+		"Comma separated list of consumer kinds in the form Kind.group whose health is checked before a promotion. "+
+			"It defaults to the Flux Kustomization and HelmRelease kinds. The consumers of every kind must be tracked "+
+			"in the status of the Artifacts by a ConsumerReconciler, otherwise promotions are blocked. "+
+			"The controller requires get permissions for other kinds.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -122,6 +163,49 @@ func main() {
 		os.Exit(1)
 	}
 
+	if enableGC {
+		var storage gc.Storage
+		if storagePath != "" {
+			storage = &gc.DirectoryStorage{Root: storagePath}
+		}
+		collector := &gc.Reconciler{
+			Client:      mgr.GetClient(),
+			OwnerReader: mgr.GetAPIReader(),
+			OwnerKinds:  parseGroupKinds(gcOwnerKinds),
+			Storage:     storage,
+			TTL:         gcTTL,
+			Interval:    gcInterval,
+			DryRun:      gcDryRun,
+		}
+		if err = collector.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ArtifactGC")
+			os.Exit(1)
+		}
+		if enableDebugEndpoints {
+			if err := mgr.AddMetricsServerExtraHandler(gc.DefaultPath, collector.Handler()); err != nil {
+				setupLog.Error(err, "unable to register debug endpoint", "endpoint", gc.DefaultPath)
+				os.Exit(1)
+			}
+		}
+	}
+	if enablePromotion {
+		if err = (&promotion.Reconciler{
+			Client:         mgr.GetClient(),
+			ConsumerReader: mgr.GetAPIReader(),
+			ConsumerKinds:  parseGroupKinds(promotionConsumerKinds),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ArtifactPromotion")
+			os.Exit(1)
+		}
+	}
+	if enableDebugEndpoints {
+		// the scheme only knows the Artifact types and no action types, the
+		// consumers are taken from the status of the Artifacts
+		if err := graph.AddToManager(mgr, &graph.Builder{}); err != nil {
+			setupLog.Error(err, "unable to register debug endpoint", "endpoint", graph.DefaultPath)
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -139,3 +223,15 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// parseGroupKinds parses a comma separated list of kinds in the form Kind.group.
+// It returns nil for an empty list, so that the defaults apply.
+func parseGroupKinds(list string) []schema.GroupKind {
+	var kinds []schema.GroupKind
+	if list != "" {
+		for _, k := range strings.Split(list, ",") {
+			kinds = append(kinds, schema.ParseGroupKind(strings.TrimSpace(k)))
+		}
+	}
+	return kinds
+}
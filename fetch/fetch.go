@@ -0,0 +1,130 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	"github.com/opencontainers/go-digest"
+	"github.com/openfluxcd/artifact/integrity"
+	"github.com/openfluxcd/artifact/oci"
+)
+
+// Source is an object publishing an Artifact.
+type Source interface {
+	GetArtifact() *sourcev1.Artifact
+}
+
+// Fetcher downloads the Artifact of a source and extracts it with the
+// Unpacker registered for its media type, see MediaTypeOf. Artifacts with
+// http(s) URLs are downloaded with the HTTP client, Artifacts with oci:// URLs
+// are pulled from the registry. The content is verified against the digest of
+// the Artifact before it is extracted, mismatches are reported as
+// integrity.DigestMismatchError. The total size of the extracted files is
+// limited by MaxExtractedSize, custom Unpackers must honor the limit of the
+// context themselves, see MaxExtractedSize.
+type Fetcher struct {
+	// HTTPClient is used for http and https URLs, it defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// OCI is used for oci:// URLs.
+	OCI *oci.Fetcher
+
+	// Unpackers defaults to the DefaultRegistry.
+	Unpackers *Registry
+
+	// MaxExtractedSize limits the total size of the extracted files, it
+	// defaults to DefaultMaxExtractedSize. A negative size disables the limit.
+	MaxExtractedSize int64
+}
+
+// Fetch extracts the Artifact of the source into the directory.
+func (f *Fetcher) Fetch(ctx context.Context, src Source, dir string) error {
+	art := src.GetArtifact()
+	if art == nil {
+		return fmt.Errorf("source has no artifact")
+	}
+	unpackers := f.Unpackers
+	if unpackers == nil {
+		unpackers = DefaultRegistry
+	}
+	unpacker, err := unpackers.Lookup(MediaTypeOf(src))
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp("", "artifact-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := f.download(ctx, art, tmp); err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if f.MaxExtractedSize != 0 {
+		ctx = WithMaxExtractedSize(ctx, f.MaxExtractedSize)
+	}
+	if err := unpacker.Unpack(ctx, art, tmp, dir); err != nil {
+		return fmt.Errorf("unable to unpack artifact %s: %w", art.URL, err)
+	}
+	return nil
+}
+
+func (f *Fetcher) download(ctx context.Context, art *sourcev1.Artifact, w io.Writer) error {
+	switch {
+	case oci.IsOCIURL(art.URL):
+		fetcher := f.OCI
+		if fetcher == nil {
+			fetcher = &oci.Fetcher{}
+		}
+		return fetcher.Fetch(ctx, art, w)
+	case strings.HasPrefix(art.URL, "http://"), strings.HasPrefix(art.URL, "https://"):
+		return f.downloadHTTP(ctx, art, w)
+	}
+	return fmt.Errorf("unsupported artifact url %q", art.URL)
+}
+
+func (f *Fetcher) downloadHTTP(ctx context.Context, art *sourcev1.Artifact, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, art.URL, nil)
+	if err != nil {
+		return err
+	}
+	client := f.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to download artifact %s: %w", art.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to download artifact %s: %s", art.URL, resp.Status)
+	}
+
+	if art.Digest == "" {
+		_, err = io.Copy(w, resp.Body)
+		return err
+	}
+	expected, err := digest.Parse(art.Digest)
+	if err != nil {
+		return fmt.Errorf("invalid digest of artifact %s: %w", art.URL, err)
+	}
+	digester := expected.Algorithm().Digester()
+	if _, err := io.Copy(io.MultiWriter(w, digester.Hash()), resp.Body); err != nil {
+		return fmt.Errorf("unable to download artifact %s: %w", art.URL, err)
+	}
+	if actual := digester.Digest(); actual != expected {
+		return &integrity.DigestMismatchError{URL: art.URL, Expected: expected.String(), Actual: actual.String()}
+	}
+	return nil
+}
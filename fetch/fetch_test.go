@@ -0,0 +1,208 @@
+package fetch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	"github.com/klauspost/compress/zstd"
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/go-digest"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/integrity"
+)
+
+func tarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zstded(t *testing.T, data []byte) []byte {
+	t.Helper()
+	zw, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zw.Close()
+	return zw.EncodeAll(data, nil)
+}
+
+func zipped(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFetch(t *testing.T) {
+	files := map[string]string{"deploy/app.yaml": "kind: ConfigMap\n"}
+	content := map[string][]byte{
+		"/app.tar.gz":  gzipped(t, tarball(t, files)),
+		"/app.tar.zst": zstded(t, tarball(t, files)),
+		"/app.zip":     zipped(t, files),
+		"/app.yaml":    []byte("kind: ConfigMap\n"),
+		"/chart.tgz":   gzipped(t, tarball(t, map[string]string{"podinfo/Chart.yaml": "name: podinfo\n"})),
+		"/evil.tar.gz": gzipped(t, tarball(t, map[string]string{"../evil.yaml": "kind: Secret\n"})),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := content[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+
+	newArtifact := func(path, mediaType string) *artifactv1.Artifact {
+		return &artifactv1.Artifact{Spec: artifactv1.ArtifactSpec{
+			URL:       srv.URL + path,
+			Revision:  "v1",
+			Digest:    digest.SHA256.FromBytes(content[path]).String(),
+			MediaType: mediaType,
+		}}
+	}
+
+	tests := []struct {
+		name     string
+		artifact *artifactv1.Artifact
+		file     string
+	}{
+		{"default", newArtifact("/app.tar.gz", ""), "deploy/app.yaml"},
+		{"tar+zstd", newArtifact("/app.tar.zst", artifactv1.MediaTypeTarZstd), "deploy/app.yaml"},
+		{"zip", newArtifact("/app.zip", artifactv1.MediaTypeZip), "deploy/app.yaml"},
+		{"raw", newArtifact("/app.yaml", artifactv1.MediaTypeRaw), "app.yaml"},
+		{"helm chart", newArtifact("/chart.tgz", artifactv1.MediaTypeHelmChart), "podinfo/Chart.yaml"},
+		{"metadata fallback", func() *artifactv1.Artifact {
+			art := newArtifact("/app.zip", "")
+			art.Spec.Metadata = map[string]string{artifactv1.MediaTypeMetadataKey: artifactv1.MediaTypeZip}
+			return art
+		}(), "deploy/app.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			dir := t.TempDir()
+			g.Expect((&Fetcher{}).Fetch(context.Background(), tt.artifact, dir)).To(Succeed())
+			_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(tt.file)))
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+
+	t.Run("unsupported media type", func(t *testing.T) {
+		g := NewWithT(t)
+		err := (&Fetcher{}).Fetch(context.Background(), newArtifact("/app.yaml", "text/plain"), t.TempDir())
+		g.Expect(IsUnsupportedMediaType(err)).To(BeTrue(), "unexpected error %v", err)
+	})
+
+	t.Run("digest mismatch", func(t *testing.T) {
+		g := NewWithT(t)
+		art := newArtifact("/app.tar.gz", "")
+		art.Spec.Digest = digest.SHA256.FromString("other").String()
+		err := (&Fetcher{}).Fetch(context.Background(), art, t.TempDir())
+		g.Expect(integrity.IsDigestMismatch(err)).To(BeTrue(), "unexpected error %v", err)
+	})
+
+	t.Run("path traversal", func(t *testing.T) {
+		g := NewWithT(t)
+		dir := t.TempDir()
+		g.Expect((&Fetcher{}).Fetch(context.Background(), newArtifact("/evil.tar.gz", ""), filepath.Join(dir, "target"))).NotTo(Succeed())
+		_, err := os.Stat(filepath.Join(dir, "evil.yaml"))
+		g.Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+}
+
+func TestMaxExtractedSize(t *testing.T) {
+	bomb := strings.Repeat("0", 1<<20)
+	files := map[string]string{"a.yaml": bomb, "b.yaml": bomb}
+	art := &sourcev1.Artifact{URL: "http://storage/app.yaml"}
+
+	tests := []struct {
+		name     string
+		unpacker UnpackerFunc
+		content  []byte
+		limit    int64
+		exceeded bool
+	}{
+		{name: "gzip", unpacker: UntarGzip, content: gzipped(t, tarball(t, files)), limit: 1 << 20, exceeded: true},
+		{name: "zstd", unpacker: UntarZstd, content: zstded(t, tarball(t, files)), limit: 1 << 20, exceeded: true},
+		{name: "zip", unpacker: Unzip, content: zipped(t, files), limit: 1 << 20, exceeded: true},
+		{name: "raw", unpacker: CopyRaw, content: []byte(bomb), limit: 1 << 19, exceeded: true},
+		{name: "within limit", unpacker: UntarGzip, content: gzipped(t, tarball(t, files)), limit: 2 << 20},
+		{name: "unlimited", unpacker: UntarGzip, content: gzipped(t, tarball(t, files)), limit: -1},
+		{name: "default", unpacker: UntarGzip, content: gzipped(t, tarball(t, files))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := WithMaxExtractedSize(context.Background(), tt.limit)
+			err := tt.unpacker(ctx, art, bytes.NewReader(tt.content), t.TempDir())
+			if tt.exceeded {
+				g.Expect(IsMaxExtractedSizeExceeded(err)).To(BeTrue(), "unexpected error %v", err)
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+
+	t.Run("fetcher", func(t *testing.T) {
+		g := NewWithT(t)
+		content := gzipped(t, tarball(t, files))
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(content)
+		}))
+		defer srv.Close()
+		art := &artifactv1.Artifact{Spec: artifactv1.ArtifactSpec{URL: srv.URL + "/app.tar.gz", Revision: "v1"}}
+
+		err := (&Fetcher{MaxExtractedSize: 1 << 20}).Fetch(context.Background(), art, t.TempDir())
+		g.Expect(IsMaxExtractedSizeExceeded(err)).To(BeTrue(), "unexpected error %v", err)
+		g.Expect((&Fetcher{}).Fetch(context.Background(), art, t.TempDir())).To(Succeed())
+	})
+}
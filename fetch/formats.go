@@ -0,0 +1,219 @@
+package fetch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultMaxExtractedSize is the maximum total size of the files extracted
+// from an Artifact, if no limit is configured.
+const DefaultMaxExtractedSize int64 = 100 << 20
+
+type maxExtractedSizeKey struct{}
+
+// WithMaxExtractedSize returns a context limiting the total size of the files
+// extracted by the builtin Unpackers. Zero selects the DefaultMaxExtractedSize,
+// a negative size disables the limit.
+func WithMaxExtractedSize(ctx context.Context, size int64) context.Context {
+	return context.WithValue(ctx, maxExtractedSizeKey{}, size)
+}
+
+// MaxExtractedSize returns the limit for the total size of extracted files
+// configured for the context. It returns a negative size if there is no limit.
+func MaxExtractedSize(ctx context.Context) int64 {
+	size, _ := ctx.Value(maxExtractedSizeKey{}).(int64)
+	if size == 0 {
+		return DefaultMaxExtractedSize
+	}
+	return size
+}
+
+// MaxExtractedSizeExceededError is returned if the extracted content of an
+// Artifact exceeds the configured limit, e.g. for decompression bombs.
+type MaxExtractedSizeExceededError struct {
+	Limit int64
+}
+
+func (e *MaxExtractedSizeExceededError) Error() string {
+	return fmt.Sprintf("extracted content exceeds the maximum size of %d bytes", e.Limit)
+}
+
+func IsMaxExtractedSizeExceeded(err error) bool {
+	var e *MaxExtractedSizeExceededError
+	return errors.As(err, &e)
+}
+
+// budget tracks the remaining size of the content to extract.
+type budget struct {
+	limit     int64
+	remaining int64
+}
+
+func newBudget(ctx context.Context) *budget {
+	limit := MaxExtractedSize(ctx)
+	return &budget{limit: limit, remaining: limit}
+}
+
+// copy copies r to w, failing if the remaining budget is exceeded.
+func (b *budget) copy(w io.Writer, r io.Reader) error {
+	if b.limit < 0 {
+		_, err := io.Copy(w, r)
+		return err
+	}
+	n, err := io.CopyN(w, r, b.remaining+1)
+	b.remaining -= n
+	if b.remaining < 0 {
+		return &MaxExtractedSizeExceededError{Limit: b.limit}
+	}
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// UntarGzip extracts a gzip compressed tar archive.
+func UntarGzip(ctx context.Context, _ *sourcev1.Artifact, r io.Reader, dir string) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("invalid gzip content: %w", err)
+	}
+	defer zr.Close()
+	return untar(newBudget(ctx), zr, dir)
+}
+
+// UntarZstd extracts a zstd compressed tar archive.
+func UntarZstd(ctx context.Context, _ *sourcev1.Artifact, r io.Reader, dir string) error {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("invalid zstd content: %w", err)
+	}
+	defer zr.Close()
+	return untar(newBudget(ctx), zr, dir)
+}
+
+// UntarHelmChart extracts a packaged Helm chart. The archive must contain
+// the chart directory with its Chart.yaml.
+func UntarHelmChart(ctx context.Context, art *sourcev1.Artifact, r io.Reader, dir string) error {
+	if err := UntarGzip(ctx, art, r, dir); err != nil {
+		return err
+	}
+	charts, err := filepath.Glob(filepath.Join(dir, "*", "Chart.yaml"))
+	if err != nil {
+		return err
+	}
+	if len(charts) == 0 {
+		return fmt.Errorf("invalid helm chart: no Chart.yaml found")
+	}
+	return nil
+}
+
+// Unzip extracts a zip archive. The content is buffered in a temporary
+// file, because zip archives cannot be read sequentially.
+func Unzip(ctx context.Context, _ *sourcev1.Artifact, r io.Reader, dir string) error {
+	f, ok := r.(*os.File)
+	if !ok {
+		tmp, err := os.CreateTemp("", "artifact-*.zip")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		if _, err := io.Copy(tmp, r); err != nil {
+			return err
+		}
+		f = tmp
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return fmt.Errorf("invalid zip content: %w", err)
+	}
+	b := newBudget(ctx)
+	for _, entry := range zr.File {
+		if err := extract(b, dir, entry.Name, entry.Mode(), entry.Open); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyRaw stores the content as a single file named after the last path
+// element of the Artifact URL.
+func CopyRaw(ctx context.Context, art *sourcev1.Artifact, r io.Reader, dir string) error {
+	name := "artifact"
+	if u, err := url.Parse(art.URL); err == nil {
+		if base := path.Base(u.Path); base != "." && base != "/" {
+			name = base
+		}
+	}
+	return extract(newBudget(ctx), dir, name, 0o644, func() (io.ReadCloser, error) {
+		return io.NopCloser(r), nil
+	})
+}
+
+func untar(b *budget, r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid tar content: %w", err)
+		}
+		if err := extract(b, dir, hdr.Name, hdr.FileInfo().Mode(), func() (io.ReadCloser, error) {
+			return io.NopCloser(tr), nil
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+// extract creates a directory or regular file of an archive in dir. Other
+// file types, like symbolic links, are skipped. Names escaping dir are rejected.
+// The size of the extracted file is charged to the budget.
+func extract(b *budget, dir, name string, mode fs.FileMode, open func() (io.ReadCloser, error)) error {
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return fmt.Errorf("invalid path %q in archive", name)
+	}
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	switch {
+	case mode.IsDir():
+		return os.MkdirAll(target, 0o755)
+	case !mode.IsRegular():
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	rc, err := open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm()|0o600)
+	if err != nil {
+		return err
+	}
+	if err := b.copy(f, rc); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to extract %q: %w", name, err)
+	}
+	return f.Close()
+}
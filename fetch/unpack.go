@@ -0,0 +1,129 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+)
+
+// Unpacker extracts the content of an Artifact into a directory.
+type Unpacker interface {
+	Unpack(ctx context.Context, art *sourcev1.Artifact, r io.Reader, dir string) error
+}
+
+// UnpackerFunc is a function implementing the Unpacker interface.
+type UnpackerFunc func(ctx context.Context, art *sourcev1.Artifact, r io.Reader, dir string) error
+
+func (f UnpackerFunc) Unpack(ctx context.Context, art *sourcev1.Artifact, r io.Reader, dir string) error {
+	return f(ctx, art, r, dir)
+}
+
+// UnsupportedMediaTypeError is returned if no Unpacker is registered for
+// the media type of an Artifact.
+type UnsupportedMediaTypeError struct {
+	MediaType string
+}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("unsupported media type %q", e.MediaType)
+}
+
+func IsUnsupportedMediaType(err error) bool {
+	var e *UnsupportedMediaTypeError
+	return errors.As(err, &e)
+}
+
+// Registry maps media types to Unpackers.
+type Registry struct {
+	lock      sync.RWMutex
+	unpackers map[string]Unpacker
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{unpackers: map[string]Unpacker{}}
+}
+
+// Register registers an Unpacker for the given media types, replacing
+// previously registered Unpackers.
+func (r *Registry) Register(u Unpacker, mediaTypes ...string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, t := range mediaTypes {
+		r.unpackers[t] = u
+	}
+}
+
+// Lookup returns the Unpacker for a media type.
+func (r *Registry) Lookup(mediaType string) (Unpacker, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	u, ok := r.unpackers[mediaType]
+	if !ok {
+		return nil, &UnsupportedMediaTypeError{MediaType: mediaType}
+	}
+	return u, nil
+}
+
+// MediaTypes returns the registered media types.
+func (r *Registry) MediaTypes() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	var list []string
+	for t := range r.unpackers {
+		list = append(list, t)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// DefaultRegistry contains the Unpackers for the builtin media types and
+// the equivalent media types used by OCI and Flux.
+var DefaultRegistry = NewRegistry()
+
+func init() {
+	DefaultRegistry.Register(UnpackerFunc(UntarGzip),
+		artifactv1.MediaTypeTarGzip,
+		"application/x-tar+gzip",
+		"application/gzip",
+		"application/vnd.oci.image.layer.v1.tar+gzip",
+		"application/vnd.cncf.flux.content.v1.tar+gzip",
+	)
+	DefaultRegistry.Register(UnpackerFunc(UntarZstd),
+		artifactv1.MediaTypeTarZstd,
+		"application/vnd.oci.image.layer.v1.tar+zstd",
+	)
+	DefaultRegistry.Register(UnpackerFunc(Unzip), artifactv1.MediaTypeZip)
+	DefaultRegistry.Register(UnpackerFunc(CopyRaw), artifactv1.MediaTypeRaw)
+	DefaultRegistry.Register(UnpackerFunc(UntarHelmChart), artifactv1.MediaTypeHelmChart)
+}
+
+// MediaTypeProvider is implemented by sources providing the media type of
+// their Artifact, like Artifact and ClusterArtifact.
+type MediaTypeProvider interface {
+	GetMediaType() string
+}
+
+// MediaTypeOf returns the media type of the Artifact of a source. If the
+// source does not provide it, the MediaTypeMetadataKey of the Artifact
+// Metadata is used. Artifacts without media type are gzip compressed tar
+// archives, as produced by the Flux source-controller.
+func MediaTypeOf(src interface{ GetArtifact() *sourcev1.Artifact }) string {
+	if p, ok := src.(MediaTypeProvider); ok {
+		if t := p.GetMediaType(); t != "" {
+			return t
+		}
+	}
+	if art := src.GetArtifact(); art != nil {
+		if t := art.Metadata[artifactv1.MediaTypeMetadataKey]; t != "" {
+			return t
+		}
+	}
+	return artifactv1.MediaTypeTarGzip
+}
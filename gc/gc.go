@@ -0,0 +1,308 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	sourcev1b2 "github.com/fluxcd/source-controller/api/v1beta2"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/utils"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Reason describes why an Artifact is garbage.
+type Reason string
+
+const (
+	// ReasonOwnerGone is used for Artifacts whose owner does not exist anymore.
+	ReasonOwnerGone Reason = "OwnerGone"
+	// ReasonOwnerStale is used for Artifacts whose owner has been recreated.
+	ReasonOwnerStale Reason = "OwnerStale"
+	// ReasonExpired is used for Artifacts without owner exceeding the TTL.
+	ReasonExpired Reason = "Expired"
+)
+
+// Finding describes an Artifact to be garbage collected.
+type Finding struct {
+	Artifact ctrlclient.ObjectKey `json:"artifact"`
+	Reason   Reason               `json:"reason"`
+	Message  string               `json:"message"`
+}
+
+// DefaultOwnerKinds are the owner kinds checked by the Reconciler, if no
+// OwnerKinds are configured.
+var DefaultOwnerKinds = []schema.GroupKind{
+	{Group: sourcev1.GroupVersion.Group, Kind: sourcev1.GitRepositoryKind},
+	{Group: sourcev1.GroupVersion.Group, Kind: sourcev1.HelmRepositoryKind},
+	{Group: sourcev1.GroupVersion.Group, Kind: sourcev1.HelmChartKind},
+	{Group: sourcev1b2.GroupVersion.Group, Kind: sourcev1b2.BucketKind},
+	{Group: sourcev1b2.GroupVersion.Group, Kind: sourcev1b2.OCIRepositoryKind},
+}
+
+// Reconciler deletes Artifacts whose owner does not exist anymore, and
+// Artifacts without owner, which have not been updated for longer than TTL.
+// Only owners of the OwnerKinds are checked, Artifacts owned by other kinds
+// are never collected. The content of deleted Artifacts, including the
+// revisions in their history, is removed from the Storage, if configured and
+// not referenced by another Artifact anymore.
+// In DryRun mode nothing is deleted, the findings are only reported.
+type Reconciler struct {
+	Client ctrlclient.Client
+
+	// OwnerReader is used to read the metadata of owners. It should be
+	// uncached, otherwise an informer is started for every owner kind.
+	// It defaults to the API reader of the manager.
+	// +optional
+	OwnerReader ctrlclient.Reader
+
+	// OwnerKinds are the owner kinds to check. The controller requires
+	// get permissions for them. It defaults to DefaultOwnerKinds.
+	// +optional
+	OwnerKinds []schema.GroupKind
+
+	// Storage is used to remove the content of deleted Artifacts.
+	// +optional
+	Storage Storage
+
+	// TTL for Artifacts without owner. Zero disables expiration.
+	TTL time.Duration
+
+	// Interval is used to recheck the owners of Artifacts.
+	// It defaults to 10 minutes.
+	Interval time.Duration
+
+	// DryRun only reports the Artifacts to be deleted.
+	DryRun bool
+
+	// Now returns the current time, it defaults to time.Now.
+	Now func() time.Time
+}
+
+// +kubebuilder:rbac:groups=openfluxcd.ocm.software,resources=artifacts,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=openfluxcd.ocm.software,resources=clusterartifacts,verbs=get;list;watch
+// +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=gitrepositories;helmrepositories;helmcharts;buckets;ocirepositories,verbs=get
+
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.OwnerReader == nil {
+		r.OwnerReader = mgr.GetAPIReader()
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("artifact-gc").
+		For(&artifactv1.Artifact{}).
+		Complete(r)
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	var art artifactv1.Artifact
+	if err := r.Client.Get(ctx, req.NamespacedName, &art); err != nil {
+		return reconcile.Result{}, ctrlclient.IgnoreNotFound(err)
+	}
+	if !art.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+
+	finding, requeue, err := r.Check(ctx, &art)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if finding == nil {
+		return reconcile.Result{RequeueAfter: requeue}, nil
+	}
+
+	if r.DryRun {
+		log.Info("dry-run: artifact would be garbage collected", "reason", finding.Reason, "message", finding.Message)
+		return reconcile.Result{RequeueAfter: r.interval()}, nil
+	}
+	if err := r.collect(ctx, &art); err != nil {
+		return reconcile.Result{}, err
+	}
+	log.Info("artifact garbage collected", "reason", finding.Reason, "message", finding.Message)
+	return reconcile.Result{}, nil
+}
+
+// Check determines whether an Artifact is garbage. If not, it returns the
+// duration after which it should be checked again.
+func (r *Reconciler) Check(ctx context.Context, art *artifactv1.Artifact) (*Finding, time.Duration, error) {
+	key := ctrlclient.ObjectKeyFromObject(art)
+
+	if len(art.OwnerReferences) == 0 {
+		if r.TTL <= 0 {
+			return nil, 0, nil
+		}
+		age := r.now().Sub(lastUpdate(art))
+		if age < r.TTL {
+			return nil, r.TTL - age, nil
+		}
+		return &Finding{Artifact: key, Reason: ReasonExpired,
+			Message: fmt.Sprintf("artifact has not been updated for %s, exceeding the TTL of %s", age.Round(time.Second), r.TTL)}, 0, nil
+	}
+
+	for _, ref := range art.OwnerReferences {
+		if !r.checksOwnerKind(ref) {
+			continue
+		}
+		state, err := utils.CheckOwner(ctx, r.ownerReader(), art.Namespace, ref)
+		if err != nil {
+			return nil, 0, err
+		}
+		switch state {
+		case utils.OwnerGone:
+			return &Finding{Artifact: key, Reason: ReasonOwnerGone,
+				Message: fmt.Sprintf("owner %s '%s' does not exist anymore", ref.Kind, ref.Name)}, 0, nil
+		case utils.OwnerStale:
+			return &Finding{Artifact: key, Reason: ReasonOwnerStale,
+				Message: fmt.Sprintf("owner %s '%s' has been recreated, expected uid %s", ref.Kind, ref.Name, ref.UID)}, 0, nil
+		}
+	}
+	return nil, r.interval(), nil
+}
+
+// Report checks all Artifacts in the given namespace (all namespaces if
+// empty) and returns the findings without deleting anything.
+func (r *Reconciler) Report(ctx context.Context, namespace string) ([]Finding, error) {
+	var list artifactv1.ArtifactList
+	if err := r.Client.List(ctx, &list, ctrlclient.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("unable to list artifacts: %w", err)
+	}
+	var findings []Finding
+	for i := range list.Items {
+		finding, _, err := r.Check(ctx, &list.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		if finding != nil {
+			findings = append(findings, *finding)
+		}
+	}
+	return findings, nil
+}
+
+func (r *Reconciler) collect(ctx context.Context, art *artifactv1.Artifact) error {
+	if r.Storage != nil {
+		shared, err := r.sharedContent(ctx, art)
+		if err != nil {
+			return err
+		}
+		for _, url := range contentURLs(art) {
+			if shared[url] {
+				ctrl.LoggerFrom(ctx).Info("artifact content is still referenced by another artifact, keeping it", "url", url)
+			} else if err := r.Storage.Delete(ctx, url); err != nil {
+				return err
+			}
+		}
+	}
+	if err := r.Client.Delete(ctx, art, ctrlclient.Preconditions{UID: &art.UID}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("unable to delete artifact: %w", err)
+	}
+	return nil
+}
+
+// contentURLs returns the URLs of the current revision and the history of an Artifact.
+func contentURLs(art *artifactv1.Artifact) []string {
+	urls := []string{art.Spec.URL}
+	for _, h := range art.Status.History {
+		if !slices.Contains(urls, h.URL) {
+			urls = append(urls, h.URL)
+		}
+	}
+	return slices.DeleteFunc(urls, func(url string) bool { return url == "" })
+}
+
+// sharedContent returns the content URLs of an Artifact, which are referenced
+// by another Artifact or ClusterArtifact, e.g. a promoted copy, either as
+// current revision or in its history.
+func (r *Reconciler) sharedContent(ctx context.Context, art *artifactv1.Artifact) (map[string]bool, error) {
+	urls := contentURLs(art)
+	shared := map[string]bool{}
+	var list artifactv1.ArtifactList
+	if err := r.Client.List(ctx, &list); err != nil {
+		return nil, fmt.Errorf("unable to list artifacts: %w", err)
+	}
+	for i := range list.Items {
+		other := &list.Items[i]
+		if other.UID != art.UID {
+			markReferenced(shared, urls, &other.Spec, other.Status.History)
+		}
+	}
+	var clusterList artifactv1.ClusterArtifactList
+	if err := r.Client.List(ctx, &clusterList); err != nil {
+		return nil, fmt.Errorf("unable to list cluster artifacts: %w", err)
+	}
+	for i := range clusterList.Items {
+		other := &clusterList.Items[i]
+		markReferenced(shared, urls, &other.Spec.ArtifactSpec, other.Status.History)
+	}
+	return shared, nil
+}
+
+func markReferenced(shared map[string]bool, urls []string, spec *artifactv1.ArtifactSpec, history []artifactv1.ArtifactRevision) {
+	for _, url := range urls {
+		if referencesURL(spec, history, url) {
+			shared[url] = true
+		}
+	}
+}
+
+func referencesURL(spec *artifactv1.ArtifactSpec, history []artifactv1.ArtifactRevision, url string) bool {
+	if spec.URL == url {
+		return true
+	}
+	for _, h := range history {
+		if h.URL == url {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reconciler) checksOwnerKind(ref metav1.OwnerReference) bool {
+	kinds := r.OwnerKinds
+	if kinds == nil {
+		kinds = DefaultOwnerKinds
+	}
+	gk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind).GroupKind()
+	for _, k := range kinds {
+		if k == gk {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reconciler) ownerReader() ctrlclient.Reader {
+	if r.OwnerReader != nil {
+		return r.OwnerReader
+	}
+	return r.Client
+}
+
+func (r *Reconciler) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+func (r *Reconciler) interval() time.Duration {
+	if r.Interval > 0 {
+		return r.Interval
+	}
+	return 10 * time.Minute
+}
+
+func lastUpdate(art *artifactv1.Artifact) time.Time {
+	if art.Spec.LastUpdateTime.IsZero() {
+		return art.CreationTimestamp.Time
+	}
+	return art.Spec.LastUpdateTime.Time
+}
@@ -0,0 +1,251 @@
+package gc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var now = time.Date(2024, 5, 4, 12, 0, 0, 0, time.UTC)
+
+func newTestClient(objs ...ctrlclient.Object) ctrlclient.Client {
+	scheme := runtime.NewScheme()
+	_ = artifactv1.AddToScheme(scheme)
+	_ = sourcev1.AddToScheme(scheme)
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func newGitRepository(name string, uid types.UID) *sourcev1.GitRepository {
+	repo := &sourcev1.GitRepository{}
+	repo.Namespace, repo.Name, repo.UID = "default", name, uid
+	return repo
+}
+
+func newArtifact(name string, owner *metav1.OwnerReference) *artifactv1.Artifact {
+	art := &artifactv1.Artifact{}
+	art.Namespace, art.Name, art.UID = "default", name, types.UID(name)
+	art.Spec.URL = "http://storage/default/" + name + ".tar.gz"
+	art.Spec.Revision = "v1"
+	art.Spec.LastUpdateTime = metav1.NewTime(now.Add(-time.Hour))
+	if owner != nil {
+		art.OwnerReferences = []metav1.OwnerReference{*owner}
+	}
+	return art
+}
+
+func ownedBy(kind, apiVersion, name string, uid types.UID) *metav1.OwnerReference {
+	return &metav1.OwnerReference{APIVersion: apiVersion, Kind: kind, Name: name, UID: uid}
+}
+
+func gitOwner(name string, uid types.UID) *metav1.OwnerReference {
+	return ownedBy(sourcev1.GitRepositoryKind, sourcev1.GroupVersion.String(), name, uid)
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		art     *artifactv1.Artifact
+		reason  Reason
+		requeue time.Duration
+	}{
+		{name: "owner valid", art: newArtifact("a", gitOwner("repo", "uid")), requeue: 10 * time.Minute},
+		{name: "owner gone", art: newArtifact("a", gitOwner("other", "uid")), reason: ReasonOwnerGone},
+		{name: "owner stale", art: newArtifact("a", gitOwner("repo", "old")), reason: ReasonOwnerStale},
+		{name: "owner without uid", art: newArtifact("a", gitOwner("repo", "")), requeue: 10 * time.Minute},
+		{name: "unchecked owner kind", art: newArtifact("a", ownedBy("Component", "example.com/v1", "gone", "uid")), requeue: 10 * time.Minute},
+		{name: "no owner without ttl", art: newArtifact("a", nil)},
+		{name: "no owner within ttl", ttl: 3 * time.Hour, art: newArtifact("a", nil), requeue: 2 * time.Hour},
+		{name: "no owner expired", ttl: 30 * time.Minute, art: newArtifact("a", nil), reason: ReasonExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			client := newTestClient(newGitRepository("repo", "uid"), tt.art)
+			r := &Reconciler{Client: client, OwnerReader: client, TTL: tt.ttl, Now: func() time.Time { return now }}
+
+			finding, requeue, err := r.Check(context.Background(), tt.art)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(requeue).To(Equal(tt.requeue))
+			if tt.reason == "" {
+				g.Expect(finding).To(BeNil())
+				return
+			}
+			g.Expect(finding).ToNot(BeNil())
+			g.Expect(finding.Reason).To(Equal(tt.reason))
+			g.Expect(finding.Artifact).To(Equal(ctrlclient.ObjectKeyFromObject(tt.art)))
+		})
+	}
+}
+
+func TestCheckOwnerKinds(t *testing.T) {
+	g := NewWithT(t)
+	art := newArtifact("a", ownedBy("Component", "example.com/v1", "gone", "uid"))
+	client := newTestClient(art)
+	r := &Reconciler{Client: client, OwnerReader: client,
+		OwnerKinds: []schema.GroupKind{{Group: sourcev1.GroupVersion.Group, Kind: sourcev1.GitRepositoryKind}}}
+
+	finding, _, err := r.Check(context.Background(), art)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(finding).To(BeNil())
+}
+
+func TestReport(t *testing.T) {
+	g := NewWithT(t)
+	orphan := newArtifact("orphan", gitOwner("gone", "uid"))
+	stale := newArtifact("stale", gitOwner("repo", "old"))
+	valid := newArtifact("valid", gitOwner("repo", "uid"))
+	other := newArtifact("other", gitOwner("gone", "uid"))
+	other.Namespace = "other"
+	client := newTestClient(newGitRepository("repo", "uid"), orphan, stale, valid, other)
+	r := &Reconciler{Client: client, OwnerReader: client}
+
+	findings, err := r.Report(context.Background(), "default")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(findings).To(ConsistOf(
+		HaveField("Artifact", ctrlclient.ObjectKeyFromObject(orphan)),
+		HaveField("Artifact", ctrlclient.ObjectKeyFromObject(stale)),
+	))
+
+	findings, err = r.Report(context.Background(), "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(findings).To(HaveLen(3))
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/?namespace=other", nil))
+	g.Expect(rec.Code).To(Equal(http.StatusOK))
+	g.Expect(json.Unmarshal(rec.Body.Bytes(), &findings)).To(Succeed())
+	g.Expect(findings).To(ConsistOf(HaveField("Artifact", ctrlclient.ObjectKeyFromObject(other))))
+}
+
+func TestReconcile(t *testing.T) {
+	setup := func(t *testing.T, objs ...ctrlclient.Object) (*Reconciler, string) {
+		root := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(root, "default"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"orphan", "orphan-v0", "orphan-v1", "valid"} {
+			if err := os.WriteFile(filepath.Join(root, "default", name+".tar.gz"), []byte(name), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		client := newTestClient(append(objs, newGitRepository("repo", "uid"))...)
+		return &Reconciler{Client: client, OwnerReader: client, Storage: &DirectoryStorage{Root: root}}, root
+	}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "orphan"}}
+
+	t.Run("collects orphaned artifact", func(t *testing.T) {
+		g := NewWithT(t)
+		r, root := setup(t, newArtifact("orphan", gitOwner("gone", "uid")))
+
+		_, err := r.Reconcile(context.Background(), request)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(apierrors.IsNotFound(r.Client.Get(context.Background(), request.NamespacedName, &artifactv1.Artifact{}))).To(BeTrue())
+		g.Expect(filepath.Join(root, "default", "orphan.tar.gz")).ToNot(BeAnExistingFile())
+		g.Expect(filepath.Join(root, "default", "valid.tar.gz")).To(BeAnExistingFile())
+	})
+
+	t.Run("keeps valid artifact", func(t *testing.T) {
+		g := NewWithT(t)
+		r, root := setup(t, newArtifact("valid", gitOwner("repo", "uid")))
+
+		result, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "valid"}})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(10 * time.Minute))
+		g.Expect(filepath.Join(root, "default", "valid.tar.gz")).To(BeAnExistingFile())
+	})
+
+	t.Run("dry-run", func(t *testing.T) {
+		g := NewWithT(t)
+		r, root := setup(t, newArtifact("orphan", gitOwner("gone", "uid")))
+		r.DryRun = true
+
+		result, err := r.Reconcile(context.Background(), request)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(10 * time.Minute))
+		g.Expect(r.Client.Get(context.Background(), request.NamespacedName, &artifactv1.Artifact{})).To(Succeed())
+		g.Expect(filepath.Join(root, "default", "orphan.tar.gz")).To(BeAnExistingFile())
+	})
+
+	t.Run("keeps content of promoted copy", func(t *testing.T) {
+		g := NewWithT(t)
+		orphan := newArtifact("orphan", gitOwner("gone", "uid"))
+		promoted := newArtifact("promoted", nil)
+		promoted.Namespace = "production"
+		promoted.Spec.URL = orphan.Spec.URL
+		r, root := setup(t, orphan, promoted)
+
+		_, err := r.Reconcile(context.Background(), request)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(apierrors.IsNotFound(r.Client.Get(context.Background(), request.NamespacedName, &artifactv1.Artifact{}))).To(BeTrue())
+		g.Expect(filepath.Join(root, "default", "orphan.tar.gz")).To(BeAnExistingFile())
+	})
+
+	t.Run("keeps content in history of cluster artifact", func(t *testing.T) {
+		g := NewWithT(t)
+		orphan := newArtifact("orphan", gitOwner("gone", "uid"))
+		shared := &artifactv1.ClusterArtifact{}
+		shared.Name = "shared"
+		shared.Spec.URL = "http://storage/shared.tar.gz"
+		shared.Status.History = []artifactv1.ArtifactRevision{{URL: orphan.Spec.URL, Revision: "v1"}}
+		r, root := setup(t, orphan, shared)
+
+		_, err := r.Reconcile(context.Background(), request)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(filepath.Join(root, "default", "orphan.tar.gz")).To(BeAnExistingFile())
+	})
+
+	t.Run("collects unshared history", func(t *testing.T) {
+		g := NewWithT(t)
+		orphan := newArtifact("orphan", gitOwner("gone", "uid"))
+		orphan.Status.History = []artifactv1.ArtifactRevision{
+			{URL: orphan.Spec.URL, Revision: "v2"},
+			{URL: "http://storage/default/orphan-v1.tar.gz", Revision: "v1"},
+			{URL: "http://storage/default/orphan-v0.tar.gz", Revision: "v0"},
+		}
+		promoted := newArtifact("promoted", nil)
+		promoted.Namespace = "production"
+		promoted.Status.History = []artifactv1.ArtifactRevision{{URL: "http://storage/default/orphan-v1.tar.gz", Revision: "v1"}}
+		r, root := setup(t, orphan, promoted)
+
+		_, err := r.Reconcile(context.Background(), request)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(filepath.Join(root, "default", "orphan.tar.gz")).ToNot(BeAnExistingFile())
+		g.Expect(filepath.Join(root, "default", "orphan-v0.tar.gz")).ToNot(BeAnExistingFile())
+		g.Expect(filepath.Join(root, "default", "orphan-v1.tar.gz")).To(BeAnExistingFile())
+		g.Expect(filepath.Join(root, "default", "valid.tar.gz")).To(BeAnExistingFile())
+	})
+}
+
+func TestDirectoryStorageDelete(t *testing.T) {
+	g := NewWithT(t)
+	root := t.TempDir()
+	path := filepath.Join(root, "default", "app.tar.gz")
+	g.Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+	g.Expect(os.WriteFile(path, []byte("content"), 0o644)).To(Succeed())
+	s := &DirectoryStorage{Root: root}
+
+	g.Expect(s.Delete(context.Background(), "http://storage/default/app.tar.gz")).To(Succeed())
+	g.Expect(path).ToNot(BeAnExistingFile())
+	g.Expect(s.Delete(context.Background(), "http://storage/default/app.tar.gz")).To(Succeed())
+
+	g.Expect(s.Delete(context.Background(), "http://storage/../../etc/passwd")).To(Succeed())
+	g.Expect(s.Delete(context.Background(), "http://storage/")).To(MatchError(ContainSubstring("outside of the storage")))
+}
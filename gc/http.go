@@ -0,0 +1,27 @@
+package gc
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// DefaultPath is the path the Report of the garbage collection is usually
+// served at by the metrics server.
+const DefaultPath = "/debug/artifacts/gc"
+
+// Handler serves the Report of the reconciler as json. The query parameter
+// namespace restricts the report to a single namespace.
+func (r *Reconciler) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		findings, err := r.Report(req.Context(), req.URL.Query().Get("namespace"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if findings == nil {
+			findings = []Finding{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(findings)
+	})
+}
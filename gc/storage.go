@@ -0,0 +1,45 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Storage removes the content of Artifacts from a storage backend.
+type Storage interface {
+	// Delete removes the content served at the given Artifact URL. Missing
+	// content is not an error.
+	Delete(ctx context.Context, url string) error
+}
+
+// DirectoryStorage is a Storage serving Artifacts from a local directory,
+// as done by the Flux source-controller. The path of the Artifact URL is
+// interpreted relative to the root directory.
+type DirectoryStorage struct {
+	Root string
+}
+
+var _ Storage = (*DirectoryStorage)(nil)
+
+func (s *DirectoryStorage) Delete(_ context.Context, artifactURL string) error {
+	u, err := url.Parse(artifactURL)
+	if err != nil {
+		return fmt.Errorf("invalid artifact url %q: %w", artifactURL, err)
+	}
+	root, err := filepath.Abs(s.Root)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(root, filepath.FromSlash(filepath.Clean("/"+u.Path)))
+	if !strings.HasPrefix(path, root+string(filepath.Separator)) {
+		return fmt.Errorf("artifact url %q points outside of the storage", artifactURL)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to delete artifact content %q: %w", path, err)
+	}
+	return nil
+}
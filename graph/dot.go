@@ -0,0 +1,41 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+var nodeShapes = map[NodeType]string{
+	NodeSource:   "box",
+	NodeArtifact: "note",
+	NodeAction:   "ellipse",
+	NodeMissing:  "octagon",
+}
+
+// DOT renders the graph in the Graphviz DOT format.
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph artifacts {\n")
+	b.WriteString("  rankdir=LR;\n")
+	for _, n := range g.Nodes {
+		label := fmt.Sprintf("%s\\n%s/%s", n.Kind, n.Namespace, n.Name)
+		if n.Revision != "" {
+			label += "\\n" + n.Revision
+		}
+		style := ""
+		if n.Type == NodeMissing {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  %q [label=%s, shape=%s%s];\n", n.ID, quote(label), nodeShapes[n.Type], style)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", e.From, e.To, e.Type)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// quote quotes a label keeping DOT escape sequences like \n.
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,318 @@
+// Package graph builds the dependency graph of sources, Artifacts and
+// action resources from the cluster cache. Nodes are identified by the
+// keys used for the source reference indices (see utils.KeyForReference).
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/openfluxcd/artifact/action"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/matchers"
+	"github.com/openfluxcd/artifact/utils"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+type NodeType string
+
+const (
+	NodeSource   NodeType = "source"
+	NodeArtifact NodeType = "artifact"
+	NodeAction   NodeType = "action"
+	// NodeMissing is used for referenced objects, which do not exist.
+	NodeMissing NodeType = "missing"
+)
+
+type EdgeType string
+
+const (
+	// EdgeOwns connects a source with the Artifact it owns.
+	EdgeOwns EdgeType = "owns"
+	// EdgeReferences connects a source with an action referencing it.
+	EdgeReferences EdgeType = "references"
+	// EdgeProvides connects an Artifact with an action consuming it via
+	// the owner of the Artifact, or with a consumer recorded in its status.
+	EdgeProvides EdgeType = "provides"
+)
+
+type Node struct {
+	ID        string   `json:"id"`
+	Type      NodeType `json:"type"`
+	Group     string   `json:"group,omitempty"`
+	Kind      string   `json:"kind"`
+	Namespace string   `json:"namespace,omitempty"`
+	Name      string   `json:"name"`
+	Revision  string   `json:"revision,omitempty"`
+}
+
+type Edge struct {
+	From string   `json:"from"`
+	To   string   `json:"to"`
+	Type EdgeType `json:"type"`
+}
+
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+
+	index map[string]int
+	edges map[Edge]bool
+}
+
+func (g *Graph) Node(id string) *Node {
+	if i, ok := g.index[id]; ok {
+		return &g.Nodes[i]
+	}
+	return nil
+}
+
+func (g *Graph) addNode(n Node) *Node {
+	if g.index == nil {
+		g.index = map[string]int{}
+	}
+	if i, ok := g.index[n.ID]; ok {
+		if g.Nodes[i].Type == NodeMissing {
+			g.Nodes[i] = n
+		}
+		return &g.Nodes[i]
+	}
+	g.index[n.ID] = len(g.Nodes)
+	g.Nodes = append(g.Nodes, n)
+	return &g.Nodes[len(g.Nodes)-1]
+}
+
+func (g *Graph) addEdge(from, to string, typ EdgeType) {
+	e := Edge{From: from, To: to, Type: typ}
+	if g.edges[e] {
+		return
+	}
+	if g.edges == nil {
+		g.edges = map[Edge]bool{}
+	}
+	g.edges[e] = true
+	g.Edges = append(g.Edges, e)
+}
+
+func (g *Graph) sort() {
+	sort.SliceStable(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	g.index = map[string]int{}
+	for i, n := range g.Nodes {
+		g.index[n.ID] = i
+	}
+	sort.SliceStable(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+}
+
+// ActionLister lists the action resources of one type.
+type ActionLister func(ctx context.Context, reader ctrlclient.Reader) ([]action.ActionResource, error)
+
+// ActionsOf returns an ActionLister for the action type T.
+func ActionsOf[T any, P action.ActionResourcePointerType[T]](scheme *runtime.Scheme) ActionLister {
+	return func(ctx context.Context, reader ctrlclient.Reader) ([]action.ActionResource, error) {
+		list := utils.CreateListForType[T, P](scheme)
+		if list == nil {
+			return nil, fmt.Errorf("no list type registered for %T", new(T))
+		}
+		if err := reader.List(ctx, list); err != nil {
+			return nil, err
+		}
+		objs, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]action.ActionResource, 0, len(objs))
+		for _, o := range objs {
+			result = append(result, o.(action.ActionResource))
+		}
+		return result, nil
+	}
+}
+
+// Builder builds the graph from the objects found by the client.
+//
+// Action resources are listed with the Actions. Actions of other types
+// are taken from the consumers recorded in the status of the Artifacts
+// (see action.ConsumerReconciler), so the graph shows them even if the
+// types of the action resources are unknown to the Builder.
+type Builder struct {
+	Client  ctrlclient.Reader
+	Scheme  *runtime.Scheme
+	Mapper  meta.RESTMapper
+	Actions []ActionLister
+	// Options are used to resolve the source references of actions.
+	Options []action.Option
+}
+
+// Build builds the graph for the given namespace, or for all namespaces if empty.
+// Source kinds not registered in the scheme or not served by the cluster are skipped.
+func (b *Builder) Build(ctx context.Context, namespace string) (*Graph, error) {
+	g := &Graph{}
+
+	factory := matchers.BuiltinFluxSourceVersions.ForMapper(b.Mapper)
+	artifactGK := schema.GroupKind{Group: artifactv1.GroupVersion.Group, Kind: artifactv1.ArtifactKind}
+	for _, gk := range factory.Kinds.GroupKinds() {
+		if gk == artifactGK {
+			continue
+		}
+		ns := namespace
+		if utils.ClusterScopedSourceKinds[gk] {
+			// cluster-scoped sources may be consumed in any namespace
+			ns = ""
+		}
+		objs, err := b.listSources(ctx, factory.Create(gk), ns)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range objs {
+			n := nodeFor(NodeSource, gk, o)
+			if src, ok := o.(action.ArtifactSource); ok && src.GetArtifact() != nil {
+				n.Revision = src.GetArtifact().Revision
+			}
+			g.addNode(n)
+		}
+	}
+
+	var arts artifactv1.ArtifactList
+	if err := b.Client.List(ctx, &arts, ctrlclient.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("unable to list artifacts: %w", err)
+	}
+	owned := map[string][]string{}
+	for i := range arts.Items {
+		art := &arts.Items[i]
+		n := nodeFor(NodeArtifact, artifactGK, art)
+		n.Revision = art.Spec.Revision
+		g.addNode(n)
+		for _, ref := range art.OwnerReferences {
+			owner := g.addNode(Node{
+				ID:        fmt.Sprintf("%s/%s/%s/%s", utils.ExtractGroupName(ref.APIVersion), ref.Kind, art.Namespace, ref.Name),
+				Type:      NodeSource,
+				Group:     utils.ExtractGroupName(ref.APIVersion),
+				Kind:      ref.Kind,
+				Namespace: art.Namespace,
+				Name:      ref.Name,
+			})
+			g.addEdge(owner.ID, n.ID, EdgeOwns)
+			owned[owner.ID] = append(owned[owner.ID], n.ID)
+		}
+	}
+
+	opts := action.EvalOptions(b.Options...)
+	listed := map[string]bool{}
+	for _, lister := range b.Actions {
+		actions, err := lister(ctx, b.Client)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list actions: %w", err)
+		}
+		for _, a := range actions {
+			if namespace != "" && a.GetNamespace() != namespace {
+				continue
+			}
+			gk := utils.GetGroupKindForObject(b.Scheme, a)
+			if gk == nil {
+				continue
+			}
+			n := g.addNode(nodeFor(NodeAction, *gk, a))
+			id := n.ID
+			n.Revision = action.LastAppliedRevision(a)
+			listed[id] = true
+
+			ref, err := action.ResolvedSourceRef(a, opts.KindResolver)
+			if err != nil || ref == nil {
+				continue
+			}
+			key := utils.KeyForReference(a, ref)
+			if key == "" {
+				continue
+			}
+			if g.Node(key) == nil {
+				g.addNode(Node{
+					ID:        key,
+					Type:      NodeMissing,
+					Group:     ref.GetGroupKind().Group,
+					Kind:      ref.GetGroupKind().Kind,
+					Namespace: ref.GetNamespace(),
+					Name:      ref.GetName(),
+				})
+			}
+			g.addEdge(key, id, EdgeReferences)
+			for _, art := range owned[key] {
+				g.addEdge(art, id, EdgeProvides)
+			}
+		}
+	}
+
+	// consumers of types not listed by the Actions
+	for i := range arts.Items {
+		art := &arts.Items[i]
+		for _, c := range art.Status.Consumers {
+			if namespace != "" && c.Namespace != namespace {
+				continue
+			}
+			id := fmt.Sprintf("%s/%s/%s/%s", c.Group, c.Kind, c.Namespace, c.Name)
+			if listed[id] {
+				continue
+			}
+			g.addNode(Node{
+				ID:        id,
+				Type:      NodeAction,
+				Group:     c.Group,
+				Kind:      c.Kind,
+				Namespace: c.Namespace,
+				Name:      c.Name,
+				Revision:  c.LastAppliedRevision,
+			})
+			g.addEdge(nodeFor(NodeArtifact, artifactGK, art).ID, id, EdgeProvides)
+		}
+	}
+	g.sort()
+	return g, nil
+}
+
+func (b *Builder) listSources(ctx context.Context, obj ctrlclient.Object, namespace string) ([]ctrlclient.Object, error) {
+	gvk, err := apiutil.GVKForObject(obj, b.Scheme)
+	if err != nil {
+		// not registered in the scheme
+		return nil, nil
+	}
+	o, err := b.Scheme.New(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err != nil {
+		return nil, nil
+	}
+	list := o.(ctrlclient.ObjectList)
+	if err := b.Client.List(ctx, list, ctrlclient.InNamespace(namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to list %s: %w", gvk.Kind, err)
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]ctrlclient.Object, 0, len(items))
+	for _, i := range items {
+		result = append(result, i.(ctrlclient.Object))
+	}
+	return result, nil
+}
+
+func nodeFor(typ NodeType, gk schema.GroupKind, o ctrlclient.Object) Node {
+	return Node{
+		ID:        fmt.Sprintf("%s/%s/%s/%s", gk.Group, gk.Kind, o.GetNamespace(), o.GetName()),
+		Type:      typ,
+		Group:     gk.Group,
+		Kind:      gk.Kind,
+		Namespace: o.GetNamespace(),
+		Name:      o.GetName(),
+	}
+}
@@ -0,0 +1,59 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestArtifact(namespace, name string, consumers ...artifactv1.ArtifactConsumer) *artifactv1.Artifact {
+	art := &artifactv1.Artifact{}
+	art.Namespace, art.Name = namespace, name
+	art.Spec.Revision = "v2"
+	art.OwnerReferences = []metav1.OwnerReference{{APIVersion: "source.toolkit.fluxcd.io/v1", Kind: "GitRepository", Name: name}}
+	art.Status.Consumers = consumers
+	return art
+}
+
+func TestBuildWithConsumers(t *testing.T) {
+	g := NewWithT(t)
+	scheme := runtime.NewScheme()
+	_ = artifactv1.AddToScheme(scheme)
+	app := artifactv1.ArtifactConsumer{Group: "kustomize.toolkit.fluxcd.io", Kind: "Kustomization", Namespace: "default", Name: "app", LastAppliedRevision: "v1"}
+	other := artifactv1.ArtifactConsumer{Group: "kustomize.toolkit.fluxcd.io", Kind: "Kustomization", Namespace: "other", Name: "app"}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newTestArtifact("default", "app", app, other),
+		newTestArtifact("default", "config", app),
+	).Build()
+	b := &Builder{Client: client, Scheme: scheme, Mapper: client.RESTMapper()}
+
+	graph, err := b.Build(context.Background(), "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(graph.Node("kustomize.toolkit.fluxcd.io/Kustomization/default/app")).To(Equal(&Node{
+		ID:        "kustomize.toolkit.fluxcd.io/Kustomization/default/app",
+		Type:      NodeAction,
+		Group:     "kustomize.toolkit.fluxcd.io",
+		Kind:      "Kustomization",
+		Namespace: "default",
+		Name:      "app",
+		Revision:  "v1",
+	}))
+	g.Expect(graph.Edges).To(ConsistOf(
+		Edge{From: "source.toolkit.fluxcd.io/GitRepository/default/app", To: "openfluxcd.ocm.software/Artifact/default/app", Type: EdgeOwns},
+		Edge{From: "source.toolkit.fluxcd.io/GitRepository/default/config", To: "openfluxcd.ocm.software/Artifact/default/config", Type: EdgeOwns},
+		Edge{From: "openfluxcd.ocm.software/Artifact/default/app", To: "kustomize.toolkit.fluxcd.io/Kustomization/default/app", Type: EdgeProvides},
+		Edge{From: "openfluxcd.ocm.software/Artifact/default/config", To: "kustomize.toolkit.fluxcd.io/Kustomization/default/app", Type: EdgeProvides},
+		Edge{From: "openfluxcd.ocm.software/Artifact/default/app", To: "kustomize.toolkit.fluxcd.io/Kustomization/other/app", Type: EdgeProvides},
+	))
+
+	// consumers in other namespaces are not part of a namespace graph
+	graph, err = b.Build(context.Background(), "default")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(graph.Node("kustomize.toolkit.fluxcd.io/Kustomization/other/app")).To(BeNil())
+	g.Expect(graph.Edges).To(HaveLen(4))
+}
@@ -0,0 +1,49 @@
+package graph
+
+import (
+	"encoding/json"
+	"net/http"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// DefaultPath is the path of the debug endpoint registered by AddToManager.
+const DefaultPath = "/debug/artifacts/graph"
+
+// Handler serves the graph built by the builder. The query parameter
+// format selects json (default) or dot, the parameter namespace restricts
+// the graph to a single namespace.
+func Handler(b *Builder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		g, err := b.Build(req.Context(), req.URL.Query().Get("namespace"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		switch req.URL.Query().Get("format") {
+		case "dot":
+			w.Header().Set("Content-Type", "text/vnd.graphviz")
+			_, _ = w.Write([]byte(g.DOT()))
+		case "", "json":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(g)
+		default:
+			http.Error(w, "unsupported format, use json or dot", http.StatusBadRequest)
+		}
+	})
+}
+
+// AddToManager registers the graph handler at DefaultPath on the metrics
+// server of the manager. Missing fields of the builder are taken from the manager.
+func AddToManager(mgr ctrl.Manager, b *Builder) error {
+	if b.Client == nil {
+		b.Client = mgr.GetClient()
+	}
+	if b.Scheme == nil {
+		b.Scheme = mgr.GetScheme()
+	}
+	if b.Mapper == nil {
+		b.Mapper = mgr.GetRESTMapper()
+	}
+	return mgr.AddMetricsServerExtraHandler(DefaultPath, Handler(b))
+}
@@ -0,0 +1,26 @@
+// Package integrity contains the errors reported if the content of an
+// Artifact cannot be verified against its digest, independent of the way
+// the content is retrieved.
+package integrity
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DigestMismatchError is returned if the content of an Artifact does not
+// match its digest.
+type DigestMismatchError struct {
+	URL      string
+	Expected string
+	Actual   string
+}
+
+func (e *DigestMismatchError) Error() string {
+	return fmt.Sprintf("digest mismatch for artifact %s: expected %s, got %s", e.URL, e.Expected, e.Actual)
+}
+
+func IsDigestMismatch(err error) bool {
+	var e *DigestMismatchError
+	return errors.As(err, &e)
+}
@@ -10,6 +10,8 @@ import (
 
 type MapMatcher map[schema.GroupKind]client.Object
 
+var _ SourceFactory = MapMatcher(nil)
+
 func (m MapMatcher) Match(gk schema.GroupKind) bool {
 	_, ok := m[gk]
 	return ok
@@ -25,22 +27,32 @@ func (m MapMatcher) Create(gk schema.GroupKind) client.Object {
 
 var (
 	BuiltinGeneralSourceKinds = MapMatcher{
-		{sourcev1.GroupVersion.Group, sourcev1.GitRepositoryKind}:     &sourcev1.GitRepository{},
-		{sourcev1b2.GroupVersion.Group, sourcev1b2.BucketKind}:        &sourcev1b2.Bucket{},
-		{sourcev1b2.GroupVersion.Group, sourcev1b2.OCIRepositoryKind}: &sourcev1b2.OCIRepository{},
+		{Group: sourcev1.GroupVersion.Group, Kind: sourcev1.GitRepositoryKind}:     &sourcev1.GitRepository{},
+		{Group: sourcev1b2.GroupVersion.Group, Kind: sourcev1b2.BucketKind}:        &sourcev1b2.Bucket{},
+		{Group: sourcev1b2.GroupVersion.Group, Kind: sourcev1b2.OCIRepositoryKind}: &sourcev1b2.OCIRepository{},
 	}
 
-	BuiltinFluxSourceKinds = MapMatcher{
-		{sourcev1.GroupVersion.Group, sourcev1.GitRepositoryKind}:     &sourcev1.GitRepository{},
-		{sourcev1b2.GroupVersion.Group, sourcev1b2.BucketKind}:        &sourcev1b2.Bucket{},
-		{sourcev1b2.GroupVersion.Group, sourcev1b2.OCIRepositoryKind}: &sourcev1b2.OCIRepository{},
-		{sourcev1.GroupVersion.Group, sourcev1.HelmRepositoryKind}:    &sourcev1.HelmRepository{},
-		{sourcev1.GroupVersion.Group, sourcev1.HelmChartKind}:         &sourcev1.HelmChart{},
-		{artifactv1.GroupVersion.Group, artifactv1.ArtifactKind}:      &artifactv1.Artifact{},
+	// BuiltinFluxSourceVersions contains all known versions of the builtin
+	// source kinds. Use ForMapper to create objects for the version served
+	// by a cluster.
+	BuiltinFluxSourceVersions = VersionedMapMatcher{
+		sourcev1.GroupVersion.WithKind(sourcev1.GitRepositoryKind):       &sourcev1.GitRepository{},
+		sourcev1b2.GroupVersion.WithKind(sourcev1b2.GitRepositoryKind):   &sourcev1b2.GitRepository{},
+		sourcev1b2.GroupVersion.WithKind(sourcev1b2.BucketKind):          &sourcev1b2.Bucket{},
+		sourcev1b2.GroupVersion.WithKind(sourcev1b2.OCIRepositoryKind):   &sourcev1b2.OCIRepository{},
+		sourcev1.GroupVersion.WithKind(sourcev1.HelmRepositoryKind):      &sourcev1.HelmRepository{},
+		sourcev1b2.GroupVersion.WithKind(sourcev1b2.HelmRepositoryKind):  &sourcev1b2.HelmRepository{},
+		sourcev1.GroupVersion.WithKind(sourcev1.HelmChartKind):           &sourcev1.HelmChart{},
+		sourcev1b2.GroupVersion.WithKind(sourcev1b2.HelmChartKind):       &sourcev1b2.HelmChart{},
+		artifactv1.GroupVersion.WithKind(artifactv1.ArtifactKind):        &artifactv1.Artifact{},
+		artifactv1.GroupVersion.WithKind(artifactv1.ClusterArtifactKind): &artifactv1.ClusterArtifact{},
 	}
 
+	// BuiltinFluxSourceKinds contains the preferred version of every builtin source kind.
+	BuiltinFluxSourceKinds = BuiltinFluxSourceVersions.Preferred()
+
 	BuiltinHelmSourceKinds = MapMatcher{
-		{sourcev1.GroupVersion.Group, sourcev1.HelmRepositoryKind}: &sourcev1.HelmRepository{},
+		{Group: sourcev1.GroupVersion.Group, Kind: sourcev1.HelmRepositoryKind}: &sourcev1.HelmRepository{},
 	}
 
 	DynamicSourceKinds = Not(BuiltinFluxSourceKinds)
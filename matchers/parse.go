@@ -0,0 +1,203 @@
+package matchers
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// PatternMatcher is a SourceMatcher described by a comma separated list of
+// group kind patterns of the form <kind>[.<group>], e.g.
+//
+//	GitRepository.source.toolkit.fluxcd.io,*.ocm.software,!Bucket.*
+//
+// A pattern is matched against the string <kind>.<group> (or just <kind> for
+// the core group). The wildcard '*' matches any sequence of characters,
+// including dots, and '?' matches a single character. Patterns prefixed with
+// '!' exclude matching group kinds.
+//
+// A group kind matches if it matches at least one positive pattern, or if
+// there are no positive patterns at all, and it does not match any negated
+// pattern.
+//
+// PatternMatcher implements flag.Value and encoding.TextUnmarshaler, so it can
+// be used directly for command line flags and declarative configuration.
+type PatternMatcher struct {
+	patterns []pattern
+}
+
+var _ SourceMatcher = (*PatternMatcher)(nil)
+
+type pattern struct {
+	negate bool
+	glob   string
+}
+
+func (p pattern) String() string {
+	if p.negate {
+		return "!" + p.glob
+	}
+	return p.glob
+}
+
+// ParseError describes a syntax error in a pattern expression.
+type ParseError struct {
+	// Expr is the complete expression.
+	Expr string
+	// Pos is the byte offset of the faulty pattern in Expr.
+	Pos int
+	// Msg describes the problem.
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid source kind expression %q at position %d: %s", e.Expr, e.Pos, e.Msg)
+}
+
+// Parse parses a comma separated list of group kind patterns.
+// An empty expression matches all group kinds.
+func Parse(expr string) (*PatternMatcher, error) {
+	m := &PatternMatcher{}
+	if strings.TrimSpace(expr) == "" {
+		return m, nil
+	}
+
+	pos := 0
+	for _, elem := range strings.Split(expr, ",") {
+		start := pos + len(elem) - len(strings.TrimLeft(elem, " \t"))
+		pos += len(elem) + 1
+
+		p, err := parsePattern(strings.TrimSpace(elem))
+		if err != nil {
+			return nil, &ParseError{Expr: expr, Pos: start, Msg: err.Error()}
+		}
+		m.patterns = append(m.patterns, p)
+	}
+	return m, nil
+}
+
+// MustParse is like Parse but panics if the expression cannot be parsed.
+func MustParse(expr string) *PatternMatcher {
+	m, err := Parse(expr)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func parsePattern(s string) (pattern, error) {
+	var p pattern
+	if strings.HasPrefix(s, "!") {
+		p.negate = true
+		s = s[1:]
+	}
+	if s == "" {
+		return p, fmt.Errorf("empty pattern")
+	}
+	if strings.HasPrefix(s, ".") || strings.HasSuffix(s, ".") || strings.Contains(s, "..") {
+		return p, fmt.Errorf("pattern %q contains an empty kind or group segment", s)
+	}
+	for i, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '-', c == '*', c == '?':
+		default:
+			return p, fmt.Errorf("pattern %q contains invalid character %q at offset %d", s, c, i)
+		}
+	}
+	p.glob = s
+	return p, nil
+}
+
+func (m *PatternMatcher) Match(gk schema.GroupKind) bool {
+	name := gk.Kind
+	if gk.Group != "" {
+		name += "." + gk.Group
+	}
+
+	positive, matched := false, false
+	for _, p := range m.patterns {
+		if p.negate {
+			if glob(p.glob, name) {
+				return false
+			}
+			continue
+		}
+		positive = true
+		if !matched {
+			matched = glob(p.glob, name)
+		}
+	}
+	return matched || !positive
+}
+
+// String returns the canonical expression, which parses to an equivalent matcher.
+func (m *PatternMatcher) String() string {
+	if m == nil {
+		return ""
+	}
+	list := make([]string, 0, len(m.patterns))
+	for _, p := range m.patterns {
+		list = append(list, p.String())
+	}
+	return strings.Join(list, ",")
+}
+
+// Set implements flag.Value.
+func (m *PatternMatcher) Set(expr string) error {
+	n, err := Parse(expr)
+	if err != nil {
+		return err
+	}
+	*m = *n
+	return nil
+}
+
+// Type implements the pflag.Value interface.
+func (m *PatternMatcher) Type() string {
+	return "sourceKinds"
+}
+
+func (m *PatternMatcher) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *PatternMatcher) UnmarshalText(text []byte) error {
+	return m.Set(string(text))
+}
+
+// glob matches name against a pattern where '*' matches any sequence
+// of characters and '?' matches exactly one character.
+func glob(pattern, name string) bool {
+	px, nx := 0, 0
+	nextPx, nextNx := -1, -1
+	for px < len(pattern) || nx < len(name) {
+		if px < len(pattern) {
+			switch c := pattern[px]; c {
+			case '*':
+				nextPx, nextNx = px, nx+1
+				px++
+				continue
+			case '?':
+				if nx < len(name) {
+					px++
+					nx++
+					continue
+				}
+			default:
+				if nx < len(name) && name[nx] == c {
+					px++
+					nx++
+					continue
+				}
+			}
+		}
+		if nextNx > 0 && nextNx <= len(name) {
+			px, nx = nextPx, nextNx
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,174 @@
+package matchers
+
+import (
+	"errors"
+	"flag"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	gitRepository = schema.GroupKind{Group: "source.toolkit.fluxcd.io", Kind: "GitRepository"}
+	bucket        = schema.GroupKind{Group: "source.toolkit.fluxcd.io", Kind: "Bucket"}
+	artifact      = schema.GroupKind{Group: "openfluxcd.ocm.software", Kind: "Artifact"}
+	configMap     = schema.GroupKind{Kind: "ConfigMap"}
+)
+
+func TestParse(t *testing.T) {
+	all := []schema.GroupKind{gitRepository, bucket, artifact, configMap}
+
+	tests := []struct {
+		expr      string
+		canonical string
+		matches   []schema.GroupKind
+	}{
+		{expr: "", canonical: "", matches: all},
+		{expr: "  ", canonical: "", matches: all},
+		{expr: "GitRepository.source.toolkit.fluxcd.io", canonical: "GitRepository.source.toolkit.fluxcd.io", matches: []schema.GroupKind{gitRepository}},
+		{expr: "*.source.toolkit.fluxcd.io", canonical: "*.source.toolkit.fluxcd.io", matches: []schema.GroupKind{gitRepository, bucket}},
+		{expr: "*.ocm.software, ConfigMap", canonical: "*.ocm.software,ConfigMap", matches: []schema.GroupKind{artifact, configMap}},
+		{expr: "!Bucket.*", canonical: "!Bucket.*", matches: []schema.GroupKind{gitRepository, artifact, configMap}},
+		{expr: "*.source.toolkit.fluxcd.io,!Bucket.*", canonical: "*.source.toolkit.fluxcd.io,!Bucket.*", matches: []schema.GroupKind{gitRepository}},
+		{expr: " Git?epository.* ,\t!*", canonical: "Git?epository.*,!*"},
+		{expr: "Config?ap", canonical: "Config?ap", matches: []schema.GroupKind{configMap}},
+		{expr: "*", canonical: "*", matches: all},
+		{expr: "Config-Map", canonical: "Config-Map"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			m, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if m.String() != tt.canonical {
+				t.Errorf("expected canonical expression %q, got %q", tt.canonical, m.String())
+			}
+
+			var matches []schema.GroupKind
+			for _, gk := range all {
+				if m.Match(gk) {
+					matches = append(matches, gk)
+				}
+			}
+			if !reflect.DeepEqual(matches, tt.matches) {
+				t.Errorf("expected matches %v, got %v", tt.matches, matches)
+			}
+
+			// the canonical expression parses to an equivalent matcher
+			round, err := Parse(m.String())
+			if err != nil {
+				t.Fatalf("unable to parse canonical expression: %v", err)
+			}
+			if !reflect.DeepEqual(round, m) {
+				t.Errorf("canonical expression %q parses to %v, expected %v", m.String(), round.patterns, m.patterns)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+	}{
+		{expr: ",", pos: 0},
+		{expr: "GitRepository,", pos: 14},
+		{expr: "GitRepository, !", pos: 15},
+		{expr: "GitRepository,,Bucket", pos: 14},
+		{expr: "!", pos: 0},
+		{expr: ".source.toolkit.fluxcd.io", pos: 0},
+		{expr: "Bucket, GitRepository.", pos: 8},
+		{expr: "Bucket,Git..io", pos: 7},
+		{expr: "Bucket,  Git_Repository", pos: 9},
+		{expr: "Git Repository", pos: 0},
+		{expr: "!!Bucket", pos: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected parse error, got %v", err)
+			}
+			if perr.Expr != tt.expr || perr.Pos != tt.pos {
+				t.Errorf("expected error for %q at position %d, got %q at position %d", tt.expr, tt.pos, perr.Expr, perr.Pos)
+			}
+		})
+	}
+
+	t.Run("must parse", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected panic")
+			}
+		}()
+		MustParse("Git_Repository")
+	})
+}
+
+func TestGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"", "", true},
+		{"", "a", false},
+		{"*", "", true},
+		{"*", "a.b.c", true},
+		{"a*", "abc", true},
+		{"a*c", "abbbc", true},
+		{"a*c", "abcb", false},
+		{"*.io", "a.b.io", true},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"*b*", "abc", true},
+		{"a*b*c", "axbxbxc", true},
+		{"a*b*c", "axbx", false},
+	}
+	for _, tt := range tests {
+		if glob(tt.pattern, tt.name) != tt.match {
+			t.Errorf("glob(%q, %q) != %t", tt.pattern, tt.name, tt.match)
+		}
+	}
+}
+
+func TestPatternMatcherValue(t *testing.T) {
+	var m PatternMatcher
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&m, "source-kinds", "")
+	if err := fs.Parse([]string{"--source-kinds", "*.source.toolkit.fluxcd.io, !Bucket.*"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	const canonical = "*.source.toolkit.fluxcd.io,!Bucket.*"
+	if m.String() != canonical {
+		t.Errorf("expected %q, got %q", canonical, m.String())
+	}
+	if !m.Match(gitRepository) || m.Match(bucket) {
+		t.Errorf("unexpected matches of %q", m.String())
+	}
+	// invalid expressions keep the previous value
+	if err := m.Set("Git_Repository"); err == nil {
+		t.Errorf("expected error for invalid expression")
+	}
+	if m.String() != canonical {
+		t.Errorf("expected %q, got %q", canonical, m.String())
+	}
+
+	text, err := m.MarshalText()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var u PatternMatcher
+	if err := u.UnmarshalText(text); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(u, m) {
+		t.Errorf("expected %v, got %v", m.patterns, u.patterns)
+	}
+	if (*PatternMatcher)(nil).String() != "" {
+		t.Errorf("expected empty expression for nil matcher")
+	}
+}
@@ -0,0 +1,142 @@
+package matchers
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SourceFactory creates empty source objects for a group kind.
+// MapMatcher, VersionedMapMatcher and ServedVersionFactory implement it.
+type SourceFactory interface {
+	SourceMatcher
+	Create(gk schema.GroupKind) client.Object
+}
+
+// VersionedMapMatcher is a SourceFactory knowing the Go types of several
+// versions of a group kind. Without further information, Create chooses the
+// version with the highest priority according to the Kubernetes version
+// ordering (e.g. v1 before v1beta2).
+type VersionedMapMatcher map[schema.GroupVersionKind]client.Object
+
+var _ SourceFactory = VersionedMapMatcher(nil)
+
+func (m VersionedMapMatcher) Match(gk schema.GroupKind) bool {
+	return len(m.Versions(gk)) > 0
+}
+
+func (m VersionedMapMatcher) MatchVersion(gvk schema.GroupVersionKind) bool {
+	_, ok := m[gvk]
+	return ok
+}
+
+// Versions returns the known versions of a group kind in order of preference.
+func (m VersionedMapMatcher) Versions(gk schema.GroupKind) []string {
+	var versions []string
+	for gvk := range m {
+		if gvk.GroupKind() == gk {
+			versions = append(versions, gvk.Version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return version.CompareKubeAwareVersionStrings(versions[i], versions[j]) > 0
+	})
+	return versions
+}
+
+// GroupKinds returns all group kinds known by the matcher.
+func (m VersionedMapMatcher) GroupKinds() []schema.GroupKind {
+	set := map[schema.GroupKind]struct{}{}
+	var list []schema.GroupKind
+	for gvk := range m {
+		if _, ok := set[gvk.GroupKind()]; !ok {
+			set[gvk.GroupKind()] = struct{}{}
+			list = append(list, gvk.GroupKind())
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].String() < list[j].String()
+	})
+	return list
+}
+
+func (m VersionedMapMatcher) Create(gk schema.GroupKind) client.Object {
+	versions := m.Versions(gk)
+	if len(versions) == 0 {
+		return nil
+	}
+	return m.CreateVersion(gk.WithVersion(versions[0]))
+}
+
+func (m VersionedMapMatcher) CreateVersion(gvk schema.GroupVersionKind) client.Object {
+	obj := m[gvk]
+	if obj == nil {
+		return nil
+	}
+	return obj.DeepCopyObject().(client.Object)
+}
+
+// Preferred returns a MapMatcher containing the preferred version of every
+// group kind.
+func (m VersionedMapMatcher) Preferred() MapMatcher {
+	r := MapMatcher{}
+	for _, gk := range m.GroupKinds() {
+		r[gk] = m.Create(gk)
+	}
+	return r
+}
+
+// ForMapper returns a SourceFactory choosing the version served by the
+// cluster known by the given RESTMapper.
+func (m VersionedMapMatcher) ForMapper(mapper meta.RESTMapper) *ServedVersionFactory {
+	return &ServedVersionFactory{Kinds: m, Mapper: mapper}
+}
+
+// ServedVersionFactory creates source objects for the version of a group kind
+// served by the cluster. The known versions are tried in order of preference.
+// If the RESTMapper cannot resolve any of them, the preferred known version
+// is used.
+type ServedVersionFactory struct {
+	Kinds  VersionedMapMatcher
+	Mapper meta.RESTMapper
+}
+
+var _ SourceFactory = (*ServedVersionFactory)(nil)
+
+func (f *ServedVersionFactory) Match(gk schema.GroupKind) bool {
+	return f.Kinds.Match(gk)
+}
+
+func (f *ServedVersionFactory) Create(gk schema.GroupKind) client.Object {
+	gvk, ok := f.ServedVersion(gk)
+	if !ok {
+		return f.Kinds.Create(gk)
+	}
+	return f.Kinds.CreateVersion(gvk)
+}
+
+// CreateVersion creates an object for the requested version if it is known.
+// An empty version is resolved to the served one.
+func (f *ServedVersionFactory) CreateVersion(gvk schema.GroupVersionKind) client.Object {
+	if gvk.Version == "" || !f.Kinds.MatchVersion(gvk) {
+		return f.Create(gvk.GroupKind())
+	}
+	return f.Kinds.CreateVersion(gvk)
+}
+
+// ServedVersion returns the first known version of the group kind served
+// by the cluster.
+func (f *ServedVersionFactory) ServedVersion(gk schema.GroupKind) (schema.GroupVersionKind, bool) {
+	versions := f.Kinds.Versions(gk)
+	if len(versions) == 0 || f.Mapper == nil {
+		return schema.GroupVersionKind{}, false
+	}
+	mapping, err := f.Mapper.RESTMapping(gk, versions...)
+	if err != nil || !f.Kinds.MatchVersion(mapping.GroupVersionKind) {
+		return schema.GroupVersionKind{}, false
+	}
+	return mapping.GroupVersionKind, true
+}
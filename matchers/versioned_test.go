@@ -0,0 +1,122 @@
+package matchers
+
+import (
+	"reflect"
+	"testing"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	sourcev1b2 "github.com/fluxcd/source-controller/api/v1beta2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	gitRepositoryV1   = sourcev1.GroupVersion.WithKind(sourcev1.GitRepositoryKind)
+	gitRepositoryV1b2 = sourcev1b2.GroupVersion.WithKind(sourcev1b2.GitRepositoryKind)
+	bucketV1b2        = sourcev1b2.GroupVersion.WithKind(sourcev1b2.BucketKind)
+)
+
+func newTestVersions() VersionedMapMatcher {
+	return VersionedMapMatcher{
+		gitRepositoryV1:   &sourcev1.GitRepository{},
+		gitRepositoryV1b2: &sourcev1b2.GitRepository{},
+		bucketV1b2:        &sourcev1b2.Bucket{},
+	}
+}
+
+// newTestMapper returns a RESTMapper serving the given versions.
+func newTestMapper(gvks ...schema.GroupVersionKind) meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for _, gvk := range gvks {
+		mapper.Add(gvk, meta.RESTScopeNamespace)
+	}
+	return mapper
+}
+
+func expectType(t *testing.T, obj client.Object, expected client.Object) {
+	t.Helper()
+	if reflect.TypeOf(obj) != reflect.TypeOf(expected) {
+		t.Errorf("expected %T, got %T", expected, obj)
+	}
+}
+
+func TestVersionedMapMatcher(t *testing.T) {
+	m := newTestVersions()
+
+	if !m.Match(gitRepository) || !m.Match(bucket) || m.Match(artifact) {
+		t.Errorf("unexpected matches")
+	}
+	if !m.MatchVersion(gitRepositoryV1b2) || m.MatchVersion(bucket.WithVersion("v1")) {
+		t.Errorf("unexpected version matches")
+	}
+	if versions := m.Versions(gitRepository); !reflect.DeepEqual(versions, []string{"v1", "v1beta2"}) {
+		t.Errorf("unexpected versions %v", versions)
+	}
+	if gks := m.GroupKinds(); !reflect.DeepEqual(gks, []schema.GroupKind{bucket, gitRepository}) {
+		t.Errorf("unexpected group kinds %v", gks)
+	}
+
+	expectType(t, m.Create(gitRepository), &sourcev1.GitRepository{})
+	expectType(t, m.CreateVersion(gitRepositoryV1b2), &sourcev1b2.GitRepository{})
+	if m.Create(artifact) != nil || m.CreateVersion(bucket.WithVersion("v1")) != nil {
+		t.Errorf("expected no object for unknown kinds")
+	}
+
+	// created objects are copies
+	obj := m.Create(bucket)
+	obj.SetName("changed")
+	if m.Create(bucket).GetName() != "" {
+		t.Errorf("prototype modified")
+	}
+
+	preferred := m.Preferred()
+	if len(preferred) != 2 {
+		t.Errorf("unexpected preferred kinds %v", preferred)
+	}
+	expectType(t, preferred.Create(gitRepository), &sourcev1.GitRepository{})
+	expectType(t, preferred.Create(bucket), &sourcev1b2.Bucket{})
+}
+
+func TestServedVersionFactory(t *testing.T) {
+	tests := []struct {
+		name     string
+		mapper   meta.RESTMapper
+		gvk      schema.GroupVersionKind
+		expected client.Object
+	}{
+		{name: "no mapper", gvk: gitRepository.WithVersion(""), expected: &sourcev1.GitRepository{}},
+		{name: "preferred version served", mapper: newTestMapper(gitRepositoryV1, gitRepositoryV1b2),
+			gvk: gitRepository.WithVersion(""), expected: &sourcev1.GitRepository{}},
+		{name: "older version served", mapper: newTestMapper(gitRepositoryV1b2),
+			gvk: gitRepository.WithVersion(""), expected: &sourcev1b2.GitRepository{}},
+		{name: "unknown version served", mapper: newTestMapper(gitRepository.WithVersion("v2")),
+			gvk: gitRepository.WithVersion(""), expected: &sourcev1.GitRepository{}},
+		{name: "not served", mapper: newTestMapper(),
+			gvk: gitRepository.WithVersion(""), expected: &sourcev1.GitRepository{}},
+		{name: "requested version", mapper: newTestMapper(gitRepositoryV1, gitRepositoryV1b2),
+			gvk: gitRepositoryV1b2, expected: &sourcev1b2.GitRepository{}},
+		{name: "unknown requested version", mapper: newTestMapper(gitRepositoryV1b2),
+			gvk: gitRepository.WithVersion("v2"), expected: &sourcev1b2.GitRepository{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestVersions().ForMapper(tt.mapper)
+			if !f.Match(tt.gvk.GroupKind()) {
+				t.Errorf("expected match for %s", tt.gvk.GroupKind())
+			}
+			expectType(t, f.CreateVersion(tt.gvk), tt.expected)
+		})
+	}
+
+	f := newTestVersions().ForMapper(newTestMapper(gitRepositoryV1b2))
+	if gvk, ok := f.ServedVersion(gitRepository); !ok || gvk != gitRepositoryV1b2 {
+		t.Errorf("unexpected served version %s", gvk)
+	}
+	if _, ok := f.ServedVersion(bucket); ok {
+		t.Errorf("expected no served version for %s", bucket)
+	}
+	if f.Create(artifact) != nil {
+		t.Errorf("expected no object for unknown kind")
+	}
+}
@@ -0,0 +1,120 @@
+package oci
+
+import (
+	"context"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"fmt"
+	"io"
+	"strings"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/opencontainers/go-digest"
+	"github.com/openfluxcd/artifact/integrity"
+)
+
+// Scheme is the URL scheme of Artifacts stored in an OCI registry.
+// The URL has the form oci://<registry>/<repository>@<manifest digest>.
+const Scheme = "oci://"
+
+// IsOCIURL checks whether an Artifact URL refers to an OCI registry.
+func IsOCIURL(url string) bool {
+	return strings.HasPrefix(url, Scheme)
+}
+
+// ParseURL parses an oci:// URL. Only references by digest are accepted,
+// tags are mutable and cannot be verified.
+func ParseURL(url string, opts ...name.Option) (name.Digest, error) {
+	if !IsOCIURL(url) {
+		return name.Digest{}, fmt.Errorf("invalid OCI artifact url %q: expected scheme %s", url, Scheme)
+	}
+	ref, err := name.NewDigest(strings.TrimPrefix(url, Scheme), opts...)
+	if err != nil {
+		return name.Digest{}, fmt.Errorf("invalid OCI artifact url %q: %w", url, err)
+	}
+	return ref, nil
+}
+
+// URLFor returns the oci:// URL of a manifest in a repository.
+func URLFor(repo name.Repository, manifest v1.Hash) string {
+	return Scheme + repo.Digest(manifest.String()).String()
+}
+
+// Fetcher pulls Artifacts with oci:// URLs. The manifest is fetched by the
+// digest of the URL, the content is the layer with the digest of the Artifact.
+// Artifacts without digest are accepted if the manifest has a single layer.
+type Fetcher struct {
+	// NameOptions are used to parse the URLs, e.g. name.Insecure.
+	NameOptions []name.Option
+	// RemoteOptions are used to access the registry, e.g. for authentication.
+	RemoteOptions []remote.Option
+}
+
+// Fetch writes the content of the Artifact to the writer. The content is
+// verified against the digest of the Artifact while it is written. If the
+// verification fails, an integrity.DigestMismatchError is returned and the
+// content written so far must be discarded.
+func (f *Fetcher) Fetch(ctx context.Context, art *sourcev1.Artifact, w io.Writer) error {
+	ref, err := ParseURL(art.URL, f.NameOptions...)
+	if err != nil {
+		return err
+	}
+	// the manifest is verified against the digest of the reference by remote.Image
+	img, err := remote.Image(ref, append([]remote.Option{remote.WithContext(ctx)}, f.RemoteOptions...)...)
+	if err != nil {
+		return fmt.Errorf("unable to fetch manifest of artifact %s: %w", art.URL, err)
+	}
+	layer, err := selectLayer(img, art)
+	if err != nil {
+		return err
+	}
+
+	expected := digest.Digest(art.Digest)
+	if expected == "" {
+		d, err := layer.Digest()
+		if err != nil {
+			return err
+		}
+		expected = digest.Digest(d.String())
+	}
+	if err := expected.Validate(); err != nil {
+		return fmt.Errorf("invalid digest of artifact %s: %w", art.URL, err)
+	}
+
+	rc, err := layer.Compressed()
+	if err != nil {
+		return fmt.Errorf("unable to fetch content of artifact %s: %w", art.URL, err)
+	}
+	defer rc.Close()
+	digester := expected.Algorithm().Digester()
+	if _, err := io.Copy(io.MultiWriter(w, digester.Hash()), rc); err != nil {
+		return fmt.Errorf("unable to fetch content of artifact %s: %w", art.URL, err)
+	}
+	if actual := digester.Digest(); actual != expected {
+		return &integrity.DigestMismatchError{URL: art.URL, Expected: expected.String(), Actual: actual.String()}
+	}
+	return nil
+}
+
+// selectLayer returns the layer of the image with the digest of the Artifact.
+func selectLayer(img v1.Image, art *sourcev1.Artifact) (v1.Layer, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read manifest of artifact %s: %w", art.URL, err)
+	}
+	if art.Digest == "" {
+		if len(manifest.Layers) != 1 {
+			return nil, fmt.Errorf("artifact %s has no digest and %d layers, expected exactly one", art.URL, len(manifest.Layers))
+		}
+		return img.LayerByDigest(manifest.Layers[0].Digest)
+	}
+	for _, l := range manifest.Layers {
+		if l.Digest.String() == art.Digest {
+			return img.LayerByDigest(l.Digest)
+		}
+	}
+	return nil, &integrity.DigestMismatchError{URL: art.URL, Expected: art.Digest, Actual: "no matching layer"}
+}
@@ -0,0 +1,103 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	. "github.com/onsi/gomega"
+	"github.com/openfluxcd/artifact/integrity"
+)
+
+func newTestRegistry(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+func writeContent(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "content.tar.gz")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPushAndFetch(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	host := newTestRegistry(t)
+
+	now := time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)
+	pusher := &Pusher{Now: func() time.Time { return now }}
+	art, err := pusher.Push(ctx, host+"/manifests/app", writeContent(t, "content"), "v1.0.0", map[string]string{"team": "platform"})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(art.Spec.URL).To(HavePrefix(Scheme + host + "/manifests/app@sha256:"))
+	g.Expect(art.Spec.Revision).To(Equal("v1.0.0"))
+	g.Expect(art.Spec.Digest).To(Equal(fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("content")))))
+	g.Expect(*art.Spec.Size).To(Equal(int64(len("content"))))
+	g.Expect(art.Spec.LastUpdateTime.Time).To(Equal(now))
+	g.Expect(art.Spec.Metadata).To(Equal(map[string]string{
+		"team":             "platform",
+		RevisionAnnotation: "v1.0.0",
+		CreatedAnnotation:  "2024-05-06T12:00:00Z",
+	}))
+
+	var buf bytes.Buffer
+	g.Expect((&Fetcher{}).Fetch(ctx, art.GetArtifact(), &buf)).To(Succeed())
+	g.Expect(buf.String()).To(Equal("content"))
+
+	// without digest, the single layer is used
+	src := art.GetArtifact()
+	src.Digest = ""
+	buf.Reset()
+	g.Expect((&Fetcher{}).Fetch(ctx, src, &buf)).To(Succeed())
+	g.Expect(buf.String()).To(Equal("content"))
+}
+
+func TestFetchDigestMismatch(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	host := newTestRegistry(t)
+
+	art, err := (&Pusher{}).Push(ctx, host+"/app", writeContent(t, "content"), "v1", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	src := art.GetArtifact()
+	src.Digest = fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("other")))
+	err = (&Fetcher{}).Fetch(ctx, src, io.Discard)
+	g.Expect(integrity.IsDigestMismatch(err)).To(BeTrue(), "unexpected error %v", err)
+
+	// the manifest is verified against the digest of the URL
+	src = art.GetArtifact()
+	src.URL = src.URL[:strings.Index(src.URL, "@")] + "@sha256:" + strings.Repeat("0", 64)
+	g.Expect((&Fetcher{}).Fetch(ctx, src, io.Discard)).NotTo(Succeed())
+}
+
+func TestParseURL(t *testing.T) {
+	g := NewWithT(t)
+
+	digest := "sha256:" + strings.Repeat("a", 64)
+	ref, err := ParseURL("oci://ghcr.io/org/repo@" + digest)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ref.Context().String()).To(Equal("ghcr.io/org/repo"))
+	g.Expect(ref.DigestStr()).To(Equal(digest))
+
+	_, err = ParseURL("https://ghcr.io/org/repo@" + digest)
+	g.Expect(err).To(HaveOccurred())
+	_, err = ParseURL("oci://ghcr.io/org/repo:latest")
+	g.Expect(err).To(HaveOccurred())
+}
@@ -0,0 +1,151 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ConfigMediaType is the media type of the config of pushed artifacts,
+	// as used by Flux.
+	ConfigMediaType types.MediaType = "application/vnd.cncf.flux.config.v1+json"
+	// ContentMediaType is the media type of the content layer of pushed
+	// artifacts, as used by Flux.
+	ContentMediaType types.MediaType = "application/vnd.cncf.flux.content.v1.tar+gzip"
+
+	// RevisionAnnotation is the OCI annotation holding the revision.
+	RevisionAnnotation = "org.opencontainers.image.revision"
+	// CreatedAnnotation is the OCI annotation holding the creation time.
+	CreatedAnnotation = "org.opencontainers.image.created"
+)
+
+// Pusher pushes storage content as OCI artifacts.
+type Pusher struct {
+	// NameOptions are used to parse the repository, e.g. name.Insecure.
+	NameOptions []name.Option
+	// RemoteOptions are used to access the registry, e.g. for authentication.
+	RemoteOptions []remote.Option
+
+	// Now returns the current time, it defaults to time.Now.
+	Now func() time.Time
+}
+
+// Push pushes the file at the given path as the single layer of an OCI
+// artifact into the repository. The file content is pushed unchanged, so
+// the digest of the returned Artifact is the digest of the file. The given
+// annotations are added to the manifest and used as metadata of the Artifact.
+// The returned Artifact has no name and namespace.
+func (p *Pusher) Push(ctx context.Context, repository, path, revision string, annotations map[string]string) (*artifactv1.Artifact, error) {
+	repo, err := name.NewRepository(repository, p.NameOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository %q: %w", repository, err)
+	}
+	layer, err := newFileLayer(path)
+	if err != nil {
+		return nil, err
+	}
+
+	now := p.now().UTC().Truncate(time.Second)
+	metadata := maps.Clone(annotations)
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metadata[RevisionAnnotation] = revision
+	metadata[CreatedAnnotation] = now.Format(time.RFC3339)
+
+	img := mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+	img = mutate.ConfigMediaType(img, ConfigMediaType)
+	l, err := partial.CompressedToLayer(layer)
+	if err != nil {
+		return nil, err
+	}
+	img, err = mutate.Append(img, mutate.Addendum{Layer: l})
+	if err != nil {
+		return nil, err
+	}
+	img = mutate.Annotations(img, metadata).(v1.Image)
+
+	manifest, err := img.Digest()
+	if err != nil {
+		return nil, err
+	}
+	if err := remote.Write(repo.Digest(manifest.String()), img, append([]remote.Option{remote.WithContext(ctx)}, p.RemoteOptions...)...); err != nil {
+		return nil, fmt.Errorf("unable to push artifact to %s: %w", repo, err)
+	}
+	size := layer.size
+	return &artifactv1.Artifact{
+		Spec: artifactv1.ArtifactSpec{
+			URL:            URLFor(repo, manifest),
+			Revision:       revision,
+			Digest:         layer.digest.String(),
+			LastUpdateTime: metav1.NewTime(now),
+			Size:           &size,
+			Metadata:       metadata,
+		},
+	}, nil
+}
+
+func (p *Pusher) now() time.Time {
+	if p.Now != nil {
+		return p.Now()
+	}
+	return time.Now()
+}
+
+// fileLayer is a layer with the unmodified content of a file.
+type fileLayer struct {
+	path   string
+	digest v1.Hash
+	size   int64
+}
+
+var _ partial.CompressedLayer = (*fileLayer)(nil)
+
+func newFileLayer(path string) (*fileLayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open artifact content: %w", err)
+	}
+	defer f.Close()
+	digest, size, err := v1.SHA256(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to digest artifact content: %w", err)
+	}
+	return &fileLayer{path: path, digest: digest, size: size}, nil
+}
+
+func (l *fileLayer) Digest() (v1.Hash, error) {
+	return l.digest, nil
+}
+
+// DiffID returns the digest of the file, the content is not interpreted,
+// as done by Flux for static layers.
+func (l *fileLayer) DiffID() (v1.Hash, error) {
+	return l.digest, nil
+}
+
+func (l *fileLayer) Compressed() (io.ReadCloser, error) {
+	return os.Open(l.path)
+}
+
+func (l *fileLayer) Size() (int64, error) {
+	return l.size, nil
+}
+
+func (l *fileLayer) MediaType() (types.MediaType, error) {
+	return ContentMediaType, nil
+}
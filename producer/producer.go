@@ -0,0 +1,239 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	"github.com/opencontainers/go-digest"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// DefaultFieldOwner is the field manager used for the published Artifacts,
+// if no FieldOwner is configured.
+const DefaultFieldOwner = "artifact-producer"
+
+// Content describes the content of an Artifact to publish.
+type Content struct {
+	// Name of the Artifact, it defaults to the name of the owner.
+	Name string
+
+	// URL is the address the content is served at.
+	URL string
+
+	// Revision of the content.
+	Revision string
+
+	// Path is the local file with the content. If given, the digest and size
+	// are computed from the file.
+	Path string
+
+	// Digest is the digest of the content, used if no Path is given.
+	Digest string
+
+	// Size is the size of the content, used if no Path is given.
+	Size *int64
+
+	// MediaType of the content.
+	MediaType string
+
+	// Metadata holds upstream information such as OCI annotations.
+	Metadata map[string]string
+}
+
+// Producer publishes the content of a source as an Artifact owned by the
+// source. Source controllers call Publish after storing a new revision.
+//
+// The Artifact is server-side applied with the configured field owner.
+// It is controlled by the source, so it is garbage collected together with
+// it, and blocks the deletion of the source in the foreground until it is
+// deleted. Publishing unchanged content does not modify the Artifact, its
+// LastUpdateTime is only updated if the content changes. The published
+// artifact is mirrored into status.artifact of the source.
+type Producer struct {
+	Client ctrlclient.Client
+
+	// FieldOwner is the field manager used for server-side apply.
+	// It defaults to DefaultFieldOwner.
+	FieldOwner string
+
+	// Now returns the current time, it defaults to time.Now.
+	Now func() time.Time
+}
+
+// Publish creates or updates the Artifact for the content of the owner.
+// If an Artifact with the same name is controlled by another object, a
+// controllerutil.AlreadyOwnedError is returned. Conflicting concurrent
+// updates are retried.
+func (p *Producer) Publish(ctx context.Context, owner ctrlclient.Object, content Content) (*artifactv1.Artifact, error) {
+	gvk, err := apiutil.GVKForObject(owner, p.Client.Scheme())
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine kind of owner: %w", err)
+	}
+	if owner.GetUID() == "" {
+		return nil, fmt.Errorf("owner %s '%s/%s' has no uid", gvk.Kind, owner.GetNamespace(), owner.GetName())
+	}
+
+	spec := artifactv1.ArtifactSpec{
+		URL:       content.URL,
+		Revision:  content.Revision,
+		Digest:    content.Digest,
+		Size:      content.Size,
+		MediaType: content.MediaType,
+		Metadata:  content.Metadata,
+	}
+	if content.Path != "" {
+		dig, size, err := Digest(content.Path)
+		if err != nil {
+			return nil, err
+		}
+		spec.Digest, spec.Size = dig.String(), &size
+	}
+
+	name := content.Name
+	if name == "" {
+		name = owner.GetName()
+	}
+	art := &artifactv1.Artifact{}
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		art, err = p.apply(ctx, owner, ctrlclient.ObjectKey{Namespace: owner.GetNamespace(), Name: name}, spec)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.mirrorStatus(ctx, owner, art.GetArtifact()); err != nil {
+		return art, err
+	}
+	return art, nil
+}
+
+func (p *Producer) apply(ctx context.Context, owner ctrlclient.Object, key ctrlclient.ObjectKey, spec artifactv1.ArtifactSpec) (*artifactv1.Artifact, error) {
+	var existing artifactv1.Artifact
+	exists := true
+	if err := p.Client.Get(ctx, key, &existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("unable to get artifact '%s': %w", key, err)
+		}
+		exists = false
+	}
+
+	art := &artifactv1.Artifact{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       artifactv1.ArtifactKind,
+			APIVersion: artifactv1.GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: key.Namespace,
+			Name:      key.Name,
+		},
+		Spec: spec,
+	}
+	// keep the controller reference, so that conflicting owners are detected
+	art.OwnerReferences = existing.OwnerReferences
+	if err := controllerutil.SetControllerReference(owner, art, p.Client.Scheme()); err != nil {
+		return nil, err
+	}
+	art.OwnerReferences = []metav1.OwnerReference{*metav1.GetControllerOf(art)}
+
+	art.Spec.LastUpdateTime = metav1.NewTime(p.now())
+	if exists {
+		current := existing.Spec
+		current.LastUpdateTime = art.Spec.LastUpdateTime
+		if equality.Semantic.DeepEqual(current, art.Spec) && metav1.IsControlledBy(&existing, owner) {
+			return &existing, nil
+		}
+		if art.GetArtifact().HasRevision(existing.Spec.Revision) && art.Spec.Digest == existing.Spec.Digest {
+			// the content is unchanged
+			art.Spec.LastUpdateTime = existing.Spec.LastUpdateTime
+		}
+		// conflict on concurrent updates
+		art.ResourceVersion = existing.ResourceVersion
+	}
+
+	if err := p.Client.Patch(ctx, art, ctrlclient.Apply, ctrlclient.ForceOwnership, ctrlclient.FieldOwner(p.fieldOwner())); err != nil {
+		return nil, err
+	}
+	return art, nil
+}
+
+// mirrorStatus sets status.artifact of the owner, if it does not match the artifact yet.
+func (p *Producer) mirrorStatus(ctx context.Context, owner ctrlclient.Object, artifact *sourcev1.Artifact) error {
+	if equality.Semantic.DeepEqual(statusArtifact(owner), artifact) {
+		return nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"artifact": artifact,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if err := p.Client.Status().Patch(ctx, owner, ctrlclient.RawPatch(types.MergePatchType, patch)); err != nil {
+		return fmt.Errorf("unable to update artifact in status of '%s/%s': %w", owner.GetNamespace(), owner.GetName(), err)
+	}
+	return nil
+}
+
+// statusArtifact returns status.artifact of an object, or nil if not set.
+func statusArtifact(obj ctrlclient.Object) *sourcev1.Artifact {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil
+	}
+	m, ok, err := unstructured.NestedMap(u, "status", "artifact")
+	if err != nil || !ok {
+		return nil
+	}
+	var artifact sourcev1.Artifact
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &artifact); err != nil {
+		return nil
+	}
+	return &artifact
+}
+
+func (p *Producer) fieldOwner() string {
+	if p.FieldOwner != "" {
+		return p.FieldOwner
+	}
+	return DefaultFieldOwner
+}
+
+func (p *Producer) now() time.Time {
+	if p.Now != nil {
+		return p.Now()
+	}
+	return time.Now()
+}
+
+// Digest computes the sha256 digest and the size of a file.
+func Digest(path string) (digest.Digest, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("unable to open artifact content: %w", err)
+	}
+	defer f.Close()
+	digester := digest.SHA256.Digester()
+	size, err := io.Copy(digester.Hash(), f)
+	if err != nil {
+		return "", 0, fmt.Errorf("unable to digest artifact content: %w", err)
+	}
+	return digester.Digest(), size, nil
+}
@@ -0,0 +1,186 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+var now = time.Date(2024, 5, 4, 12, 0, 0, 0, time.UTC)
+
+// testClient records the calls of a fake client. The fake client does not
+// support server-side apply, apply patches are executed as create or update.
+type testClient struct {
+	ctrlclient.Client
+	applies       int
+	statusPatches int
+	conflicts     int
+}
+
+func newTestClient(objs ...ctrlclient.Object) *testClient {
+	scheme := runtime.NewScheme()
+	_ = artifactv1.AddToScheme(scheme)
+	_ = sourcev1.AddToScheme(scheme)
+	c := &testClient{}
+	c.Client = fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithStatusSubresource(&sourcev1.GitRepository{}, &artifactv1.Artifact{}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, client ctrlclient.WithWatch, obj ctrlclient.Object, patch ctrlclient.Patch, opts ...ctrlclient.PatchOption) error {
+				if patch.Type() != types.ApplyPatchType {
+					return client.Patch(ctx, obj, patch, opts...)
+				}
+				c.applies++
+				if c.conflicts > 0 {
+					c.conflicts--
+					return apierrors.NewConflict(schema.GroupResource{Group: artifactv1.GroupVersion.Group, Resource: "artifacts"}, obj.GetName(), errors.New("concurrent update"))
+				}
+				if obj.GetResourceVersion() == "" {
+					return client.Create(ctx, obj)
+				}
+				return client.Update(ctx, obj)
+			},
+			SubResourcePatch: func(ctx context.Context, client ctrlclient.Client, subResourceName string, obj ctrlclient.Object, patch ctrlclient.Patch, opts ...ctrlclient.SubResourcePatchOption) error {
+				c.statusPatches++
+				return client.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+			},
+		}).Build()
+	return c
+}
+
+func newOwner() *sourcev1.GitRepository {
+	repo := &sourcev1.GitRepository{}
+	repo.Namespace, repo.Name, repo.UID = "default", "app", "uid"
+	return repo
+}
+
+func newContent(revision string) Content {
+	size := int64(42)
+	return Content{
+		URL:       "http://storage/default/app/" + revision + ".tar.gz",
+		Revision:  revision,
+		Digest:    "sha256:" + revision,
+		Size:      &size,
+		MediaType: artifactv1.MediaTypeTarGzip,
+	}
+}
+
+func TestPublish(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	owner := newOwner()
+	client := newTestClient(owner)
+	clock := now
+	p := &Producer{Client: client, Now: func() time.Time { return clock }}
+
+	art, err := p.Publish(ctx, owner, newContent("v1"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(art.Name).To(Equal("app"))
+	g.Expect(art.Spec.Revision).To(Equal("v1"))
+	g.Expect(art.Spec.LastUpdateTime.Time).To(BeTemporally("==", now))
+	g.Expect(metav1.IsControlledBy(art, owner)).To(BeTrue())
+	g.Expect(*art.OwnerReferences[0].BlockOwnerDeletion).To(BeTrue())
+
+	// the artifact is mirrored into the status of the owner
+	var repo sourcev1.GitRepository
+	g.Expect(client.Get(ctx, ctrlclient.ObjectKeyFromObject(owner), &repo)).To(Succeed())
+	g.Expect(repo.Status.Artifact).ToNot(BeNil())
+	g.Expect(repo.Status.Artifact.Revision).To(Equal("v1"))
+	g.Expect(repo.Status.Artifact.URL).To(Equal(art.Spec.URL))
+	g.Expect(client.applies).To(Equal(1))
+	g.Expect(client.statusPatches).To(Equal(1))
+
+	t.Run("unchanged content", func(t *testing.T) {
+		g := NewWithT(t)
+		clock = now.Add(time.Hour)
+		art, err := p.Publish(ctx, owner, newContent("v1"))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(art.Spec.LastUpdateTime.Time).To(BeTemporally("==", now))
+		g.Expect(client.applies).To(Equal(1))
+		g.Expect(client.statusPatches).To(Equal(1))
+	})
+
+	t.Run("changed metadata", func(t *testing.T) {
+		g := NewWithT(t)
+		clock = now.Add(2 * time.Hour)
+		content := newContent("v1")
+		content.Metadata = map[string]string{"org.opencontainers.image.source": "https://example.com"}
+		art, err := p.Publish(ctx, owner, content)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(art.Spec.Metadata).To(Equal(content.Metadata))
+		g.Expect(art.Spec.LastUpdateTime.Time).To(BeTemporally("==", now))
+		g.Expect(client.applies).To(Equal(2))
+	})
+
+	t.Run("new revision", func(t *testing.T) {
+		g := NewWithT(t)
+		clock = now.Add(3 * time.Hour)
+		art, err := p.Publish(ctx, owner, newContent("v2"))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(art.Spec.Revision).To(Equal("v2"))
+		g.Expect(art.Spec.LastUpdateTime.Time).To(BeTemporally("==", clock))
+		g.Expect(owner.Status.Artifact.Revision).To(Equal("v2"))
+		g.Expect(client.statusPatches).To(Equal(3))
+	})
+}
+
+func TestPublishFromPath(t *testing.T) {
+	g := NewWithT(t)
+	path := filepath.Join(t.TempDir(), "content.tar.gz")
+	g.Expect(os.WriteFile(path, []byte("content"), 0o644)).To(Succeed())
+	owner := newOwner()
+	p := &Producer{Client: newTestClient(owner)}
+
+	content := newContent("v1")
+	content.Path = path
+	art, err := p.Publish(context.Background(), owner, content)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(art.Spec.Digest).To(Equal("sha256:ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"))
+	g.Expect(*art.Spec.Size).To(Equal(int64(7)))
+}
+
+func TestPublishAlreadyOwned(t *testing.T) {
+	g := NewWithT(t)
+	owner := newOwner()
+	other := newOwner()
+	other.Name, other.UID = "other", "other"
+	existing := &artifactv1.Artifact{}
+	existing.Namespace, existing.Name = "default", "app"
+	g.Expect(controllerutil.SetControllerReference(other, existing, newTestClient().Scheme())).To(Succeed())
+	client := newTestClient(owner, other, existing)
+	p := &Producer{Client: client}
+
+	_, err := p.Publish(context.Background(), owner, newContent("v1"))
+	var owned *controllerutil.AlreadyOwnedError
+	g.Expect(errors.As(err, &owned)).To(BeTrue())
+	g.Expect(client.applies).To(BeZero())
+	g.Expect(client.statusPatches).To(BeZero())
+}
+
+func TestPublishConflict(t *testing.T) {
+	g := NewWithT(t)
+	owner := newOwner()
+	client := newTestClient(owner)
+	client.conflicts = 2
+	p := &Producer{Client: client}
+
+	art, err := p.Publish(context.Background(), owner, newContent("v1"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(art.Spec.Revision).To(Equal("v1"))
+	g.Expect(client.applies).To(Equal(3))
+}
@@ -0,0 +1,415 @@
+package promotion
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/acl"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/openfluxcd/artifact/action"
+	"github.com/openfluxcd/artifact/api/commonv1"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// PromotedReason is used if the revision has been promoted.
+	PromotedReason = "Promoted"
+	// AccessDeniedReason is used if no SourceAccessGrant permits the access
+	// to the source Artifact in another namespace.
+	AccessDeniedReason = "AccessDenied"
+	// SourceNotFoundReason is used if the source Artifact does not exist.
+	SourceNotFoundReason = "SourceNotFound"
+	// RevisionNotAvailableReason is used if the source Artifact does not
+	// publish the revision and digest to promote.
+	RevisionNotAvailableReason = "RevisionNotAvailable"
+	// AwaitingApprovalReason is used until the promotion is approved.
+	AwaitingApprovalReason = "AwaitingApproval"
+	// SoakingReason is used until the revision has soaked for the soak time.
+	SoakingReason = "Soaking"
+	// ConsumersUnhealthyReason is used as long as a consumer of the source
+	// Artifact in its namespace has not successfully applied the revision.
+	ConsumersUnhealthyReason = "ConsumersUnhealthy"
+	// ConsumerTrackingMissingReason is used as long as the consumers of a
+	// consumer kind are not tracked in the status of the source Artifact.
+	ConsumerTrackingMissingReason = "ConsumerTrackingMissing"
+	// TargetConflictReason is used if the target Artifact exists and is not
+	// managed by the promotion.
+	TargetConflictReason = "TargetConflict"
+)
+
+// DefaultConsumerKinds are the consumer kinds checked by the Reconciler, if
+// no ConsumerKinds are configured.
+var DefaultConsumerKinds = []schema.GroupKind{
+	{Group: "kustomize.toolkit.fluxcd.io", Kind: "Kustomization"},
+	{Group: "helm.toolkit.fluxcd.io", Kind: "HelmRelease"},
+}
+
+// Reconciler copies the revision of an Artifact described by an
+// ArtifactPromotion into the namespace of the promotion, once the promotion
+// is approved, the soak time has passed and all consumers of the source
+// Artifact in its namespace have successfully applied the revision.
+//
+// The consumers are taken from the status of the source Artifact. Every one
+// of the ConsumerKinds must be tracked there by an action.ConsumerReconciler,
+// consumers of other kinds block the promotion, because their health cannot
+// be verified. If the source Artifact is located in another namespace, a
+// SourceAccessGrant in its namespace must permit the access by the promotion.
+//
+// The promoted Artifact is controlled by the ArtifactPromotion. It preserves
+// URL, revision, digest, size and metadata of the source Artifact and records
+// its provenance in the Promoted* annotations. Once promoted, the target is
+// kept, even if the source Artifact moves on to another revision.
+type Reconciler struct {
+	Client ctrlclient.Client
+
+	// ConsumerReader is used to read the consumers of the source Artifact.
+	// It should be uncached, otherwise an informer is started for every
+	// consumer kind. It defaults to the API reader of the manager.
+	// +optional
+	ConsumerReader ctrlclient.Reader
+
+	// ConsumerKinds are the consumer kinds whose health is checked. The
+	// controller requires get permissions for them. It defaults to
+	// DefaultConsumerKinds.
+	// +optional
+	ConsumerKinds []schema.GroupKind
+
+	// Interval is used to recheck the consumers of the source Artifact.
+	// It defaults to 1 minute.
+	Interval time.Duration
+
+	// Now returns the current time, it defaults to time.Now.
+	Now func() time.Time
+}
+
+// +kubebuilder:rbac:groups=openfluxcd.ocm.software,resources=artifactpromotions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=openfluxcd.ocm.software,resources=artifactpromotions/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=openfluxcd.ocm.software,resources=artifacts,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=openfluxcd.ocm.software,resources=sourceaccessgrants,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kustomize.toolkit.fluxcd.io,resources=kustomizations,verbs=get
+// +kubebuilder:rbac:groups=helm.toolkit.fluxcd.io,resources=helmreleases,verbs=get
+
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.ConsumerReader == nil {
+		r.ConsumerReader = mgr.GetAPIReader()
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("artifact-promotion").
+		For(&artifactv1.ArtifactPromotion{}).
+		Owns(&artifactv1.Artifact{}).
+		Watches(&artifactv1.Artifact{}, handler.EnqueueRequestsFromMapFunc(r.promotionsForSource)).
+		Watches(&artifactv1.SourceAccessGrant{}, handler.EnqueueRequestsFromMapFunc(r.promotionsForGrant)).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.promotionsInNamespace),
+			builder.WithPredicates(predicate.LabelChangedPredicate{})).
+		Complete(r)
+}
+
+// promotionsForSource maps an Artifact to the promotions using it as source.
+func (r *Reconciler) promotionsForSource(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	var list artifactv1.ArtifactPromotionList
+	if err := r.Client.List(ctx, &list); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "unable to list artifact promotions")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, p := range list.Items {
+		if p.Spec.Source.Namespace == obj.GetNamespace() && p.Spec.Source.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(&p)})
+		}
+	}
+	return requests
+}
+
+// promotionsForGrant maps a SourceAccessGrant to the promotions of other
+// namespaces using a source in its namespace.
+func (r *Reconciler) promotionsForGrant(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	var list artifactv1.ArtifactPromotionList
+	if err := r.Client.List(ctx, &list); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "unable to list artifact promotions")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, p := range list.Items {
+		if p.Spec.Source.Namespace == obj.GetNamespace() && p.Namespace != obj.GetNamespace() {
+			requests = append(requests, reconcile.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(&p)})
+		}
+	}
+	return requests
+}
+
+// promotionsInNamespace maps a Namespace to the promotions located in it,
+// whose access may be granted by a namespace selector.
+func (r *Reconciler) promotionsInNamespace(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	var list artifactv1.ArtifactPromotionList
+	if err := r.Client.List(ctx, &list, ctrlclient.InNamespace(obj.GetName())); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "unable to list artifact promotions")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, p := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(&p)})
+	}
+	return requests
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	var promotion artifactv1.ArtifactPromotion
+	if err := r.Client.Get(ctx, req.NamespacedName, &promotion); err != nil {
+		return reconcile.Result{}, ctrlclient.IgnoreNotFound(err)
+	}
+	if !promotion.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+
+	orig := promotion.DeepCopy()
+	result, err := r.reconcile(ctx, &promotion)
+	if err != nil {
+		conditions.MarkFalse(&promotion, meta.ReadyCondition, meta.FailedReason, "%s", err)
+	}
+	promotion.Status.ObservedGeneration = promotion.Generation
+	if !equality.Semantic.DeepEqual(orig.Status, promotion.Status) {
+		patch := ctrlclient.MergeFromWithOptions(orig, ctrlclient.MergeFromWithOptimisticLock{})
+		if perr := r.Client.Status().Patch(ctx, &promotion, patch); perr != nil && err == nil {
+			err = fmt.Errorf("unable to update status of artifact promotion: %w", perr)
+		}
+	}
+	return result, err
+}
+
+func (r *Reconciler) reconcile(ctx context.Context, promotion *artifactv1.ArtifactPromotion) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+	spec := promotion.Spec
+	source := fmt.Sprintf("%s/%s", spec.Source.Namespace, spec.Source.Name)
+
+	target := &artifactv1.Artifact{}
+	targetKey := ctrlclient.ObjectKey{Namespace: promotion.Namespace, Name: TargetName(promotion)}
+	if err := r.Client.Get(ctx, targetKey, target); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return reconcile.Result{}, fmt.Errorf("unable to get target artifact: %w", err)
+		}
+		target = nil
+	}
+	if target != nil {
+		if !metav1.IsControlledBy(target, promotion) {
+			conditions.MarkFalse(promotion, meta.ReadyCondition, TargetConflictReason,
+				"artifact '%s' exists and is not managed by the promotion", targetKey.Name)
+			return reconcile.Result{}, nil
+		}
+	}
+
+	if spec.Source.Namespace != promotion.Namespace {
+		ref := &commonv1.SourceRef{APIVersion: artifactv1.GroupVersion.String(), Kind: artifactv1.ArtifactKind,
+			Namespace: spec.Source.Namespace, Name: spec.Source.Name}
+		if err := action.CheckSourceAccessGrants(ctx, r.Client, promotion, ref); err != nil {
+			if !acl.IsAccessDenied(err) {
+				return reconcile.Result{}, err
+			}
+			conditions.MarkFalse(promotion, meta.ReadyCondition, AccessDeniedReason, "%s", err)
+			return reconcile.Result{}, nil
+		}
+	}
+
+	if target != nil {
+		if IsPromoted(promotion, target) {
+			conditions.MarkTrue(promotion, meta.ReadyCondition, PromotedReason,
+				"revision %s promoted from '%s'", spec.Revision, source)
+			return reconcile.Result{}, nil
+		}
+	}
+
+	var src artifactv1.Artifact
+	if err := r.Client.Get(ctx, ctrlclient.ObjectKey{Namespace: spec.Source.Namespace, Name: spec.Source.Name}, &src); err != nil {
+		if apierrors.IsNotFound(err) {
+			conditions.MarkFalse(promotion, meta.ReadyCondition, SourceNotFoundReason,
+				"source artifact '%s' not found", source)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("unable to get source artifact: %w", err)
+	}
+	if !src.GetArtifact().HasRevision(spec.Revision) || src.Spec.Digest != spec.Digest {
+		conditions.MarkFalse(promotion, meta.ReadyCondition, RevisionNotAvailableReason,
+			"source artifact '%s' serves revision %s (%s)", source, src.Spec.Revision, src.Spec.Digest)
+		return reconcile.Result{}, nil
+	}
+
+	if !spec.Approved {
+		conditions.MarkFalse(promotion, meta.ReadyCondition, AwaitingApprovalReason,
+			"promotion of revision %s has not been approved", spec.Revision)
+		return reconcile.Result{}, nil
+	}
+	if spec.SoakTime != nil {
+		soaked := r.now().Sub(src.Spec.LastUpdateTime.Time)
+		if remaining := spec.SoakTime.Duration - soaked; remaining > 0 {
+			conditions.MarkFalse(promotion, meta.ReadyCondition, SoakingReason,
+				"revision %s soaks for another %s", spec.Revision, remaining.Round(time.Second))
+			return reconcile.Result{RequeueAfter: remaining}, nil
+		}
+	}
+	if missing := r.untrackedConsumerKinds(&src); len(missing) > 0 {
+		conditions.MarkFalse(promotion, meta.ReadyCondition, ConsumerTrackingMissingReason,
+			"consumers of kinds %v are not tracked by source artifact '%s'", missing, source)
+		return reconcile.Result{RequeueAfter: r.interval()}, nil
+	}
+	unhealthy, err := r.unhealthyConsumers(ctx, &src)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if len(unhealthy) > 0 {
+		conditions.MarkFalse(promotion, meta.ReadyCondition, ConsumersUnhealthyReason,
+			"consumers have not successfully applied revision %s: %v", spec.Revision, unhealthy)
+		return reconcile.Result{RequeueAfter: r.interval()}, nil
+	}
+
+	if err := r.promote(ctx, promotion, &src, target); err != nil {
+		return reconcile.Result{}, err
+	}
+	log.Info("artifact promoted", "source", source, "revision", spec.Revision, "artifact", targetKey.Name)
+	conditions.MarkTrue(promotion, meta.ReadyCondition, PromotedReason,
+		"revision %s promoted from '%s'", spec.Revision, source)
+	return reconcile.Result{}, nil
+}
+
+// untrackedConsumerKinds returns the consumer kinds not tracked in the
+// status of the source Artifact.
+func (r *Reconciler) untrackedConsumerKinds(src *artifactv1.Artifact) []string {
+	var missing []string
+	for _, gk := range r.consumerKinds() {
+		if !slices.Contains(src.Status.TrackedConsumerKinds, gk.String()) {
+			missing = append(missing, gk.String())
+		}
+	}
+	return missing
+}
+
+// unhealthyConsumers returns the consumers of the source Artifact in its
+// namespace, which have not applied its revision, are not ready or are of
+// a kind not checked by the reconciler.
+func (r *Reconciler) unhealthyConsumers(ctx context.Context, src *artifactv1.Artifact) ([]string, error) {
+	var unhealthy []string
+	for _, c := range src.Status.Consumers {
+		if c.Namespace != src.Namespace {
+			continue
+		}
+		name := fmt.Sprintf("%s/%s", c.Kind, c.Name)
+		if !src.GetArtifact().HasRevision(c.LastAppliedRevision) || !slices.Contains(r.consumerKinds(), schema.GroupKind{Group: c.Group, Kind: c.Kind}) {
+			unhealthy = append(unhealthy, name)
+			continue
+		}
+		mapping, err := r.Client.RESTMapper().RESTMapping(schema.GroupKind{Group: c.Group, Kind: c.Kind})
+		if err != nil {
+			return nil, fmt.Errorf("unable to determine version of consumer kind %s: %w", c.Kind, err)
+		}
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(mapping.GroupVersionKind)
+		if err := r.consumerReader().Get(ctx, ctrlclient.ObjectKey{Namespace: c.Namespace, Name: c.Name}, obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("unable to get consumer %s: %w", name, err)
+		}
+		if !conditions.IsReady(conditions.UnstructuredGetter(obj)) {
+			unhealthy = append(unhealthy, name)
+		}
+	}
+	return unhealthy, nil
+}
+
+// promote creates or updates the target Artifact as a copy of the source Artifact.
+func (r *Reconciler) promote(ctx context.Context, promotion *artifactv1.ArtifactPromotion, src, target *artifactv1.Artifact) error {
+	now := metav1.NewTime(r.now())
+	create := target == nil
+	if create {
+		target = &artifactv1.Artifact{
+			ObjectMeta: metav1.ObjectMeta{Namespace: promotion.Namespace, Name: TargetName(promotion)},
+		}
+	}
+	orig := target.DeepCopy()
+
+	target.Spec = *src.Spec.DeepCopy()
+	annotations := target.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[artifactv1.PromotedFromAnnotation] = fmt.Sprintf("%s/%s", src.Namespace, src.Name)
+	annotations[artifactv1.PromotedByAnnotation] = fmt.Sprintf("%s/%s", promotion.Namespace, promotion.Name)
+	annotations[artifactv1.PromotedAtAnnotation] = now.UTC().Format(time.RFC3339)
+	if promotion.Spec.ApprovedBy != "" {
+		annotations[artifactv1.PromotionApprovedByAnnotation] = promotion.Spec.ApprovedBy
+	} else {
+		delete(annotations, artifactv1.PromotionApprovedByAnnotation)
+	}
+	target.SetAnnotations(annotations)
+	if err := controllerutil.SetControllerReference(promotion, target, r.Client.Scheme()); err != nil {
+		return err
+	}
+
+	if create {
+		if err := r.Client.Create(ctx, target); err != nil {
+			return fmt.Errorf("unable to create target artifact: %w", err)
+		}
+	} else if err := r.Client.Patch(ctx, target, ctrlclient.MergeFromWithOptions(orig, ctrlclient.MergeFromWithOptimisticLock{})); err != nil {
+		return fmt.Errorf("unable to update target artifact: %w", err)
+	}
+	promotion.Status.PromotedAt = &now
+	return nil
+}
+
+// TargetName returns the name of the Artifact created by a promotion.
+func TargetName(promotion *artifactv1.ArtifactPromotion) string {
+	if promotion.Spec.TargetName != "" {
+		return promotion.Spec.TargetName
+	}
+	return promotion.Spec.Source.Name
+}
+
+// IsPromoted checks whether an Artifact is the promoted copy described by a promotion.
+func IsPromoted(promotion *artifactv1.ArtifactPromotion, art *artifactv1.Artifact) bool {
+	src := promotion.Spec.Source
+	return art.Annotations[artifactv1.PromotedFromAnnotation] == fmt.Sprintf("%s/%s", src.Namespace, src.Name) &&
+		art.GetArtifact().HasRevision(promotion.Spec.Revision) && art.Spec.Digest == promotion.Spec.Digest
+}
+
+func (r *Reconciler) consumerKinds() []schema.GroupKind {
+	if r.ConsumerKinds != nil {
+		return r.ConsumerKinds
+	}
+	return DefaultConsumerKinds
+}
+
+func (r *Reconciler) consumerReader() ctrlclient.Reader {
+	if r.ConsumerReader != nil {
+		return r.ConsumerReader
+	}
+	return r.Client
+}
+
+func (r *Reconciler) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+func (r *Reconciler) interval() time.Duration {
+	if r.Interval > 0 {
+		return r.Interval
+	}
+	return time.Minute
+}
@@ -0,0 +1,250 @@
+package promotion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	fluxmeta "github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	. "github.com/onsi/gomega"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var kustomizationGroupVersion = schema.GroupVersion{Group: "kustomize.toolkit.fluxcd.io", Version: "v1"}
+var kustomizationKind = schema.GroupKind{Group: kustomizationGroupVersion.Group, Kind: "Kustomization"}
+
+var now = time.Date(2024, 5, 4, 12, 0, 0, 0, time.UTC)
+
+type testConsumer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status struct {
+		Conditions []metav1.Condition `json:"conditions,omitempty"`
+	} `json:"status"`
+}
+
+func (c *testConsumer) DeepCopyObject() runtime.Object {
+	o := *c
+	c.ObjectMeta.DeepCopyInto(&o.ObjectMeta)
+	o.Status.Conditions = append([]metav1.Condition(nil), c.Status.Conditions...)
+	return &o
+}
+
+func newTestConsumer(name string, ready metav1.ConditionStatus) *testConsumer {
+	c := &testConsumer{}
+	c.Namespace, c.Name = "staging", name
+	c.Status.Conditions = []metav1.Condition{{Type: fluxmeta.ReadyCondition, Status: ready, Reason: "Test", LastTransitionTime: metav1.NewTime(now)}}
+	return c
+}
+
+func newSource() *artifactv1.Artifact {
+	src := &artifactv1.Artifact{}
+	src.Namespace, src.Name = "staging", "app"
+	src.Spec.URL = "http://storage/staging/app/v1.tar.gz"
+	src.Spec.Revision = "v1"
+	src.Spec.Digest = "sha256:abc"
+	src.Spec.LastUpdateTime = metav1.NewTime(now.Add(-time.Hour))
+	src.Status.TrackedConsumerKinds = []string{kustomizationKind.String()}
+	src.Status.Consumers = []artifactv1.ArtifactConsumer{
+		{Group: kustomizationKind.Group, Kind: kustomizationKind.Kind, Namespace: "staging", Name: "app", LastAppliedRevision: "v1"},
+	}
+	return src
+}
+
+func newPromotion() *artifactv1.ArtifactPromotion {
+	p := &artifactv1.ArtifactPromotion{}
+	p.Namespace, p.Name = "production", "app"
+	p.Spec.Source = artifactv1.PromotionSource{Namespace: "staging", Name: "app"}
+	p.Spec.Revision = "v1"
+	p.Spec.Digest = "sha256:abc"
+	p.Spec.Approved = true
+	p.Spec.ApprovedBy = "jane"
+	return p
+}
+
+func newGrant() *artifactv1.SourceAccessGrant {
+	grant := &artifactv1.SourceAccessGrant{}
+	grant.Namespace, grant.Name = "staging", "promotions"
+	grant.Spec.From = []artifactv1.SourceAccessGrantFrom{{Group: artifactv1.GroupVersion.Group, Kind: artifactv1.ArtifactPromotionKind, Namespace: "production"}}
+	grant.Spec.To = []artifactv1.SourceAccessGrantTo{{Group: artifactv1.GroupVersion.Group, Kind: artifactv1.ArtifactKind}}
+	return grant
+}
+
+func newTestReconciler(objs ...ctrlclient.Object) *Reconciler {
+	scheme := runtime.NewScheme()
+	_ = artifactv1.AddToScheme(scheme)
+	scheme.AddKnownTypeWithName(kustomizationGroupVersion.WithKind(kustomizationKind.Kind), &testConsumer{})
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{kustomizationGroupVersion})
+	mapper.Add(kustomizationGroupVersion.WithKind(kustomizationKind.Kind), meta.RESTScopeNamespace)
+	client := fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper).WithObjects(objs...).
+		WithStatusSubresource(&artifactv1.ArtifactPromotion{}).Build()
+	return &Reconciler{Client: client, ConsumerKinds: []schema.GroupKind{kustomizationKind}, Now: func() time.Time { return now }}
+}
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		name    string
+		objs    func(p *artifactv1.ArtifactPromotion, src *artifactv1.Artifact) []ctrlclient.Object
+		denied  bool
+		reason  string
+		requeue time.Duration
+	}{
+		{
+			name: "promoted",
+			objs: func(p *artifactv1.ArtifactPromotion, src *artifactv1.Artifact) []ctrlclient.Object {
+				return []ctrlclient.Object{newTestConsumer("app", metav1.ConditionTrue)}
+			},
+			reason: PromotedReason,
+		},
+		{
+			name: "no consumers",
+			objs: func(p *artifactv1.ArtifactPromotion, src *artifactv1.Artifact) []ctrlclient.Object {
+				src.Status.Consumers = nil
+				return nil
+			},
+			reason: PromotedReason,
+		},
+		{
+			name: "access denied",
+			objs: func(p *artifactv1.ArtifactPromotion, src *artifactv1.Artifact) []ctrlclient.Object {
+				return []ctrlclient.Object{newTestConsumer("app", metav1.ConditionTrue)}
+			},
+			denied: true,
+			reason: AccessDeniedReason,
+		},
+		{
+			name: "source not found",
+			objs: func(p *artifactv1.ArtifactPromotion, src *artifactv1.Artifact) []ctrlclient.Object {
+				p.Spec.Source.Name = "other"
+				return nil
+			},
+			reason: SourceNotFoundReason,
+		},
+		{
+			name: "revision not available",
+			objs: func(p *artifactv1.ArtifactPromotion, src *artifactv1.Artifact) []ctrlclient.Object {
+				src.Spec.Revision = "v2"
+				return nil
+			},
+			reason: RevisionNotAvailableReason,
+		},
+		{
+			name: "awaiting approval",
+			objs: func(p *artifactv1.ArtifactPromotion, src *artifactv1.Artifact) []ctrlclient.Object {
+				p.Spec.Approved = false
+				return nil
+			},
+			reason: AwaitingApprovalReason,
+		},
+		{
+			name: "soaking",
+			objs: func(p *artifactv1.ArtifactPromotion, src *artifactv1.Artifact) []ctrlclient.Object {
+				p.Spec.SoakTime = &metav1.Duration{Duration: 3 * time.Hour}
+				return nil
+			},
+			reason:  SoakingReason,
+			requeue: 2 * time.Hour,
+		},
+		{
+			name: "consumer tracking missing",
+			objs: func(p *artifactv1.ArtifactPromotion, src *artifactv1.Artifact) []ctrlclient.Object {
+				src.Status.TrackedConsumerKinds = nil
+				src.Status.Consumers = nil
+				return nil
+			},
+			reason:  ConsumerTrackingMissingReason,
+			requeue: time.Minute,
+		},
+		{
+			name: "consumer not applied",
+			objs: func(p *artifactv1.ArtifactPromotion, src *artifactv1.Artifact) []ctrlclient.Object {
+				src.Status.Consumers[0].LastAppliedRevision = "v0"
+				return []ctrlclient.Object{newTestConsumer("app", metav1.ConditionTrue)}
+			},
+			reason:  ConsumersUnhealthyReason,
+			requeue: time.Minute,
+		},
+		{
+			name: "consumer not ready",
+			objs: func(p *artifactv1.ArtifactPromotion, src *artifactv1.Artifact) []ctrlclient.Object {
+				return []ctrlclient.Object{newTestConsumer("app", metav1.ConditionFalse)}
+			},
+			reason:  ConsumersUnhealthyReason,
+			requeue: time.Minute,
+		},
+		{
+			name: "consumer of unchecked kind",
+			objs: func(p *artifactv1.ArtifactPromotion, src *artifactv1.Artifact) []ctrlclient.Object {
+				src.Status.Consumers = append(src.Status.Consumers, artifactv1.ArtifactConsumer{
+					Group: "example.com", Kind: "Deployer", Namespace: "staging", Name: "app", LastAppliedRevision: "v1"})
+				return []ctrlclient.Object{newTestConsumer("app", metav1.ConditionTrue)}
+			},
+			reason:  ConsumersUnhealthyReason,
+			requeue: time.Minute,
+		},
+		{
+			name: "target conflict",
+			objs: func(p *artifactv1.ArtifactPromotion, src *artifactv1.Artifact) []ctrlclient.Object {
+				target := &artifactv1.Artifact{}
+				target.Namespace, target.Name = "production", "app"
+				return []ctrlclient.Object{target}
+			},
+			reason: TargetConflictReason,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			promotion, src := newPromotion(), newSource()
+			objs := tt.objs(promotion, src)
+			if !tt.denied {
+				objs = append(objs, newGrant())
+			}
+			r := newTestReconciler(append(objs, promotion, src)...)
+
+			result, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(promotion)})
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(result.RequeueAfter).To(Equal(tt.requeue))
+
+			g.Expect(r.Client.Get(context.Background(), ctrlclient.ObjectKeyFromObject(promotion), promotion)).To(Succeed())
+			g.Expect(conditions.GetReason(promotion, fluxmeta.ReadyCondition)).To(Equal(tt.reason))
+
+			var target artifactv1.Artifact
+			err = r.Client.Get(context.Background(), ctrlclient.ObjectKey{Namespace: "production", Name: "app"}, &target)
+			if tt.reason != PromotedReason {
+				g.Expect(err == nil && IsPromoted(promotion, &target)).To(BeFalse())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(IsPromoted(promotion, &target)).To(BeTrue())
+			g.Expect(metav1.IsControlledBy(&target, promotion)).To(BeTrue())
+			g.Expect(target.Spec.URL).To(Equal(src.Spec.URL))
+			g.Expect(target.Annotations).To(HaveKeyWithValue(artifactv1.PromotedByAnnotation, "production/app"))
+			g.Expect(target.Annotations).To(HaveKeyWithValue(artifactv1.PromotionApprovedByAnnotation, "jane"))
+			g.Expect(promotion.Status.PromotedAt).ToNot(BeNil())
+		})
+	}
+}
+
+func TestPromotionsForGrant(t *testing.T) {
+	g := NewWithT(t)
+	local := newPromotion()
+	local.Namespace, local.Name = "staging", "local"
+	other := newPromotion()
+	other.Name = "other"
+	other.Spec.Source.Namespace = "development"
+	r := newTestReconciler(newPromotion(), local, other)
+
+	g.Expect(r.promotionsForGrant(context.Background(), newGrant())).To(ConsistOf(
+		reconcile.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(newPromotion())},
+	))
+}
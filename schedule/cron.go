@@ -0,0 +1,206 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed cron expression with the five standard fields
+//
+//	minute hour day-of-month month day-of-week
+//
+// Every field accepts '*', single values, ranges (a-b), steps (*/n, a-b/n)
+// and comma separated lists. Months and days of the week may also be given
+// by their three letter English names (JAN-DEC, SUN-SAT), Sunday is 0 or 7.
+// As in classic cron, if both day fields are restricted, a time matches if
+// either of them matches.
+type Cron struct {
+	expr   string
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	// domStar and dowStar record unrestricted day fields.
+	domStar bool
+	dowStar bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// ParseCron parses a cron expression.
+func ParseCron(expr string) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields, found %d", expr, len(fields))
+	}
+	c := &Cron{expr: expr}
+	var err error
+	if c.minute, err = minuteField.parse(fields[0]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	if c.hour, err = hourField.parse(fields[1]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	if c.dom, err = domField.parse(fields[2]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	if c.month, err = monthField.parse(fields[3]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	if c.dow, err = dowField.parse(fields[4]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = fields[2] == "*" || fields[2] == "?"
+	c.dowStar = fields[4] == "*" || fields[4] == "?"
+	return c, nil
+}
+
+func (f cronField) parse(s string) (uint64, error) {
+	var bits uint64
+	for _, elem := range strings.Split(s, ",") {
+		b, err := f.parseElem(elem)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+func (f cronField) parseElem(s string) (uint64, error) {
+	rng, step := s, 1
+	if i := strings.Index(s, "/"); i >= 0 {
+		n, err := strconv.Atoi(s[i+1:])
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid step in %s field %q", f.name, s)
+		}
+		rng, step = s[:i], n
+	}
+
+	lo, hi := f.min, f.max
+	switch {
+	case rng == "*" || rng == "?":
+	case strings.Contains(rng, "-"):
+		parts := strings.SplitN(rng, "-", 2)
+		var err error
+		if lo, err = f.value(parts[0]); err != nil {
+			return 0, err
+		}
+		if hi, err = f.value(parts[1]); err != nil {
+			return 0, err
+		}
+		if lo > hi {
+			return 0, fmt.Errorf("invalid range in %s field %q", f.name, s)
+		}
+	default:
+		v, err := f.value(rng)
+		if err != nil {
+			return 0, err
+		}
+		lo = v
+		if step == 1 {
+			hi = v
+		}
+	}
+
+	var bits uint64
+	for v := lo; v <= hi; v += step {
+		bits |= 1 << uint(v)
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s %q, expected a value between %d and %d", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+func (c *Cron) String() string {
+	return c.expr
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
+
+func (c *Cron) matchesDay(t time.Time) bool {
+	dom, dow := has(c.dom, t.Day()), has(c.dow, int(t.Weekday()))
+	switch {
+	case c.domStar && c.dowStar:
+		return true
+	case c.domStar:
+		return dow
+	case c.dowStar:
+		return dom
+	}
+	return dom || dow
+}
+
+// Matches checks whether the minute of the given time is matched by the expression.
+func (c *Cron) Matches(t time.Time) bool {
+	return has(c.month, int(t.Month())) && c.matchesDay(t) && has(c.hour, t.Hour()) && has(c.minute, t.Minute())
+}
+
+// Next returns the first time matched by the expression strictly after the
+// given time, in the location of the given time. It returns the zero time if
+// there is no such time within the next five years.
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !has(c.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !has(c.hour, t.Hour()) {
+			next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			if !next.After(t) {
+				// daylight saving time transition
+				next = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+			}
+			t = next
+			continue
+		}
+		if !has(c.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,80 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, expr := range []string{
+		"* * * * *",
+		"0 22 * * 1-5",
+		"*/15 0-6 1,15 * MON-FRI",
+		"30 2 * JAN-MAR sun,7",
+		"0 0-23/2 * * *",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			g := NewWithT(t)
+			c, err := ParseCron(expr)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(c.String()).To(Equal(expr))
+		})
+	}
+
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * FOO *",
+	} {
+		t.Run("invalid "+expr, func(t *testing.T) {
+			g := NewWithT(t)
+			_, err := ParseCron(expr)
+			g.Expect(err).To(HaveOccurred())
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 5, 1, 10, 0, 30, 0, time.UTC), time.Date(2024, 5, 1, 10, 1, 0, 0, time.UTC)},
+		{"0 22 * * 1-5", time.Date(2024, 5, 3, 22, 0, 0, 0, time.UTC), time.Date(2024, 5, 6, 22, 0, 0, 0, time.UTC)},
+		{"0 22 * * 1-5", time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC), time.Date(2024, 5, 6, 22, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 5, 1, 10, 16, 0, 0, time.UTC), time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// day of month or day of week
+		{"0 0 13 * 5", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)},
+		// sunday as 7
+		{"0 12 * * 7", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 5, 12, 0, 0, 0, time.UTC)},
+		// skipped hour at the begin of daylight saving time
+		{"30 * * * *", time.Date(2024, 3, 31, 1, 45, 0, 0, berlin), time.Date(2024, 3, 31, 3, 30, 0, 0, berlin)},
+		{"0 22 * * *", time.Date(2024, 5, 1, 12, 0, 0, 0, berlin), time.Date(2024, 5, 1, 22, 0, 0, 0, berlin)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr+" "+tt.from.String(), func(t *testing.T) {
+			g := NewWithT(t)
+			c, err := ParseCron(tt.expr)
+			g.Expect(err).NotTo(HaveOccurred())
+			next := c.Next(tt.from)
+			g.Expect(next.Equal(tt.want)).To(BeTrue(), "expected %s, got %s", tt.want, next)
+			g.Expect(c.Matches(next)).To(BeTrue())
+		})
+	}
+}
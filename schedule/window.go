@@ -0,0 +1,106 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+)
+
+// Window is a recurring time window starting at the times matched by a cron
+// expression in a time zone.
+type Window struct {
+	Start    *Cron
+	Duration time.Duration
+	Location *time.Location
+}
+
+// NewWindow creates a Window from its API representation.
+func NewWindow(spec artifactv1.MaintenanceWindow) (*Window, error) {
+	start, err := ParseCron(spec.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Duration.Duration <= 0 {
+		return nil, fmt.Errorf("invalid duration %s of window %q, it must be positive", spec.Duration.Duration, spec.Schedule)
+	}
+	loc := time.UTC
+	if spec.TimeZone != "" {
+		loc, err = time.LoadLocation(spec.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone of window %q: %w", spec.Schedule, err)
+		}
+	}
+	return &Window{Start: start, Duration: spec.Duration.Duration, Location: loc}, nil
+}
+
+// Open returns the start of the window containing the given time. The
+// second result is false, if the window is closed.
+func (w *Window) Open(t time.Time) (time.Time, bool) {
+	// the window is open if it has been started within the last duration
+	start := w.Start.Next(t.In(w.Location).Add(-w.Duration))
+	if start.IsZero() || start.After(t) {
+		return time.Time{}, false
+	}
+	return start, true
+}
+
+// Contains checks whether the window is open at the given time.
+func (w *Window) Contains(t time.Time) bool {
+	_, ok := w.Open(t)
+	return ok
+}
+
+// NextOpen returns the given time, if the window is open, or the time
+// the window opens next. It returns the zero time, if the window does
+// not open within the next five years.
+func (w *Window) NextOpen(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	return w.Start.Next(t.In(w.Location))
+}
+
+// Windows is a set of windows, which is open if any of its windows is open.
+// An empty set is always open.
+type Windows []*Window
+
+// NewWindows creates Windows from their API representation.
+func NewWindows(specs ...artifactv1.MaintenanceWindow) (Windows, error) {
+	var list Windows
+	for _, spec := range specs {
+		w, err := NewWindow(spec)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, w)
+	}
+	return list, nil
+}
+
+func (l Windows) Contains(t time.Time) bool {
+	if len(l) == 0 {
+		return true
+	}
+	for _, w := range l {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns the earliest time at or after the given time at which
+// one of the windows is open.
+func (l Windows) NextOpen(t time.Time) time.Time {
+	if l.Contains(t) {
+		return t
+	}
+	var next time.Time
+	for _, w := range l {
+		if n := w.NextOpen(t); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return next
+}
@@ -0,0 +1,65 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWindow(t *testing.T) {
+	g := NewWithT(t)
+
+	// weekday nights from 22:00 to 04:00 in Berlin (UTC+2 in summer)
+	w, err := NewWindow(artifactv1.MaintenanceWindow{
+		Schedule: "0 22 * * MON-FRI",
+		Duration: metav1.Duration{Duration: 6 * time.Hour},
+		TimeZone: "Europe/Berlin",
+	})
+	if err != nil {
+		t.Skipf("time zone database not available: %s", err)
+	}
+
+	// Wednesday
+	g.Expect(w.Contains(time.Date(2024, 5, 1, 19, 59, 0, 0, time.UTC))).To(BeFalse())
+	g.Expect(w.Contains(time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC))).To(BeTrue())
+	g.Expect(w.Contains(time.Date(2024, 5, 2, 1, 59, 0, 0, time.UTC))).To(BeTrue())
+	g.Expect(w.Contains(time.Date(2024, 5, 2, 2, 0, 0, 0, time.UTC))).To(BeFalse())
+
+	start, ok := w.Open(time.Date(2024, 5, 2, 1, 0, 0, 0, time.UTC))
+	g.Expect(ok).To(BeTrue())
+	g.Expect(start.Equal(time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC))).To(BeTrue())
+
+	// Saturday noon, the next window opens Monday evening
+	now := time.Date(2024, 5, 4, 12, 0, 0, 0, time.UTC)
+	g.Expect(w.Contains(now)).To(BeFalse())
+	g.Expect(w.NextOpen(now).Equal(time.Date(2024, 5, 6, 20, 0, 0, 0, time.UTC))).To(BeTrue())
+
+	// an open window is open now
+	open := time.Date(2024, 5, 6, 21, 0, 0, 0, time.UTC)
+	g.Expect(w.NextOpen(open)).To(Equal(open))
+}
+
+func TestWindows(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(Windows(nil).Contains(time.Now())).To(BeTrue())
+
+	list, err := NewWindows(
+		artifactv1.MaintenanceWindow{Schedule: "0 2 * * *", Duration: metav1.Duration{Duration: time.Hour}},
+		artifactv1.MaintenanceWindow{Schedule: "0 14 * * *", Duration: metav1.Duration{Duration: time.Hour}},
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(list.Contains(time.Date(2024, 5, 1, 2, 30, 0, 0, time.UTC))).To(BeTrue())
+	g.Expect(list.Contains(time.Date(2024, 5, 1, 14, 30, 0, 0, time.UTC))).To(BeTrue())
+	g.Expect(list.Contains(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))).To(BeFalse())
+	g.Expect(list.NextOpen(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))).To(Equal(time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)))
+
+	_, err = NewWindows(artifactv1.MaintenanceWindow{Schedule: "0 2 * * *"})
+	g.Expect(err).To(HaveOccurred())
+	_, err = NewWindows(artifactv1.MaintenanceWindow{Schedule: "0 2 * * *", Duration: metav1.Duration{Duration: time.Hour}, TimeZone: "Nowhere/Town"})
+	g.Expect(err).To(HaveOccurred())
+}
@@ -14,6 +14,10 @@ import (
 )
 
 func ApplyArtifact(client ctrl.Client, testServer *testserver.ArtifactServer, artifact ctrl.ObjectKey, urlpath string, revision string) (*commonv1.SourceRef, error) {
+	return applyArtifact(context.Background(), client, testServer, artifact, urlpath, revision)
+}
+
+func applyArtifact(ctx context.Context, client ctrl.Client, testServer *testserver.ArtifactServer, artifact ctrl.ObjectKey, urlpath string, revision string) (*commonv1.SourceRef, error) {
 	b, _ := os.ReadFile(filepath.Join(testServer.Root(), urlpath))
 	dig := digest.SHA256.FromBytes(b)
 
@@ -48,7 +52,7 @@ func ApplyArtifact(client ctrl.Client, testServer *testserver.ArtifactServer, ar
 		ctrl.FieldOwner("kustomize-controller"),
 	}
 
-	if err := client.Patch(context.Background(), art, ctrl.Apply, opt...); err != nil {
+	if err := client.Patch(ctx, art, ctrl.Apply, opt...); err != nil {
 		return nil, err
 	}
 
@@ -56,11 +60,15 @@ func ApplyArtifact(client ctrl.Client, testServer *testserver.ArtifactServer, ar
 }
 
 func ApplyGenericSource(client ctrl.Client, testServer *testserver.ArtifactServer, source ctrl.Object, urlpath string, revision string) (*commonv1.SourceRef, error) {
+	return applyGenericSource(context.Background(), client, testServer, source, urlpath, revision)
+}
+
+func applyGenericSource(ctx context.Context, client ctrl.Client, testServer *testserver.ArtifactServer, source ctrl.Object, urlpath string, revision string) (*commonv1.SourceRef, error) {
 	if source.GetObjectKind().GroupVersionKind().Version == "" || source.GetObjectKind().GroupVersionKind().Kind == "" {
 		return nil, fmt.Errorf("source APIVersion and Kind must be set")
 	}
 	sourceCopy := source.DeepCopyObject().(ctrl.Object)
-	if err := client.Create(context.Background(), sourceCopy); err != nil {
+	if err := client.Create(ctx, sourceCopy); err != nil {
 		return nil, err
 	}
 	source.SetUID(sourceCopy.GetUID())
@@ -72,6 +80,14 @@ func ApplyGenericSource(client ctrl.Client, testServer *testserver.ArtifactServe
 		Name:       source.GetName(),
 	}
 
+	if err := applyOwnedArtifact(ctx, client, testServer, source, urlpath, revision); err != nil {
+		return nil, err
+	}
+	return sourceref, nil
+}
+
+// applyOwnedArtifact applies the Artifact of a source created by ApplyGenericSource.
+func applyOwnedArtifact(ctx context.Context, client ctrl.Client, testServer *testserver.ArtifactServer, source ctrl.Object, urlpath string, revision string) error {
 	b, _ := os.ReadFile(filepath.Join(testServer.Root(), urlpath))
 	dig := digest.SHA256.FromBytes(b)
 
@@ -107,9 +123,5 @@ func ApplyGenericSource(client ctrl.Client, testServer *testserver.ArtifactServe
 		ctrl.FieldOwner("kustomize-controller"),
 	}
 
-	if err := client.Patch(context.Background(), art, ctrl.Apply, opt...); err != nil {
-		return nil, err
-	}
-
-	return sourceref, nil
+	return client.Patch(ctx, art, ctrl.Apply, opt...)
 }
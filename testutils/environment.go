@@ -0,0 +1,380 @@
+package testutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	sourcev1b2 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/openfluxcd/artifact/action"
+	"github.com/openfluxcd/artifact/api/commonv1"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/matchers"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	pkgruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// DefaultPollInterval and DefaultTimeout are used by the wait helpers of
+// an Environment.
+const (
+	DefaultPollInterval = 100 * time.Millisecond
+	DefaultTimeout      = 30 * time.Second
+)
+
+// Environment is an integration test environment. It runs a Kubernetes API
+// server with the CRDs of the Flux sources and of this module, an
+// ArtifactServer serving the content of the Artifacts and a controller
+// manager running the registered actions. Tests use the real watches and
+// indices instead of mocks.
+//
+// The API server is started by envtest, which requires the control plane
+// binaries, see KUBEBUILDER_ASSETS.
+type Environment struct {
+	// Config of the API server.
+	Config *rest.Config
+
+	// Client reads directly from the API server, it is not affected
+	// by the cache of the manager.
+	Client ctrlclient.Client
+
+	Manager        ctrl.Manager
+	ArtifactServer *testserver.ArtifactServer
+
+	// Timeout and PollInterval are used by the wait helpers, they default
+	// to DefaultTimeout and DefaultPollInterval.
+	Timeout      time.Duration
+	PollInterval time.Duration
+
+	testEnv *envtest.Environment
+	cancel  context.CancelFunc
+	done    chan error
+}
+
+// EnvironmentOption configures an Environment created by NewEnvironment.
+type EnvironmentOption func(o *environmentOptions)
+
+type environmentOptions struct {
+	addToScheme []func(*pkgruntime.Scheme) error
+	crdPaths    []string
+	setup       []func(env *Environment) error
+}
+
+// WithAddToScheme registers additional types, like the action resources
+// under test.
+func WithAddToScheme(f ...func(*pkgruntime.Scheme) error) EnvironmentOption {
+	return func(o *environmentOptions) {
+		o.addToScheme = append(o.addToScheme, f...)
+	}
+}
+
+// WithCRDDirectoryPaths installs additional CRDs, like the ones of the
+// action resources under test.
+func WithCRDDirectoryPaths(paths ...string) EnvironmentOption {
+	return func(o *environmentOptions) {
+		o.crdPaths = append(o.crdPaths, paths...)
+	}
+}
+
+// WithSetup registers a function called with the created Environment,
+// before the manager is started. It is used to add controllers to the
+// manager.
+func WithSetup(f func(env *Environment) error) EnvironmentOption {
+	return func(o *environmentOptions) {
+		o.setup = append(o.setup, f)
+	}
+}
+
+// WithAction registers a controller for the action resource type T with
+// the indices and watches of action.Setup. The reconciler is created with
+// the Environment, its client is the one of the manager.
+func WithAction[T any, P action.ActionResourcePointerType[T]](newReconciler func(env *Environment) reconcile.Reconciler, options ...action.Option) EnvironmentOption {
+	return WithSetup(func(env *Environment) error {
+		bldr, err := action.Setup[T, P](context.Background(), env.Manager, env.Manager.GetClient(), options...)
+		if err != nil {
+			return err
+		}
+		return bldr.Complete(newReconciler(env))
+	})
+}
+
+// NewEnvironment starts an Environment. It must be stopped with Stop.
+func NewEnvironment(options ...EnvironmentOption) (_ *Environment, err error) {
+	opts := &environmentOptions{}
+	for _, o := range options {
+		o(opts)
+	}
+
+	scheme := pkgruntime.NewScheme()
+	for _, f := range append([]func(*pkgruntime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		sourcev1.AddToScheme,
+		sourcev1b2.AddToScheme,
+		artifactv1.AddToScheme,
+	}, opts.addToScheme...) {
+		if err := f(scheme); err != nil {
+			return nil, err
+		}
+	}
+
+	env := &Environment{
+		testEnv: &envtest.Environment{
+			CRDDirectoryPaths: append([]string{crdDirectory()}, opts.crdPaths...),
+			CRDs:              fluxSourceCRDs(),
+			Scheme:            scheme,
+
+			ErrorIfCRDPathMissing: true,
+		},
+		done: make(chan error, 1),
+	}
+	env.Config, err = env.testEnv.Start()
+	if err != nil {
+		return nil, fmt.Errorf("unable to start test environment: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = env.Stop()
+		}
+	}()
+
+	env.ArtifactServer, err = testserver.NewTempArtifactServer()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create artifact server: %w", err)
+	}
+	env.ArtifactServer.Start()
+
+	env.Client, err = ctrlclient.New(env.Config, ctrlclient.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+	env.Manager, err = ctrl.NewManager(env.Config, ctrl.Options{
+		Scheme:  scheme,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create manager: %w", err)
+	}
+	for _, f := range opts.setup {
+		if err := f(env); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	env.cancel = cancel
+	go func() {
+		env.done <- env.Manager.Start(ctx)
+	}()
+	// the cache never syncs if the manager fails to start
+	synced := make(chan bool, 1)
+	go func() {
+		synced <- env.Manager.GetCache().WaitForCacheSync(ctx)
+	}()
+	select {
+	case err := <-env.done:
+		cancel()
+		env.cancel = nil
+		if err == nil {
+			err = fmt.Errorf("manager stopped")
+		}
+		return nil, fmt.Errorf("unable to start manager: %w", err)
+	case ok := <-synced:
+		if !ok {
+			return nil, fmt.Errorf("unable to sync cache of manager")
+		}
+	}
+	return env, nil
+}
+
+// Stop stops the manager, the ArtifactServer and the API server. All of them
+// are stopped even if one fails, the errors are joined.
+func (e *Environment) Stop() error {
+	var errs []error
+	if e.cancel != nil {
+		e.cancel()
+		if err := <-e.done; err != nil {
+			errs = append(errs, fmt.Errorf("manager failed: %w", err))
+		}
+		e.cancel = nil
+	}
+	if e.ArtifactServer != nil {
+		e.ArtifactServer.Stop()
+		if err := os.RemoveAll(e.ArtifactServer.Root()); err != nil {
+			errs = append(errs, fmt.Errorf("unable to remove artifact storage: %w", err))
+		}
+		e.ArtifactServer = nil
+	}
+	if err := e.testEnv.Stop(); err != nil {
+		errs = append(errs, fmt.Errorf("unable to stop API server: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// CreateSource creates a source object with an Artifact serving the files
+// for the revision. Artifact sources are applied, other source kinds are
+// created together with an Artifact owned by them, like it is done by
+// ApplyGenericSource.
+func (e *Environment) CreateSource(ctx context.Context, source ctrlclient.Object, revision string, files ...testserver.File) (*commonv1.SourceRef, error) {
+	if err := e.setGVK(source); err != nil {
+		return nil, err
+	}
+	urlpath, err := e.ArtifactServer.ArtifactFromFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := source.(*artifactv1.Artifact); ok {
+		return applyArtifact(ctx, e.Client, e.ArtifactServer, ctrlclient.ObjectKeyFromObject(source), urlpath, revision)
+	}
+	return applyGenericSource(ctx, e.Client, e.ArtifactServer, source, urlpath, revision)
+}
+
+// BumpRevision updates the Artifact of a source created by CreateSource
+// to serve the files for a new revision.
+func (e *Environment) BumpRevision(ctx context.Context, source ctrlclient.Object, revision string, files ...testserver.File) error {
+	if err := e.setGVK(source); err != nil {
+		return err
+	}
+	urlpath, err := e.ArtifactServer.ArtifactFromFiles(files)
+	if err != nil {
+		return err
+	}
+	if _, ok := source.(*artifactv1.Artifact); ok {
+		_, err := applyArtifact(ctx, e.Client, e.ArtifactServer, ctrlclient.ObjectKeyFromObject(source), urlpath, revision)
+		return err
+	}
+	current := source.DeepCopyObject().(ctrlclient.Object)
+	if err := e.Client.Get(ctx, ctrlclient.ObjectKeyFromObject(source), current); err != nil {
+		return err
+	}
+	source.SetUID(current.GetUID())
+	return applyOwnedArtifact(ctx, e.Client, e.ArtifactServer, source, urlpath, revision)
+}
+
+// WaitFor polls the object until the condition is met.
+func (e *Environment) WaitFor(ctx context.Context, obj ctrlclient.Object, condition func(obj ctrlclient.Object) bool) error {
+	key := ctrlclient.ObjectKeyFromObject(obj)
+	err := wait.PollUntilContextTimeout(ctx, e.pollInterval(), e.timeout(), true, func(ctx context.Context) (bool, error) {
+		if err := e.Client.Get(ctx, key, obj); err != nil {
+			return false, ctrlclient.IgnoreNotFound(err)
+		}
+		return condition(obj), nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for '%s': %w", key, err)
+	}
+	return nil
+}
+
+// WaitForRevision waits until the action resource has been reconciled for
+// the revision, i.e. its last applied or last attempted revision matches
+// the revision. See action.LastAppliedRevision and action.LastAttemptedRevision.
+func (e *Environment) WaitForRevision(ctx context.Context, obj ctrlclient.Object, revision string) error {
+	return e.WaitFor(ctx, obj, func(obj ctrlclient.Object) bool {
+		return action.LastAppliedRevision(obj) == revision || action.LastAttemptedRevision(obj) == revision
+	})
+}
+
+func (e *Environment) setGVK(obj ctrlclient.Object) error {
+	if !obj.GetObjectKind().GroupVersionKind().Empty() {
+		return nil
+	}
+	gvk, err := apiutil.GVKForObject(obj, e.Client.Scheme())
+	if err != nil {
+		return err
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	return nil
+}
+
+func (e *Environment) timeout() time.Duration {
+	if e.Timeout > 0 {
+		return e.Timeout
+	}
+	return DefaultTimeout
+}
+
+func (e *Environment) pollInterval() time.Duration {
+	if e.PollInterval > 0 {
+		return e.PollInterval
+	}
+	return DefaultPollInterval
+}
+
+// crdDirectory returns the directory with the CRDs of this module.
+func crdDirectory() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "config", "crd", "bases")
+}
+
+// fluxSourceCRDs returns CRDs for the Flux source kinds, serving all
+// versions known by matchers.BuiltinFluxSourceVersions. The schemas
+// preserve unknown fields, so they do not depend on the CRDs shipped with
+// the source-controller.
+func fluxSourceCRDs() []*apiextensionsv1.CustomResourceDefinition {
+	versions := map[string][]string{}
+	for gvk := range matchers.BuiltinFluxSourceVersions {
+		if gvk.Group != sourcev1.GroupVersion.Group {
+			continue
+		}
+		versions[gvk.Kind] = append(versions[gvk.Kind], gvk.Version)
+	}
+
+	preserve := true
+	var crds []*apiextensionsv1.CustomResourceDefinition
+	for kind, list := range versions {
+		sort.Strings(list)
+		plural, _ := meta.UnsafeGuessKindToResource(sourcev1.GroupVersion.WithKind(kind))
+		crd := &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: plural.Resource + "." + sourcev1.GroupVersion.Group,
+			},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Group: sourcev1.GroupVersion.Group,
+				Names: apiextensionsv1.CustomResourceDefinitionNames{
+					Kind:     kind,
+					ListKind: kind + "List",
+					Plural:   plural.Resource,
+					Singular: strings.ToLower(kind),
+				},
+				Scope: apiextensionsv1.NamespaceScoped,
+			},
+		}
+		for _, v := range list {
+			crd.Spec.Versions = append(crd.Spec.Versions, apiextensionsv1.CustomResourceDefinitionVersion{
+				Name:    v,
+				Served:  true,
+				Storage: v == list[0],
+				Schema: &apiextensionsv1.CustomResourceValidation{
+					OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+						Type:                   "object",
+						XPreserveUnknownFields: &preserve,
+					},
+				},
+				Subresources: &apiextensionsv1.CustomResourceSubresources{
+					Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+				},
+			})
+		}
+		crds = append(crds, crd)
+	}
+	sort.Slice(crds, func(i, j int) bool { return crds[i].Name < crds[j].Name })
+	return crds
+}
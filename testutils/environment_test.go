@@ -0,0 +1,180 @@
+package testutils
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	"github.com/openfluxcd/artifact/action"
+	"github.com/openfluxcd/artifact/api/commonv1"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	"github.com/openfluxcd/artifact/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var testActionGroupVersion = schema.GroupVersion{Group: "test.example.com", Version: "v1"}
+
+// testAction is an action resource applying the revision of its source,
+// its CRD is in testdata/crds.
+type testAction struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec struct {
+		SourceRef commonv1.SourceRef `json:"sourceRef"`
+	} `json:"spec"`
+	Status struct {
+		LastAppliedRevision string `json:"lastAppliedRevision,omitempty"`
+	} `json:"status"`
+}
+
+func (a *testAction) GetSourceRef() (utils.SourceRefProvider, error) {
+	return &a.Spec.SourceRef, nil
+}
+
+func (a *testAction) DeepCopyObject() runtime.Object {
+	c := *a
+	a.ObjectMeta.DeepCopyInto(&c.ObjectMeta)
+	return &c
+}
+
+type testActionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []testAction `json:"items"`
+}
+
+func (l *testActionList) DeepCopyObject() runtime.Object {
+	c := &testActionList{TypeMeta: l.TypeMeta}
+	l.ListMeta.DeepCopyInto(&c.ListMeta)
+	for i := range l.Items {
+		c.Items = append(c.Items, *l.Items[i].DeepCopyObject().(*testAction))
+	}
+	return c
+}
+
+func addTestActionToScheme(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypeWithName(testActionGroupVersion.WithKind("TestAction"), &testAction{})
+	scheme.AddKnownTypeWithName(testActionGroupVersion.WithKind("TestActionList"), &testActionList{})
+	metav1.AddToGroupVersion(scheme, testActionGroupVersion)
+	return nil
+}
+
+// testActionReconciler records the revision of the source as last applied revision.
+type testActionReconciler struct {
+	client ctrlclient.Client
+}
+
+func (r *testActionReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	obj := &testAction{}
+	if err := r.client.Get(ctx, req.NamespacedName, obj); err != nil {
+		return reconcile.Result{}, ctrlclient.IgnoreNotFound(err)
+	}
+	src, err := action.GetSource(ctx, r.client, obj)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	obj.Status.LastAppliedRevision = src.GetArtifact().Revision
+	return reconcile.Result{}, r.client.Status().Update(ctx, obj)
+}
+
+func TestFluxSourceCRDs(t *testing.T) {
+	g := NewWithT(t)
+
+	crds := map[string][]string{}
+	for _, crd := range fluxSourceCRDs() {
+		for _, v := range crd.Spec.Versions {
+			if v.Storage {
+				crds[crd.Name] = append([]string{v.Name}, crds[crd.Name]...)
+			} else {
+				crds[crd.Name] = append(crds[crd.Name], v.Name)
+			}
+		}
+	}
+	g.Expect(crds).To(Equal(map[string][]string{
+		"buckets.source.toolkit.fluxcd.io":          {"v1beta2"},
+		"gitrepositories.source.toolkit.fluxcd.io":  {"v1", "v1beta2"},
+		"helmcharts.source.toolkit.fluxcd.io":       {"v1", "v1beta2"},
+		"helmrepositories.source.toolkit.fluxcd.io": {"v1", "v1beta2"},
+		"ocirepositories.source.toolkit.fluxcd.io":  {"v1beta2"},
+	}))
+}
+
+func TestEnvironment(t *testing.T) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS not set")
+	}
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	env, err := NewEnvironment()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer func() {
+		g.Expect(env.Stop()).To(Succeed())
+	}()
+
+	repo := &sourcev1.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "repo"},
+		Spec:       sourcev1.GitRepositorySpec{URL: "https://github.com/openfluxcd/artifact"},
+	}
+	_, err = env.CreateSource(ctx, repo, "v1", testserver.File{Name: "app.yaml", Body: "kind: ConfigMap\n"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(env.BumpRevision(ctx, repo, "v2", testserver.File{Name: "app.yaml", Body: "kind: Secret\n"})).To(Succeed())
+
+	art := &artifactv1.Artifact{}
+	g.Expect(env.Client.Get(ctx, ctrlclient.ObjectKeyFromObject(repo), art)).To(Succeed())
+	g.Expect(art.Spec.Revision).To(Equal("v2"))
+	g.Expect(art.OwnerReferences).To(HaveLen(1))
+	g.Expect(art.OwnerReferences[0].UID).To(Equal(repo.UID))
+}
+
+func TestEnvironmentWithAction(t *testing.T) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS not set")
+	}
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	env, err := NewEnvironment(
+		WithAddToScheme(addTestActionToScheme),
+		WithCRDDirectoryPaths("testdata/crds"),
+		WithAction[testAction, *testAction](func(env *Environment) reconcile.Reconciler {
+			return &testActionReconciler{client: env.Manager.GetClient()}
+		}),
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+	defer func() {
+		g.Expect(env.Stop()).To(Succeed())
+	}()
+
+	repo := &sourcev1.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "repo"},
+		Spec:       sourcev1.GitRepositorySpec{URL: "https://github.com/openfluxcd/artifact"},
+	}
+	ref, err := env.CreateSource(ctx, repo, "v1", testserver.File{Name: "app.yaml", Body: "kind: ConfigMap\n"})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	obj := &testAction{}
+	obj.Namespace, obj.Name = "default", "app"
+	obj.Spec.SourceRef = *ref
+	g.Expect(env.Client.Create(ctx, obj)).To(Succeed())
+	g.Expect(env.WaitForRevision(ctx, obj, "v1")).To(Succeed())
+
+	// the action is reconciled for a new revision of its source
+	g.Expect(env.BumpRevision(ctx, repo, "v2", testserver.File{Name: "app.yaml", Body: "kind: Secret\n"})).To(Succeed())
+	g.Expect(env.WaitForRevision(ctx, obj, "v2")).To(Succeed())
+	g.Expect(obj.Status.LastAppliedRevision).To(Equal("v2"))
+
+	// waiting for a revision, which is never applied, times out
+	env.Timeout = time.Second
+	g.Expect(env.WaitForRevision(ctx, obj, "v3")).NotTo(Succeed())
+}
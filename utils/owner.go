@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// OwnerState describes the relation of an owner reference to the live owner object.
+type OwnerState int
+
+const (
+	// OwnerValid means the owner exists and its UID matches the reference.
+	OwnerValid OwnerState = iota
+	// OwnerStale means an object with the name of the owner exists, but it
+	// has a different UID, e.g. because it has been deleted and recreated.
+	OwnerStale
+	// OwnerGone means the owner does not exist anymore.
+	OwnerGone
+)
+
+func (s OwnerState) String() string {
+	switch s {
+	case OwnerValid:
+		return "valid"
+	case OwnerStale:
+		return "stale"
+	case OwnerGone:
+		return "gone"
+	default:
+		return fmt.Sprintf("OwnerState(%d)", int(s))
+	}
+}
+
+// CheckOwner verifies an owner reference of an object in the given namespace
+// against the live owner object. References without UID are only checked for
+// existence of the owner. The owner is read as metadata, the reader should be
+// uncached, a cached client starts an informer for every owner kind.
+func CheckOwner(ctx context.Context, reader ctrlclient.Reader, namespace string, ref metav1.OwnerReference) (OwnerState, error) {
+	owner := &metav1.PartialObjectMetadata{}
+	owner.SetGroupVersionKind(schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind))
+	if err := reader.Get(ctx, ctrlclient.ObjectKey{Namespace: namespace, Name: ref.Name}, owner); err != nil {
+		if apierrors.IsNotFound(err) {
+			return OwnerGone, nil
+		}
+		return OwnerValid, fmt.Errorf("unable to get owner %s '%s/%s': %w", ref.Kind, namespace, ref.Name, err)
+	}
+	if ref.UID != "" && owner.GetUID() != ref.UID {
+		return OwnerStale, nil
+	}
+	return OwnerValid, nil
+}
+
+// FindOwnerReference returns the owner reference of an object matching
+// the given group, kind and name.
+func FindOwnerReference(o metav1.Object, group, kind, name string) *metav1.OwnerReference {
+	for _, ref := range o.GetOwnerReferences() {
+		if ExtractGroupName(ref.APIVersion) == group && ref.Kind == kind && ref.Name == name {
+			r := ref
+			return &r
+		}
+	}
+	return nil
+}
+
+// OrphanedArtifact describes an Artifact with an owner reference, which does
+// not match a live owner object anymore.
+type OrphanedArtifact struct {
+	Artifact *artifactv1.Artifact
+	Owner    metav1.OwnerReference
+	State    OwnerState
+}
+
+// FindOrphanedArtifacts checks the owner references of all Artifacts in the
+// given namespace (all namespaces if empty) and reports those whose owner is
+// gone or has been recreated, so that they can be cleaned up.
+func FindOrphanedArtifacts(ctx context.Context, reader ctrlclient.Reader, namespace string) ([]OrphanedArtifact, error) {
+	var list artifactv1.ArtifactList
+	if err := reader.List(ctx, &list, ctrlclient.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("unable to list artifacts: %w", err)
+	}
+
+	var result []OrphanedArtifact
+	for i := range list.Items {
+		art := &list.Items[i]
+		for _, ref := range art.GetOwnerReferences() {
+			state, err := CheckOwner(ctx, reader, art.Namespace, ref)
+			if err != nil {
+				return nil, err
+			}
+			if state != OwnerValid {
+				result = append(result, OrphanedArtifact{Artifact: art, Owner: ref, State: state})
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+)
+
+// KindResolver resolves the group kind of a source reference.
+type KindResolver interface {
+	// ResolveGroupVersionKind completes the group of a group version kind and
+	// maps resource names, singular names and short names to the kind. An
+	// empty group and version means the group is unknown. The version is kept
+	// as requested.
+	ResolveGroupVersionKind(gvk schema.GroupVersionKind) (schema.GroupVersionKind, error)
+}
+
+// AmbiguousKindError is returned if a kind cannot be mapped to a unique group.
+type AmbiguousKindError struct {
+	Kind       string
+	Candidates []schema.GroupKind
+}
+
+func (e *AmbiguousKindError) Error() string {
+	list := make([]string, 0, len(e.Candidates))
+	for _, c := range e.Candidates {
+		list = append(list, c.String())
+	}
+	return fmt.Sprintf("kind %q is ambiguous, it matches %s, please specify an apiVersion", e.Kind, strings.Join(list, ", "))
+}
+
+func IsAmbiguousKindError(err error) bool {
+	var e *AmbiguousKindError
+	return errors.As(err, &e)
+}
+
+// DiscoveryRefreshInterval is the minimum time between two refreshs of the
+// discovery information by a DiscoveryKindResolver.
+const DiscoveryRefreshInterval = 30 * time.Second
+
+// DiscoveryKindResolver is a KindResolver based on the RESTMapper and the
+// API discovery of the cluster. Discovery information is cached and refreshed
+// if a reference cannot be resolved, at most once per DiscoveryRefreshInterval.
+type DiscoveryKindResolver struct {
+	mapper    meta.RESTMapper
+	discovery discovery.CachedDiscoveryInterface
+
+	lock        sync.Mutex
+	lastRefresh time.Time
+}
+
+var _ KindResolver = (*DiscoveryKindResolver)(nil)
+
+func NewKindResolver(mapper meta.RESTMapper, client discovery.DiscoveryInterface) *DiscoveryKindResolver {
+	cached, ok := client.(discovery.CachedDiscoveryInterface)
+	if !ok {
+		cached = memory.NewMemCacheClient(client)
+	}
+	return &DiscoveryKindResolver{
+		mapper:    mapper,
+		discovery: cached,
+	}
+}
+
+// NewKindResolverForConfig creates a KindResolver for the cluster described by
+// the given rest config, e.g. by using mgr.GetConfig() and mgr.GetRESTMapper().
+func NewKindResolverForConfig(cfg *rest.Config, mapper meta.RESTMapper) (*DiscoveryKindResolver, error) {
+	client, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create discovery client: %w", err)
+	}
+	return NewKindResolver(mapper, client), nil
+}
+
+func (r *DiscoveryKindResolver) ResolveGroupVersionKind(gvk schema.GroupVersionKind) (schema.GroupVersionKind, error) {
+	if gvk.Kind == "" {
+		return gvk, fmt.Errorf("no kind specified")
+	}
+	groupKnown := gvk.Group != "" || gvk.Version != ""
+
+	// fast path for fully qualified kinds
+	if groupKnown && r.mapper != nil {
+		if _, err := r.mapper.RESTMapping(gvk.GroupKind()); err == nil {
+			return gvk, nil
+		}
+	}
+
+	candidates, err := r.candidates(gvk, groupKnown)
+	if err != nil {
+		return gvk, err
+	}
+	if len(candidates) == 0 && r.refresh() {
+		// the kind may have been installed after the last discovery
+		candidates, err = r.candidates(gvk, groupKnown)
+		if err != nil {
+			return gvk, err
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return gvk, &meta.NoKindMatchError{GroupKind: gvk.GroupKind(), SearchedVersions: []string{gvk.Version}}
+	case 1:
+		return candidates[0].WithVersion(gvk.Version), nil
+	default:
+		return gvk, &AmbiguousKindError{Kind: gvk.Kind, Candidates: candidates}
+	}
+}
+
+// refresh invalidates the cached discovery information, unless it has been
+// refreshed recently.
+func (r *DiscoveryKindResolver) refresh() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if now := time.Now(); now.Sub(r.lastRefresh) >= DiscoveryRefreshInterval {
+		r.discovery.Invalidate()
+		r.lastRefresh = now
+		return true
+	}
+	return false
+}
+
+func (r *DiscoveryKindResolver) candidates(gvk schema.GroupVersionKind, groupKnown bool) ([]schema.GroupKind, error) {
+	lists, err := discovery.ServerPreferredResources(r.discovery)
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("unable to discover API resources: %w", err)
+	}
+
+	set := map[schema.GroupKind]struct{}{}
+	var exact []schema.GroupKind
+	var other []schema.GroupKind
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		if groupKnown && gv.Group != gvk.Group {
+			continue
+		}
+		for _, res := range list.APIResources {
+			if strings.Contains(res.Name, "/") {
+				// subresource
+				continue
+			}
+			gk := schema.GroupKind{Group: gv.Group, Kind: res.Kind}
+			if _, ok := set[gk]; ok {
+				continue
+			}
+			if res.Kind == gvk.Kind {
+				set[gk] = struct{}{}
+				exact = append(exact, gk)
+			} else if matchesResourceName(&res, gvk.Kind) {
+				set[gk] = struct{}{}
+				other = append(other, gk)
+			}
+		}
+	}
+	// an exact kind match wins over resource and short names
+	if len(exact) == 0 {
+		exact = other
+	}
+	sort.Slice(exact, func(i, j int) bool {
+		return exact[i].String() < exact[j].String()
+	})
+	return exact, nil
+}
+
+func matchesResourceName(res *metav1.APIResource, name string) bool {
+	if strings.EqualFold(res.Kind, name) || strings.EqualFold(res.Name, name) || strings.EqualFold(res.SingularName, name) {
+		return true
+	}
+	for _, s := range res.ShortNames {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveSourceRef returns a source reference with a resolved group kind.
+func ResolveSourceRef(resolver KindResolver, ref SourceRefProvider) (SourceRefProvider, error) {
+	gvk, err := resolver.ResolveGroupVersionKind(GetGroupVersionKind(ref))
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve kind of source reference %s: %w", ref, err)
+	}
+	ns := ref.GetNamespace()
+	if ClusterScopedSourceKinds[gvk.GroupKind()] {
+		ns = ""
+	}
+	return NewVersionedSourceRef(gvk, ns, ref.GetName()), nil
+}
@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+var (
+	gitRepository = schema.GroupKind{Group: "source.toolkit.fluxcd.io", Kind: "GitRepository"}
+	ociRepository = schema.GroupKind{Group: "source.toolkit.fluxcd.io", Kind: "OCIRepository"}
+	otherRepo     = schema.GroupKind{Group: "example.com", Kind: "OCIRepository"}
+)
+
+func newTestDiscovery() *fakediscovery.FakeDiscovery {
+	return &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{
+		{
+			GroupVersion: "source.toolkit.fluxcd.io/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "gitrepositories", SingularName: "gitrepository", Kind: "GitRepository", ShortNames: []string{"gitrepo"}, Namespaced: true},
+				{Name: "gitrepositories/status", Kind: "GitRepository", Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "source.toolkit.fluxcd.io/v1beta2",
+			APIResources: []metav1.APIResource{
+				{Name: "ocirepositories", SingularName: "ocirepository", Kind: "OCIRepository", ShortNames: []string{"ocirepo"}, Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "ocirepositories", SingularName: "ocirepository", Kind: "OCIRepository", Namespaced: true},
+				{Name: "repositories", SingularName: "repository", Kind: "Repository", ShortNames: []string{"gitrepository"}, Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "openfluxcd.ocm.software/v1alpha1",
+			APIResources: []metav1.APIResource{
+				{Name: "clusterartifacts", SingularName: "clusterartifact", Kind: "ClusterArtifact"},
+			},
+		},
+	}}}
+}
+
+func TestDiscoveryKindResolver(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(gitRepository.WithVersion("v1"), meta.RESTScopeNamespace)
+
+	tests := []struct {
+		name      string
+		gvk       schema.GroupVersionKind
+		expected  schema.GroupVersionKind
+		ambiguous []schema.GroupKind
+		noMatch   bool
+	}{
+		{name: "kind", gvk: schema.GroupVersionKind{Kind: "GitRepository"}, expected: gitRepository.WithVersion("")},
+		{name: "version kept", gvk: schema.GroupVersionKind{Group: "source.toolkit.fluxcd.io", Version: "v1beta2", Kind: "GitRepository"},
+			expected: gitRepository.WithVersion("v1beta2")},
+		{name: "plural", gvk: schema.GroupVersionKind{Kind: "gitrepositories"}, expected: gitRepository.WithVersion("")},
+		{name: "singular", gvk: schema.GroupVersionKind{Group: "source.toolkit.fluxcd.io", Kind: "gitrepository"}, expected: gitRepository.WithVersion("")},
+		{name: "short name", gvk: schema.GroupVersionKind{Kind: "gitrepo"}, expected: gitRepository.WithVersion("")},
+		{name: "short name in group", gvk: schema.GroupVersionKind{Group: "source.toolkit.fluxcd.io", Kind: "ocirepo"}, expected: ociRepository.WithVersion("")},
+		{name: "exact kind wins over short name", gvk: schema.GroupVersionKind{Kind: "GitRepository"}, expected: gitRepository.WithVersion("")},
+		{name: "ambiguous name", gvk: schema.GroupVersionKind{Kind: "gitrepository"}, ambiguous: []schema.GroupKind{gitRepository, {Group: "example.com", Kind: "Repository"}}},
+		{name: "ambiguous", gvk: schema.GroupVersionKind{Kind: "OCIRepository"}, ambiguous: []schema.GroupKind{otherRepo, ociRepository}},
+		{name: "ambiguous plural", gvk: schema.GroupVersionKind{Kind: "ocirepositories"}, ambiguous: []schema.GroupKind{otherRepo, ociRepository}},
+		{name: "group", gvk: schema.GroupVersionKind{Group: "example.com", Kind: "OCIRepository"}, expected: otherRepo.WithVersion("")},
+		{name: "unknown", gvk: schema.GroupVersionKind{Kind: "Bucket"}, noMatch: true},
+		{name: "unknown in group", gvk: schema.GroupVersionKind{Group: "example.com", Kind: "Bucket"}, noMatch: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			r := NewKindResolver(mapper, newTestDiscovery())
+			gvk, err := r.ResolveGroupVersionKind(tt.gvk)
+			switch {
+			case tt.ambiguous != nil:
+				var ambiguous *AmbiguousKindError
+				g.Expect(err).To(BeAssignableToTypeOf(ambiguous))
+				g.Expect(err.(*AmbiguousKindError).Candidates).To(Equal(tt.ambiguous))
+			case tt.noMatch:
+				g.Expect(meta.IsNoMatchError(err)).To(BeTrue(), "unexpected error %v", err)
+			default:
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(gvk).To(Equal(tt.expected))
+			}
+		})
+	}
+}
+
+func TestResolveSourceRef(t *testing.T) {
+	g := NewWithT(t)
+	r := NewKindResolver(nil, newTestDiscovery())
+
+	ref, err := ResolveSourceRef(r, NewSourceRef("", "gitrepo", "default", "app"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(GetGroupVersionKind(ref)).To(Equal(gitRepository.WithVersion("")))
+	g.Expect(ref.GetNamespace()).To(Equal("default"))
+
+	// the namespace of cluster-scoped sources is dropped
+	ref, err = ResolveSourceRef(r, NewSourceRef("", "clusterartifacts", "default", "shared"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(ref.GetGroupKind()).To(Equal(schema.GroupKind{Group: "openfluxcd.ocm.software", Kind: "ClusterArtifact"}))
+	g.Expect(ref.GetNamespace()).To(BeEmpty())
+
+	// ambiguity is detected through the wrapped error
+	_, err = ResolveSourceRef(r, NewSourceRef("", "OCIRepository", "default", "app"))
+	g.Expect(IsAmbiguousKindError(err)).To(BeTrue(), "unexpected error %v", err)
+	g.Expect(IsAmbiguousKindError(nil)).To(BeFalse())
+}
+
+func TestDiscoveryKindResolverRefresh(t *testing.T) {
+	g := NewWithT(t)
+	client := newTestDiscovery()
+	r := NewKindResolver(nil, client)
+
+	_, err := r.ResolveGroupVersionKind(schema.GroupVersionKind{Kind: "Bucket"})
+	g.Expect(meta.IsNoMatchError(err)).To(BeTrue())
+	g.Expect(r.lastRefresh.IsZero()).To(BeFalse())
+
+	client.Resources = append(client.Resources, &metav1.APIResourceList{
+		GroupVersion: "source.toolkit.fluxcd.io/v1beta2",
+		APIResources: []metav1.APIResource{
+			{Name: "ocirepositories", SingularName: "ocirepository", Kind: "OCIRepository", Namespaced: true},
+			{Name: "buckets", SingularName: "bucket", Kind: "Bucket", Namespaced: true},
+		},
+	})
+	client.Resources = append(client.Resources[:1], client.Resources[2:]...)
+
+	// the cached discovery information is not refreshed within the interval
+	_, err = r.ResolveGroupVersionKind(schema.GroupVersionKind{Kind: "Bucket"})
+	g.Expect(meta.IsNoMatchError(err)).To(BeTrue())
+
+	r.lastRefresh = time.Now().Add(-DiscoveryRefreshInterval)
+	gvk, err := r.ResolveGroupVersionKind(schema.GroupVersionKind{Kind: "Bucket"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(gvk).To(Equal(schema.GroupVersionKind{Group: "source.toolkit.fluxcd.io", Kind: "Bucket"}))
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	artifactv1 "github.com/openfluxcd/artifact/api/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -17,6 +18,60 @@ type SourceRefProvider interface {
 	String() string
 }
 
+// VersionedSourceRefProvider is implemented by source references
+// keeping the requested version of the referent.
+type VersionedSourceRefProvider interface {
+	SourceRefProvider
+	GetGroupVersionKind() schema.GroupVersionKind
+}
+
+// PinnedSourceRefProvider is implemented by source references which may be
+// pinned to a revision or digest of the referent.
+type PinnedSourceRefProvider interface {
+	SourceRefProvider
+	GetPinnedRevision() string
+	GetPinnedDigest() string
+}
+
+// ClusterScopedSourceRefProvider is implemented by source references
+// which may refer to cluster-scoped referents.
+type ClusterScopedSourceRefProvider interface {
+	SourceRefProvider
+	IsClusterScoped() bool
+}
+
+// ClusterScopedSourceKinds are the known source kinds without namespace.
+var ClusterScopedSourceKinds = map[schema.GroupKind]bool{
+	{Group: artifactv1.GroupVersion.Group, Kind: artifactv1.ClusterArtifactKind}: true,
+}
+
+// IsClusterScoped checks whether a source reference refers to a
+// cluster-scoped referent. The namespace of such references is ignored.
+func IsClusterScoped(ref SourceRefProvider) bool {
+	if p, ok := ref.(ClusterScopedSourceRefProvider); ok && p.IsClusterScoped() {
+		return true
+	}
+	return ClusterScopedSourceKinds[ref.GetGroupKind()]
+}
+
+// GetPin returns the pinned revision and digest of a source reference.
+// Both are empty if the reference is not pinned.
+func GetPin(ref SourceRefProvider) (revision, digest string) {
+	if p, ok := ref.(PinnedSourceRefProvider); ok {
+		return p.GetPinnedRevision(), p.GetPinnedDigest()
+	}
+	return "", ""
+}
+
+// GetGroupVersionKind returns the group version kind of a source reference.
+// The version is empty if the reference does not request a dedicated version.
+func GetGroupVersionKind(ref SourceRefProvider) schema.GroupVersionKind {
+	if v, ok := ref.(VersionedSourceRefProvider); ok {
+		return v.GetGroupVersionKind()
+	}
+	return ref.GetGroupKind().WithVersion("")
+}
+
 func NewSourceRef(g, k, ns, name string) SourceRefProvider {
 	return &DefaultSourceRef{
 		GroupKind: schema.GroupKind{
@@ -30,11 +85,25 @@ func NewSourceRef(g, k, ns, name string) SourceRefProvider {
 	}
 }
 
+func NewVersionedSourceRef(gvk schema.GroupVersionKind, ns, name string) SourceRefProvider {
+	return &DefaultSourceRef{
+		GroupKind: gvk.GroupKind(),
+		Version:   gvk.Version,
+		NamespacedName: types.NamespacedName{
+			Namespace: ns,
+			Name:      name,
+		},
+	}
+}
+
 type DefaultSourceRef struct {
 	schema.GroupKind
+	Version string
 	types.NamespacedName
 }
 
+var _ VersionedSourceRefProvider = (*DefaultSourceRef)(nil)
+
 func (d *DefaultSourceRef) GetObjectKey() ctrlclient.ObjectKey {
 	return d.NamespacedName
 }
@@ -43,6 +112,10 @@ func (d *DefaultSourceRef) GetGroupKind() schema.GroupKind {
 	return d.GroupKind
 }
 
+func (d *DefaultSourceRef) GetGroupVersionKind() schema.GroupVersionKind {
+	return d.GroupKind.WithVersion(d.Version)
+}
+
 func (d *DefaultSourceRef) GetName() string {
 	return d.Name
 }
@@ -62,9 +135,18 @@ func IsSameResource(a, b SourceRefProvider) bool {
 	return a.GetGroupKind() == b.GetGroupKind() && a.GetNamespace() == b.GetNamespace() && a.GetName() == b.GetName()
 }
 
+// NormalizedSourceRef returns the source reference with the namespace
+// defaulted to the given one. The namespace of references to cluster-scoped
+// referents is removed.
 func NormalizedSourceRef(ref SourceRefProvider, defns string) SourceRefProvider {
+	if IsClusterScoped(ref) {
+		if ref.GetNamespace() == "" {
+			return ref
+		}
+		return NewVersionedSourceRef(GetGroupVersionKind(ref), "", ref.GetName())
+	}
 	if ref.GetNamespace() == "" {
-		return NewSourceRef(ref.GetGroupKind().Group, ref.GetGroupKind().Kind, defns, ref.GetName())
+		return NewVersionedSourceRef(GetGroupVersionKind(ref), defns, ref.GetName())
 	}
 	return ref
 }
@@ -80,6 +162,9 @@ func KeyForReference(o metav1.Object, ref SourceRefProvider) string {
 	if ref.GetNamespace() != "" {
 		namespace = ref.GetNamespace()
 	}
+	if IsClusterScoped(ref) {
+		namespace = ""
+	}
 	return fmt.Sprintf("%s/%s/%s/%s", gk.Group, gk.Kind, namespace, ref.GetName())
 }
 